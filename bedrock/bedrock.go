@@ -198,6 +198,10 @@ func WithLoadDefaultConfig(ctx context.Context, optFns ...func(*config.LoadOptio
 // set, it is used for bearer token authentication. Otherwise, if cfg.BearerAuthTokenProvider is set,
 // it is used. If neither is available, cfg.Credentials is used for AWS SigV4 signing and must be set.
 //
+// First-party model IDs, such as anthropic.ModelClaudeSonnet4_5, are translated to Bedrock IDs with
+// modelregistry.Default(), using the inference profile of cfg.Region's geography when the model has
+// one; see [WithModelRegistry] and [WithInferenceProfile]. Other model IDs are sent unchanged.
+//
 // The Bedrock adaptation (URL and body rewriting, request signing, and
 // normalization of streaming responses to SSE) should run closest to the wire.
 // Middleware runs in registration order, so register [option.WithMiddleware]
@@ -264,7 +268,10 @@ func bedrockMiddleware(signer *v4.Signer, cfg aws.Config) option.Middleware {
 			}
 
 			if r.Method == http.MethodPost && DefaultEndpoints[r.URL.Path] {
-				model := gjson.GetBytes(body, "model").String()
+				model, err := modelID(r.Context(), cfg.Region, gjson.GetBytes(body, "model").String())
+				if err != nil {
+					return nil, err
+				}
 				stream := gjson.GetBytes(body, "stream").Bool()
 
 				body, _ = sjson.DeleteBytes(body, "model")
@@ -20,6 +20,7 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/modelregistry"
 )
 
 func TestBedrockURLEncoding(t *testing.T) {
@@ -533,3 +534,58 @@ func TestBedrockStreamingEndToEnd(t *testing.T) {
 		}
 	}
 }
+
+func TestBedrockModelTranslation(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+	t.Setenv("AWS_BEARER_TOKEN_BEDROCK", "")
+
+	var wirePath string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wirePath = r.URL.Path
+		writeMessagesResponse(w)
+	}))
+	t.Cleanup(server.Close)
+
+	send := func(region, model string, opts ...option.RequestOption) error {
+		client := anthropic.NewClient(
+			option.WithoutEnvironmentDefaults(),
+			WithConfig(makeStaticAWSConfig(region)),
+			option.WithBaseURL(server.URL),
+		)
+		_, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
+			Model:     model,
+			MaxTokens: 1,
+			Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hi"))},
+		}, opts...)
+		return err
+	}
+
+	tests := []struct {
+		name, region, model string
+		opts                []option.RequestOption
+		want                string
+	}{
+		{"region profile", "us-east-1", anthropic.ModelClaudeSonnet4_5, nil, "/model/us.anthropic.claude-sonnet-4-5-20250929-v1:0/invoke"},
+		{"eu region profile", "eu-west-1", anthropic.ModelClaudeHaiku4_5_20251001, nil, "/model/eu.anthropic.claude-haiku-4-5-20251001-v1:0/invoke"},
+		{"explicit profile", "eu-west-1", anthropic.ModelClaudeSonnet4_5, []option.RequestOption{WithInferenceProfile(modelregistry.InferenceProfileGlobal)}, "/model/global.anthropic.claude-sonnet-4-5-20250929-v1:0/invoke"},
+		{"no profile", "us-east-1", anthropic.ModelClaudeSonnet4_5, []option.RequestOption{WithInferenceProfile(modelregistry.InferenceProfileNone)}, "/model/anthropic.claude-sonnet-4-5-20250929-v1:0/invoke"},
+		{"bedrock ID unchanged", "us-east-1", "apac.anthropic.claude-sonnet-4-5-20250929-v1:0", nil, "/model/apac.anthropic.claude-sonnet-4-5-20250929-v1:0/invoke"},
+		{"custom registry", "us-east-1", "claude-custom", []option.RequestOption{WithModelRegistry(modelregistry.New(modelregistry.Entry{Model: "claude-custom", Bedrock: "anthropic.claude-custom-v1:0"}))}, "/model/anthropic.claude-custom-v1:0/invoke"},
+		{"registry disabled", "us-east-1", anthropic.ModelClaudeSonnet4_5, []option.RequestOption{WithModelRegistry(nil)}, "/model/claude-sonnet-4-5/invoke"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := send(tt.region, tt.model, tt.opts...); err != nil {
+				t.Fatal(err)
+			}
+			if wirePath != tt.want {
+				t.Errorf("got wire path %q, want %q", wirePath, tt.want)
+			}
+		})
+	}
+
+	// Opus 4.1 has no eu profile.
+	if err := send("eu-west-1", anthropic.ModelClaudeOpus4_1, WithInferenceProfile(modelregistry.InferenceProfileEU), option.WithMaxRetries(0)); err == nil || !strings.Contains(err.Error(), "inference profile") {
+		t.Errorf("expected a missing inference profile to fail the request, got %v", err)
+	}
+}
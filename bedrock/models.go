@@ -0,0 +1,52 @@
+package bedrock
+
+import (
+	"context"
+
+	"github.com/anthropics/anthropic-sdk-go/internal/requestconfig"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/modelregistry"
+)
+
+type modelRegistryKey struct{}
+
+type inferenceProfileKey struct{}
+
+// WithModelRegistry returns a request option that translates first-party
+// model IDs with registry instead of [modelregistry.Default]. A nil
+// registry sends model IDs unchanged. Pass it to anthropic.NewClient or to
+// a single request.
+func WithModelRegistry(registry *modelregistry.Registry) option.RequestOption {
+	return requestconfig.RequestOptionFunc(func(cfg *requestconfig.RequestConfig) error {
+		cfg.Request = cfg.Request.WithContext(context.WithValue(cfg.Request.Context(), modelRegistryKey{}, registry))
+		return nil
+	})
+}
+
+// WithInferenceProfile returns a request option that selects the
+// cross-region inference profile first-party model IDs are translated to,
+// such as [modelregistry.InferenceProfileGlobal], or
+// [modelregistry.InferenceProfileNone] for the foundation-model ID. By
+// default the profile of the client region's geography is used when the
+// model has one. A model without the profile fails the request.
+func WithInferenceProfile(profile string) option.RequestOption {
+	return requestconfig.RequestOptionFunc(func(cfg *requestconfig.RequestConfig) error {
+		cfg.Request = cfg.Request.WithContext(context.WithValue(cfg.Request.Context(), inferenceProfileKey{}, profile))
+		return nil
+	})
+}
+
+// modelID translates model with the registry and inference profile of ctx.
+func modelID(ctx context.Context, region, model string) (string, error) {
+	registry := modelregistry.Default()
+	if r, ok := ctx.Value(modelRegistryKey{}).(*modelregistry.Registry); ok {
+		if r == nil {
+			return model, nil
+		}
+		registry = r
+	}
+	if profile, _ := ctx.Value(inferenceProfileKey{}).(string); profile != "" {
+		return registry.BedrockID(model, profile)
+	}
+	return registry.BedrockIDForRegion(model, region), nil
+}
@@ -0,0 +1,210 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+)
+
+// BetaMessageBatchJobParams configures a [BetaMessageBatchJob].
+type BetaMessageBatchJobParams struct {
+	MessageBatchJobParams
+	// Betas are sent with every batch request. The Betas of the individual
+	// requests' params are not sent, as they are headers.
+	Betas []AnthropicBeta
+}
+
+// BetaMessageBatchJobRequest is one request of a [BetaMessageBatchJob].
+type BetaMessageBatchJobRequest struct {
+	// CustomID identifies the request's result. It must be unique within the
+	// job.
+	CustomID string
+	Params   BetaMessageNewParams
+}
+
+// BetaMessageBatchJobResult is the outcome of one request of a
+// [BetaMessageBatchJob].
+type BetaMessageBatchJobResult struct {
+	CustomID string
+	// BatchID is the ID of the batch the request was submitted in.
+	BatchID string
+	// Request is the request as submitted. For a job resumed without its
+	// requests, it holds only the CustomID.
+	Request BetaMessageBatchJobRequest
+	// Result is the outcome of the request. Switch on Result.AsAny() for the
+	// [BetaMessageBatchSucceededResult], [BetaMessageBatchErroredResult],
+	// [BetaMessageBatchCanceledResult] or [BetaMessageBatchExpiredResult].
+	Result BetaMessageBatchResultUnion
+}
+
+// BetaMessageBatchJob is [MessageBatchJob] for the beta Message Batches API.
+// Create one with [BetaMessageBatchService.Submit] or
+// [BetaMessageBatchService.ResumeJob].
+type BetaMessageBatchJob struct {
+	service  *BetaMessageBatchService
+	params   BetaMessageBatchJobParams
+	opts     []option.RequestOption
+	batchIDs []string
+	requests map[string]BetaMessageBatchJobRequest
+}
+
+// Submit creates batches for requests, splitting them across as many batches
+// as the request count and size limits in params require, and returns the job
+// that tracks them. See [MessageBatchService.Submit].
+func (r *BetaMessageBatchService) Submit(ctx context.Context, requests iter.Seq[BetaMessageBatchJobRequest], params BetaMessageBatchJobParams, opts ...option.RequestOption) (*BetaMessageBatchJob, error) {
+	job := r.newJob(nil, params, opts)
+
+	var (
+		pending []BetaMessageBatchNewParamsRequest
+		size    int
+	)
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		batch, err := r.New(ctx, BetaMessageBatchNewParams{Requests: pending, Betas: job.params.Betas}, opts...)
+		if err != nil {
+			return err
+		}
+		job.batchIDs = append(job.batchIDs, batch.ID)
+		pending, size = nil, 0
+		return nil
+	}
+	for req := range requests {
+		if _, dup := job.requests[req.CustomID]; dup {
+			return job.orNil(), fmt.Errorf("anthropic: duplicate batch request custom_id %q", req.CustomID)
+		}
+		raw, err := json.Marshal(req.Params)
+		if err != nil {
+			return job.orNil(), fmt.Errorf("anthropic: encoding batch request %q: %w", req.CustomID, err)
+		}
+		n := len(raw) + len(req.CustomID) + batchRequestOverhead
+		if n > job.params.MaxBytesPerBatch {
+			return job.orNil(), fmt.Errorf("anthropic: batch request %q is %d bytes, more than the %d allowed per batch", req.CustomID, n, job.params.MaxBytesPerBatch)
+		}
+		if len(pending) == job.params.MaxRequestsPerBatch || size+n > job.params.MaxBytesPerBatch {
+			if err := flush(); err != nil {
+				return job.orNil(), err
+			}
+		}
+		job.requests[req.CustomID] = req
+		pending = append(pending, BetaMessageBatchNewParamsRequest{
+			CustomID: req.CustomID,
+			Params:   param.Override[BetaMessageBatchNewParamsRequestParams](json.RawMessage(raw)),
+		})
+		size += n
+	}
+	if err := flush(); err != nil {
+		return job.orNil(), err
+	}
+	return job, nil
+}
+
+// ResumeJob returns the job made up of existing batches. See
+// [MessageBatchService.ResumeJob].
+func (r *BetaMessageBatchService) ResumeJob(batchIDs []string, requests iter.Seq[BetaMessageBatchJobRequest], params BetaMessageBatchJobParams, opts ...option.RequestOption) *BetaMessageBatchJob {
+	job := r.newJob(batchIDs, params, opts)
+	if requests != nil {
+		for req := range requests {
+			job.requests[req.CustomID] = req
+		}
+	}
+	return job
+}
+
+func (r *BetaMessageBatchService) newJob(batchIDs []string, params BetaMessageBatchJobParams, opts []option.RequestOption) *BetaMessageBatchJob {
+	params.MessageBatchJobParams = params.MessageBatchJobParams.withDefaults()
+	return &BetaMessageBatchJob{
+		service:  r,
+		params:   params,
+		opts:     opts,
+		batchIDs: append([]string(nil), batchIDs...),
+		requests: map[string]BetaMessageBatchJobRequest{},
+	}
+}
+
+// orNil returns j if it has created any batches.
+func (j *BetaMessageBatchJob) orNil() *BetaMessageBatchJob {
+	if len(j.batchIDs) == 0 {
+		return nil
+	}
+	return j
+}
+
+// BatchIDs returns the IDs of the job's batches. Persist them to re-attach to
+// the job with [BetaMessageBatchService.ResumeJob].
+func (j *BetaMessageBatchJob) BatchIDs() []string {
+	return append([]string(nil), j.batchIDs...)
+}
+
+// Wait polls the job's batches until every one has ended and returns them.
+func (j *BetaMessageBatchJob) Wait(ctx context.Context) ([]*BetaMessageBatch, error) {
+	batches := make([]*BetaMessageBatch, 0, len(j.batchIDs))
+	for _, id := range j.batchIDs {
+		batch, err := j.wait(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+func (j *BetaMessageBatchJob) wait(ctx context.Context, batchID string) (batch *BetaMessageBatch, err error) {
+	err = pollBatch(ctx, j.params.PollInterval, j.params.MaxPollInterval, func() (bool, error) {
+		batch, err = j.service.Get(ctx, batchID, BetaMessageBatchGetParams{Betas: j.params.Betas}, j.opts...)
+		if err != nil {
+			return false, err
+		}
+		return batch.ProcessingStatus == BetaMessageBatchProcessingStatusEnded, nil
+	})
+	return batch, err
+}
+
+// Results waits for each of the job's batches to end in turn and yields the
+// results of its requests. See [MessageBatchJob.Results].
+func (j *BetaMessageBatchJob) Results(ctx context.Context) iter.Seq2[BetaMessageBatchJobResult, error] {
+	return func(yield func(BetaMessageBatchJobResult, error) bool) {
+		for _, id := range j.batchIDs {
+			if _, err := j.wait(ctx, id); err != nil {
+				yield(BetaMessageBatchJobResult{BatchID: id}, err)
+				return
+			}
+			stream := j.service.ResultsStreaming(ctx, id, BetaMessageBatchResultsParams{Betas: j.params.Betas}, j.opts...)
+			for stream.Next() {
+				res := stream.Current()
+				req, ok := j.requests[res.CustomID]
+				if !ok {
+					req = BetaMessageBatchJobRequest{CustomID: res.CustomID}
+				}
+				if !yield(BetaMessageBatchJobResult{CustomID: res.CustomID, BatchID: id, Request: req, Result: res.Result}, nil) {
+					stream.Close()
+					return
+				}
+			}
+			err := stream.Err()
+			stream.Close()
+			if err != nil {
+				yield(BetaMessageBatchJobResult{BatchID: id}, err)
+				return
+			}
+		}
+	}
+}
+
+// Cancel cancels every batch of the job. Requests that have not yet been
+// processed end with a canceled result.
+func (j *BetaMessageBatchJob) Cancel(ctx context.Context) error {
+	var errs []error
+	for _, id := range j.batchIDs {
+		if _, err := j.service.Cancel(ctx, id, BetaMessageBatchCancelParams{Betas: j.params.Betas}, j.opts...); err != nil {
+			errs = append(errs, fmt.Errorf("cancel batch %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
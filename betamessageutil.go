@@ -6,6 +6,7 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go/internal/paramutil"
 	"github.com/anthropics/anthropic-sdk-go/packages/param"
+	"github.com/anthropics/anthropic-sdk-go/packages/partialjson"
 )
 
 // Accumulate builds up the Message incrementally from a MessageStreamEvent. The Message then can be used as
@@ -134,6 +135,40 @@ func (r *BetaMessage) ParseOutput(dest any) error {
 	return parseOutputContent(r, dest)
 }
 
+// ParsePartialOutput is [BetaMessage.ParseOutput] for a message that is still
+// streaming. The structured output received so far is parsed as a truncated
+// JSON document, with unterminated strings, arrays and objects closed, so
+// dest holds a best-effort partial value after each event:
+//
+//	var msg anthropic.BetaMessage
+//	for stream.Next() {
+//		msg.Accumulate(stream.Current())
+//		var partial MyStruct
+//		if err := msg.ParsePartialOutput(&partial); err == nil {
+//			render(partial)
+//		}
+//	}
+//
+// dest may also be a map[string]any. If no text has arrived yet, dest is left
+// unchanged.
+func (r *BetaMessage) ParsePartialOutput(dest any) error {
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			return unmarshalPartialOutput(block.Text, dest)
+		}
+	}
+	return nil
+}
+
+// ParsePartialInput unmarshals the input of a tool use block into dest. While
+// the block is still streaming, the input received so far is parsed as a
+// truncated JSON document, so dest holds a best-effort partial value; see
+// [BetaMessage.ParsePartialOutput]. If no input has arrived yet, dest is left
+// unchanged.
+func (r BetaContentBlockUnion) ParsePartialInput(dest any) error {
+	return partialjson.Unmarshal(r.Input, dest)
+}
+
 // Param converters
 
 func (r BetaContentBlockUnion) ToParam() BetaContentBlockParamUnion {
@@ -7,13 +7,14 @@ import (
 	"reflect"
 	"sync"
 
+	"github.com/anthropics/anthropic-sdk-go/packages/partialjson"
 	"github.com/invopop/jsonschema"
 )
 
 // ErrStructuredOutputParse is returned (wrapped) by [BetaMessageService.New]
-// when the API request succeeds but the response body can't be unmarshaled
-// into the struct pointer passed as Schema. The *BetaMessage is still
-// returned in that case — check it alongside the error:
+// and [MessageService.New] when the API request succeeds but the response body
+// can't be unmarshaled into the struct pointer passed as Schema. The message is
+// still returned in that case — check it alongside the error:
 //
 //	msg, err := client.Beta.Messages.New(ctx, params)
 //	if errors.Is(err, anthropic.ErrStructuredOutputParse) {
@@ -86,7 +87,18 @@ func schemaToRaw(v any) (json.RawMessage, error) {
 // outputFormatDest checks both OutputFormat.Schema and OutputConfig.Format.Schema
 // for a struct pointer and returns it. Returns (nil, false) if neither has one.
 func outputFormatDest(params BetaMessageNewParams) (any, bool) {
-	for _, schema := range []any{params.OutputFormat.Schema, params.OutputConfig.Format.Schema} {
+	return structSchemaDest(params.OutputFormat.Schema, params.OutputConfig.Format.Schema)
+}
+
+// messageOutputFormatDest returns the struct pointer set as
+// OutputConfig.Format.Schema, if any.
+func messageOutputFormatDest(params MessageNewParams) (any, bool) {
+	return structSchemaDest(params.OutputConfig.Format.Schema)
+}
+
+// structSchemaDest returns the first schema that is a non-nil struct pointer.
+func structSchemaDest(schemas ...any) (any, bool) {
+	for _, schema := range schemas {
 		if schema == nil {
 			continue
 		}
@@ -104,11 +116,34 @@ func outputFormatDest(params BetaMessageNewParams) (any, bool) {
 func parseOutputContent(msg *BetaMessage, dest any) error {
 	for _, block := range msg.Content {
 		if block.Type == "text" {
-			if err := json.Unmarshal([]byte(block.Text), dest); err != nil {
-				return fmt.Errorf("%w: %w", ErrStructuredOutputParse, err)
-			}
-			return nil
+			return unmarshalOutput(block.Text, dest)
 		}
 	}
 	return fmt.Errorf("%w: no text content block found in response", ErrStructuredOutputParse)
 }
+
+// parseMessageOutputContent is [parseOutputContent] for a [Message].
+func parseMessageOutputContent(msg *Message, dest any) error {
+	for _, block := range msg.Content {
+		if block.Type == "text" {
+			return unmarshalOutput(block.Text, dest)
+		}
+	}
+	return fmt.Errorf("%w: no text content block found in response", ErrStructuredOutputParse)
+}
+
+// unmarshalPartialOutput parses the structured output text of a message that
+// may still be streaming as a truncated JSON document.
+func unmarshalPartialOutput(text string, dest any) error {
+	if err := partialjson.Unmarshal([]byte(text), dest); err != nil {
+		return fmt.Errorf("%w: %w", ErrStructuredOutputParse, err)
+	}
+	return nil
+}
+
+func unmarshalOutput(text string, dest any) error {
+	if err := json.Unmarshal([]byte(text), dest); err != nil {
+		return fmt.Errorf("%w: %w", ErrStructuredOutputParse, err)
+	}
+	return nil
+}
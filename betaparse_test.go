@@ -10,6 +10,7 @@ import (
 	"testing"
 
 	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/tidwall/gjson"
 )
 
 type testOrder struct {
@@ -582,3 +583,257 @@ func TestMarshalJSONSchemaMapPassthrough(t *testing.T) {
 		t.Errorf("expected schema type 'object', got %v", schema["type"])
 	}
 }
+
+func TestMessageNewAutoParseWithMockServer(t *testing.T) {
+	responseJSON := `{
+		"id": "msg_789",
+		"type": "message",
+		"role": "assistant",
+		"model": "claude-sonnet-4-5-20250514",
+		"stop_reason": "end_turn",
+		"stop_sequence": null,
+		"usage": {"input_tokens": 100, "output_tokens": 50},
+		"content": [{
+			"type": "text",
+			"text": "{\"items\":[{\"name\":\"Desk\",\"quantity\":1,\"price\":250}],\"total\":250,\"currency\":\"EUR\"}"
+		}]
+	}`
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]any
+		if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+			t.Errorf("failed to decode request body: %v", err)
+		}
+		outputConfig, _ := body["output_config"].(map[string]any)
+		format, ok := outputConfig["format"].(map[string]any)
+		if !ok {
+			t.Error("expected output_config.format in request body")
+		} else {
+			schema, ok := format["schema"].(map[string]any)
+			if !ok || schema["type"] != "object" || schema["additionalProperties"] != false {
+				t.Errorf("expected a generated JSON schema, got %v", format["schema"])
+			}
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, responseJSON)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		option.WithBaseURL(server.URL),
+		option.WithAPIKey("test-key"),
+	)
+
+	var order testOrder
+	msg, err := client.Messages.New(context.Background(), MessageNewParams{
+		Model:     ModelClaudeSonnet4_5,
+		MaxTokens: 1024,
+		Messages: []MessageParam{
+			NewUserMessage(NewTextBlock("Order a desk")),
+		},
+		OutputConfig: OutputConfigParam{
+			Format: JSONOutputFormatParam{Schema: &order},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if msg.ID != "msg_789" {
+		t.Errorf("expected message ID 'msg_789', got '%s'", msg.ID)
+	}
+	if len(order.Items) != 1 || order.Items[0].Name != "Desk" || order.Currency != "EUR" {
+		t.Fatalf("expected the response to be parsed into the struct, got %+v", order)
+	}
+}
+
+func TestMessageNewAutoParseError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"max_tokens",
+			"usage":{"input_tokens":1,"output_tokens":1},"content":[{"type":"text","text":"{\"items\":["}]}`)
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		option.WithBaseURL(server.URL),
+		option.WithAPIKey("test-key"),
+	)
+
+	var order testOrder
+	msg, err := client.Messages.New(context.Background(), MessageNewParams{
+		Model:        ModelClaudeSonnet4_5,
+		MaxTokens:    1024,
+		Messages:     []MessageParam{NewUserMessage(NewTextBlock("Order"))},
+		OutputConfig: OutputConfigParam{Format: JSONOutputFormatParam{Schema: &order}},
+	})
+	if !errors.Is(err, ErrStructuredOutputParse) {
+		t.Fatalf("expected ErrStructuredOutputParse, got %v", err)
+	}
+	if msg == nil || msg.ID != "msg_1" {
+		t.Errorf("expected the message to be returned alongside the parse error, got %+v", msg)
+	}
+}
+
+func TestMessageStreamingWithParseOutput(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type":"message_start","message":{"id":"msg_s","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":0}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"{\"items\":[],"}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"\"total\":0,\"currency\":\"USD\"}"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":12}}`,
+			`{"type":"message_stop"}`,
+		} {
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", gjson.Get(event, "type").String(), event)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(
+		option.WithBaseURL(server.URL),
+		option.WithAPIKey("test-key"),
+	)
+
+	var order testOrder
+	stream := client.Messages.NewStreaming(context.Background(), MessageNewParams{
+		Model:        ModelClaudeSonnet4_5,
+		MaxTokens:    1024,
+		Messages:     []MessageParam{NewUserMessage(NewTextBlock("Nothing"))},
+		OutputConfig: OutputConfigParam{Format: JSONOutputFormatParam{Schema: &order}},
+	})
+	var msg Message
+	for stream.Next() {
+		msg.Accumulate(stream.Current())
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("unexpected stream error: %v", err)
+	}
+	if err := msg.ParseOutput(&order); err != nil {
+		t.Fatalf("unexpected parse error: %v", err)
+	}
+	if order.Currency != "USD" || order.Items == nil {
+		t.Errorf("unexpected parsed order: %+v", order)
+	}
+}
+
+func TestJSONOutputFormatParamMarshalSchema(t *testing.T) {
+	data, err := json.Marshal(JSONOutputFormatParam{Schema: &testOrder{Total: 42}})
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(data, &got); err != nil {
+		t.Fatalf("unexpected unmarshal error: %v", err)
+	}
+	schema := got["schema"].(map[string]any)
+	if schema["type"] != "object" || schema["additionalProperties"] != false {
+		t.Fatalf("expected a JSON schema, got %s", data)
+	}
+	if got["type"] != "json_schema" {
+		t.Errorf("expected type json_schema, got %v", got["type"])
+	}
+
+	data, err = json.Marshal(JSONOutputFormatParam{Schema: map[string]any{"type": "object"}})
+	if err != nil {
+		t.Fatalf("unexpected marshal error: %v", err)
+	}
+	if normalizeJSON(string(data)) != normalizeJSON(`{"schema":{"type":"object"},"type":"json_schema"}`) {
+		t.Errorf("expected a map schema to be sent as-is, got %s", data)
+	}
+}
+
+func TestParsePartialOutput(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_p","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":0}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"{\"items\":[{\"name\":\"Lap"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"top\",\"quantity\":1}],\"total\":99"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"9.5,\"currency\":\"US"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"D\"}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+	}
+	want := []testOrder{
+		{},
+		{},
+		{Items: []testOrderItem{{Name: "Lap"}}},
+		{Items: []testOrderItem{{Name: "Laptop", Quantity: 1}}, Total: 99},
+		{Items: []testOrderItem{{Name: "Laptop", Quantity: 1}}, Total: 999.5, Currency: "US"},
+		{Items: []testOrderItem{{Name: "Laptop", Quantity: 1}}, Total: 999.5, Currency: "USD"},
+		{Items: []testOrderItem{{Name: "Laptop", Quantity: 1}}, Total: 999.5, Currency: "USD"},
+	}
+
+	var beta BetaMessage
+	var stable Message
+	for i, raw := range events {
+		var betaEvent BetaRawMessageStreamEventUnion
+		var event MessageStreamEventUnion
+		if err := json.Unmarshal([]byte(raw), &betaEvent); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		if err := beta.Accumulate(betaEvent); err != nil {
+			t.Fatalf("accumulate: %v", err)
+		}
+		if err := stable.Accumulate(event); err != nil {
+			t.Fatalf("accumulate: %v", err)
+		}
+
+		var betaOrder, order testOrder
+		if err := beta.ParsePartialOutput(&betaOrder); err != nil {
+			t.Fatalf("event %d: unexpected error: %v", i, err)
+		}
+		if err := stable.ParsePartialOutput(&order); err != nil {
+			t.Fatalf("event %d: unexpected error: %v", i, err)
+		}
+		got, _ := json.Marshal(order)
+		gotBeta, _ := json.Marshal(betaOrder)
+		expected, _ := json.Marshal(want[i])
+		if string(got) != string(expected) || string(gotBeta) != string(expected) {
+			t.Errorf("event %d: expected %s, got %s (beta %s)", i, expected, got, gotBeta)
+		}
+	}
+}
+
+func TestParsePartialInput(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_t","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"stop_reason":null,"stop_sequence":null,"usage":{"input_tokens":10,"output_tokens":0}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"{\"city\": \"San"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":" Francisco\", \"days\": 3, \"wi"}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"input_json_delta","partial_json":"nd\": true}"}}`,
+		`{"type":"content_block_stop","index":0}`,
+	}
+	want := []string{
+		`{}`,
+		`{}`,
+		`{"city":"San"}`,
+		`{"city":"San Francisco","days":3}`,
+		`{"city":"San Francisco","days":3,"wind":true}`,
+		`{"city":"San Francisco","days":3,"wind":true}`,
+	}
+
+	var msg BetaMessage
+	for i, raw := range events {
+		var event BetaRawMessageStreamEventUnion
+		if err := json.Unmarshal([]byte(raw), &event); err != nil {
+			t.Fatalf("unmarshal event: %v", err)
+		}
+		if err := msg.Accumulate(event); err != nil {
+			t.Fatalf("accumulate: %v", err)
+		}
+		input := map[string]any{}
+		if len(msg.Content) > 0 {
+			if err := msg.Content[0].ParsePartialInput(&input); err != nil {
+				t.Fatalf("event %d: unexpected error: %v", i, err)
+			}
+		}
+		if got, _ := json.Marshal(input); string(got) != want[i] {
+			t.Errorf("event %d: expected %s, got %s", i, want[i], got)
+		}
+	}
+}
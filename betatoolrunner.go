@@ -3,8 +3,11 @@ package anthropic
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"iter"
+	"sync"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go/internal/stainlessheader"
 	"github.com/anthropics/anthropic-sdk-go/option"
@@ -29,6 +32,93 @@ type BetaToolRunnerParams struct {
 	// MaxIterations limits the number of API calls. When set to 0 (the default),
 	// there is no limit and the runner continues until the model stops using tools.
 	MaxIterations int
+
+	// MaxParallelToolCalls limits how many tool calls from a single model turn
+	// execute at once. When set to 0 (the default), all of a turn's tool calls
+	// run in parallel.
+	MaxParallelToolCalls int
+
+	// ToolConcurrency limits how many calls to the named tool execute at once,
+	// on top of MaxParallelToolCalls. Set a tool's limit to 1 to serialize a
+	// tool that is not safe for concurrent use. Tools without an entry (or with
+	// an entry <= 0) are not limited individually.
+	ToolConcurrency map[string]int
+
+	// ToolTimeout bounds each tool call. A call that runs longer is answered
+	// with an error tool_result and its context is cancelled; a tool that
+	// ignores the cancellation is abandoned rather than blocking the turn.
+	// When set to 0 (the default), tool calls are not bounded.
+	ToolTimeout time.Duration
+
+	// ConversationStore, if set together with ConversationID, receives a
+	// checkpoint of the conversation after each model turn and each tool
+	// result, so that [BetaMessageService.ResumeToolRunner] can continue it
+	// after the process restarts.
+	ConversationStore ConversationStore
+	// ConversationID identifies this conversation in ConversationStore.
+	ConversationID string
+
+	// HistoryCompactor, if set, is consulted before each API call and may
+	// shorten the conversation history to keep it within the model's context
+	// window. The compacted history replaces Params.Messages. See
+	// [BetaDropOldToolResults], [BetaSummarizeHistory] and
+	// [BetaTrimHistoryToTokenBudget] for the built-in strategies.
+	HistoryCompactor BetaHistoryCompactor
+
+	// Pricing, if set, is used to estimate the cost of each API call from its
+	// usage, keyed by the model that served the call. A model's alias, such
+	// as [ModelClaudeSonnet4_5], also prices calls served by its dated
+	// snapshot. See [BetaToolRunner.Usage].
+	Pricing map[Model]ModelPricing
+
+	// BeforeToolCall, if set, is called for each tool_use block before it is
+	// executed and decides whether the call runs. It can allow the call, deny it
+	// (the model receives an error tool_result instead), rewrite its input, or
+	// defer to RequestApproval. It is called concurrently for the tool calls of
+	// a turn. A non-nil error aborts the turn and is returned by the runner.
+	BeforeToolCall func(ctx context.Context, toolUse BetaToolUseBlock) (BetaToolCallDecision, error)
+
+	// RequestApproval is called for tool calls that BeforeToolCall answered
+	// with [BetaToolCallVerdictAsk], and may block until an external approver
+	// responds. Its decision is final: a second "ask" is treated as a denial,
+	// as is an "ask" when RequestApproval is nil. A non-nil error aborts the
+	// turn and is returned by the runner.
+	RequestApproval func(ctx context.Context, toolUse BetaToolUseBlock) (BetaToolCallDecision, error)
+
+	// AfterToolCall, if set, is called with the result of each executed tool
+	// call — including calls whose tool returned an error — and returns the
+	// result that is sent to the model. Use it to redact or transform tool
+	// output. It is not called for denied calls. A non-nil error aborts the
+	// turn and is returned by the runner.
+	AfterToolCall func(ctx context.Context, toolUse BetaToolUseBlock, result BetaToolResultBlockParam) (BetaToolResultBlockParam, error)
+}
+
+// BetaToolCallVerdict is the outcome of a tool-call hook.
+type BetaToolCallVerdict string
+
+const (
+	// BetaToolCallVerdictAllow runs the tool call.
+	BetaToolCallVerdictAllow BetaToolCallVerdict = "allow"
+	// BetaToolCallVerdictDeny skips the tool call and answers it with an error
+	// tool_result.
+	BetaToolCallVerdictDeny BetaToolCallVerdict = "deny"
+	// BetaToolCallVerdictAsk defers the decision to
+	// [BetaToolRunnerParams.RequestApproval].
+	BetaToolCallVerdictAsk BetaToolCallVerdict = "ask"
+)
+
+// BetaToolCallDecision is returned by [BetaToolRunnerParams.BeforeToolCall] and
+// [BetaToolRunnerParams.RequestApproval]. Only an explicit
+// [BetaToolCallVerdictAllow] runs the call; an empty or unrecognized verdict
+// fails closed as a denial.
+type BetaToolCallDecision struct {
+	Verdict BetaToolCallVerdict
+	// Reason is sent to the model as the text of the error tool_result when the
+	// call is denied. Defaults to a generic denial message.
+	Reason string
+	// Input, if non-nil, replaces the input the tool is executed with. The
+	// conversation history keeps the input the model sent.
+	Input json.RawMessage
 }
 
 // betaToolRunnerBase holds state and logic shared by BetaToolRunner and BetaToolRunnerStreaming.
@@ -43,6 +133,17 @@ type betaToolRunnerBase struct {
 	completed      bool
 	opts           []option.RequestOption
 	err            error
+
+	// toolsAnswered reports whether the tool result message for lastMessage
+	// is already in Params.Messages, so a resumed runner does not answer the
+	// same tool calls twice.
+	toolsAnswered bool
+	// toolResults holds the results produced so far for lastMessage's tool
+	// calls, keyed by tool_use ID, while checkpointing is enabled. Calls with a
+	// result here are not executed again.
+	toolResults map[string]BetaToolResultBlockParam
+	usage       BetaToolRunnerUsage
+	mu          *sync.Mutex
 }
 
 func newBetaToolRunnerBase(messageService *BetaMessageService, tools []BetaTool, params BetaToolRunnerParams, opts []option.RequestOption) betaToolRunnerBase {
@@ -71,6 +172,7 @@ func newBetaToolRunnerBase(messageService *BetaMessageService, tools []BetaTool,
 		Params:         params,
 		toolMap:        toolMap,
 		opts:           opts,
+		mu:             &sync.Mutex{},
 	}
 }
 
@@ -107,6 +209,23 @@ func (b *betaToolRunnerBase) IsCompleted() bool {
 	return b.completed
 }
 
+// HasNextTurn reports whether the next turn makes an API call. It is false
+// once the conversation has completed, and when the next turn only completes
+// it, because the iteration limit is reached or the last message has no tool
+// calls to answer.
+func (b *betaToolRunnerBase) HasNextTurn() bool {
+	switch {
+	case b.completed:
+		return false
+	case b.Params.MaxIterations > 0 && b.iterationCount >= b.Params.MaxIterations:
+		return false
+	case b.lastMessage == nil || b.toolsAnswered:
+		return true
+	default:
+		return len(pendingToolUses(b.lastMessage)) > 0
+	}
+}
+
 // Err returns the last error that occurred during iteration, if any.
 // This is useful when using All() or AllStreaming() to check for errors
 // after the iteration completes.
@@ -114,17 +233,13 @@ func (b *betaToolRunnerBase) Err() error {
 	return b.err
 }
 
-// executeTools processes any tool use blocks in the given message and returns a tool result message.
-// Returns:
-//   - (result, nil) if tools executed successfully
-//   - (nil, nil) if no tools to execute or the turn ended in a refusal
-//   - (nil, ctx.Err()) if context was cancelled
-func (b *betaToolRunnerBase) executeTools(ctx context.Context, message *BetaMessage) (*BetaMessageParam, error) {
+// pendingToolUses returns the tool calls of message the runner answers.
+func pendingToolUses(message *BetaMessage) []BetaToolUseBlock {
 	// A refusal-terminated turn is terminal: its tool calls belong to a dead
 	// conversation — executing them fires side effects the caller never
 	// confirmed and produces tool_results that cannot be coherently replayed.
 	if message.StopReason == BetaStopReasonRefusal {
-		return nil, nil
+		return nil
 	}
 
 	// Tool calls before the last fallback block belong to the attempt that
@@ -138,14 +253,22 @@ func (b *betaToolRunnerBase) executeTools(ctx context.Context, message *BetaMess
 	}
 
 	var toolUseBlocks []BetaToolUseBlock
-
-	// Find all tool use blocks in the message
 	for i, block := range message.Content {
 		if i > seam && block.Type == "tool_use" {
 			toolUseBlocks = append(toolUseBlocks, block.AsToolUse())
 		}
 	}
+	return toolUseBlocks
+}
 
+// executeTools processes any tool use blocks in the given message and returns a tool result message.
+// Returns:
+//   - (result, nil) if tools executed successfully
+//   - (nil, nil) if no tools to execute or the turn ended in a refusal
+//   - (nil, ctx.Err()) if context was cancelled
+//   - (nil, err) if a tool-call hook returned an error
+func (b *betaToolRunnerBase) executeTools(ctx context.Context, message *BetaMessage) (*BetaMessageParam, error) {
+	toolUseBlocks := pendingToolUses(message)
 	if len(toolUseBlocks) == 0 {
 		return nil, nil
 	}
@@ -154,15 +277,61 @@ func (b *betaToolRunnerBase) executeTools(ctx context.Context, message *BetaMess
 	results := make([]BetaContentBlockParamUnion, len(toolUseBlocks))
 
 	g, gctx := errgroup.WithContext(ctx)
+	// Calls take their tool's slot before a shared one, so calls queued on
+	// a limited tool don't hold back other tools.
+	var shared chan struct{}
+	if b.Params.MaxParallelToolCalls > 0 {
+		shared = make(chan struct{}, b.Params.MaxParallelToolCalls)
+	}
+	perTool := make(map[string]chan struct{})
+	for name, limit := range b.Params.ToolConcurrency {
+		if limit > 0 {
+			perTool[name] = make(chan struct{}, limit)
+		}
+	}
+	acquire := func(sem chan struct{}) (release func(), err error) {
+		if sem == nil {
+			return func() {}, nil
+		}
+		select {
+		case <-gctx.Done():
+			return nil, gctx.Err()
+		case sem <- struct{}{}:
+			return func() { <-sem }, nil
+		}
+	}
+
 	for i, toolUse := range toolUseBlocks {
 		g.Go(func() error {
+			// Wait for a slot if this tool's concurrency is limited
+			releaseTool, err := acquire(perTool[toolUse.Name])
+			if err != nil {
+				return err
+			}
+			defer releaseTool()
+			releaseShared, err := acquire(shared)
+			if err != nil {
+				return err
+			}
+			defer releaseShared()
+
 			// Check for cancellation before executing tool
 			select {
 			case <-gctx.Done():
 				return gctx.Err()
 			default:
 			}
-			result := b.executeToolUse(gctx, toolUse)
+			if result, ok := b.answeredToolUse(toolUse.ID); ok {
+				results[i] = BetaContentBlockParamUnion{OfToolResult: &result}
+				return nil
+			}
+			result, err := b.runToolUse(gctx, toolUse)
+			if err != nil {
+				return err
+			}
+			if err := b.recordToolResult(gctx, result); err != nil {
+				return err
+			}
 			results[i] = BetaContentBlockParamUnion{OfToolResult: &result}
 			return nil // tool errors become result content, not Go errors
 		})
@@ -181,6 +350,47 @@ func newBetaToolResultErrorBlockParam(toolUseID string, errorText string) BetaTo
 	return NewBetaToolResultTextBlockParam(toolUseID, errorText, true)
 }
 
+// runToolUse applies the BeforeToolCall, RequestApproval and AfterToolCall
+// hooks around executeToolUse. Hook errors are returned as Go errors; a denied
+// call is answered with an error tool_result.
+func (b *betaToolRunnerBase) runToolUse(ctx context.Context, toolUse BetaToolUseBlock) (BetaToolResultBlockParam, error) {
+	if b.Params.BeforeToolCall != nil {
+		decision, err := b.Params.BeforeToolCall(ctx, toolUse)
+		if err != nil {
+			return BetaToolResultBlockParam{}, fmt.Errorf("BeforeToolCall hook failed for tool call %s (%s): %w", toolUse.ID, toolUse.Name, err)
+		}
+		if decision.Verdict == BetaToolCallVerdictAsk && b.Params.RequestApproval != nil {
+			decision, err = b.Params.RequestApproval(ctx, toolUse)
+			if err != nil {
+				return BetaToolResultBlockParam{}, fmt.Errorf("RequestApproval hook failed for tool call %s (%s): %w", toolUse.ID, toolUse.Name, err)
+			}
+		}
+		if decision.Verdict != BetaToolCallVerdictAllow {
+			reason := decision.Reason
+			if reason == "" {
+				reason = fmt.Sprintf("Error: Tool call '%s' was denied", toolUse.Name)
+			}
+			return newBetaToolResultErrorBlockParam(toolUse.ID, reason), nil
+		}
+		if decision.Input != nil {
+			toolUse.Input = decision.Input
+		}
+	}
+
+	result := b.executeToolUse(ctx, toolUse)
+
+	if b.Params.AfterToolCall != nil {
+		var err error
+		result, err = b.Params.AfterToolCall(ctx, toolUse, result)
+		if err != nil {
+			return BetaToolResultBlockParam{}, fmt.Errorf("AfterToolCall hook failed for tool call %s (%s): %w", toolUse.ID, toolUse.Name, err)
+		}
+		// The tool_result must keep answering the original tool_use.
+		result.ToolUseID = toolUse.ID
+	}
+	return result, nil
+}
+
 // executeToolUse executes a single tool use block and returns the result.
 func (b *betaToolRunnerBase) executeToolUse(ctx context.Context, toolUse BetaToolUseBlock) BetaToolResultBlockParam {
 	tool, exists := b.toolMap[toolUse.Name]
@@ -200,7 +410,7 @@ func (b *betaToolRunnerBase) executeToolUse(ctx context.Context, toolUse BetaToo
 		)
 	}
 
-	content, err := tool.Execute(ctx, inputBytes)
+	content, err := b.callTool(ctx, tool, inputBytes)
 	if err != nil {
 		return newBetaToolResultErrorBlockParam(
 			toolUse.ID,
@@ -214,6 +424,37 @@ func (b *betaToolRunnerBase) executeToolUse(ctx context.Context, toolUse BetaToo
 	}
 }
 
+// callTool executes tool, bounded by Params.ToolTimeout when one is set.
+func (b *betaToolRunnerBase) callTool(ctx context.Context, tool BetaTool, input json.RawMessage) ([]BetaToolResultBlockParamContentUnion, error) {
+	timeout := b.Params.ToolTimeout
+	if timeout <= 0 {
+		return tool.Execute(ctx, input)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type outcome struct {
+		content []BetaToolResultBlockParamContentUnion
+		err     error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		content, err := tool.Execute(ctx, input)
+		done <- outcome{content, err}
+	}()
+
+	select {
+	case out := <-done:
+		return out.content, out.err
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			return nil, fmt.Errorf("tool '%s' timed out after %s", tool.Name(), timeout)
+		}
+		return nil, ctx.Err()
+	}
+}
+
 // BetaToolRunner manages the automatic conversation loop between the assistant and tools
 // using non-streaming API calls. It implements an iterator pattern for processing
 // conversation turns.
@@ -248,42 +489,63 @@ func (r *BetaToolRunner) NextMessage(ctx context.Context) (*BetaMessage, error)
 		return nil, nil
 	}
 
-	// Check iteration limit
-	if r.Params.MaxIterations > 0 && r.iterationCount >= r.Params.MaxIterations {
-		r.completed = true
+	messageParams, done, err := r.prepareTurn(ctx)
+	if err != nil {
+		r.err = err
+		return nil, err
+	}
+	if done {
 		return r.lastMessage, nil
 	}
 
+	message, err := r.messageService.New(ctx, messageParams, r.opts...)
+	if err != nil {
+		r.err = err
+		return nil, fmt.Errorf("failed to get next message: %w", err)
+	}
+
+	if err := r.appendAssistantMessage(ctx, message); err != nil {
+		r.err = err
+		return nil, err
+	}
+
+	return message, nil
+}
+
+// prepareTurn readies the next API call, streaming or not: it completes the
+// conversation if the iteration limit is reached or the last message needs no
+// tool calls answered, and otherwise answers them, compacts the history and
+// returns the params for the call. done reports that the conversation is
+// complete and no call should be made.
+func (b *betaToolRunnerBase) prepareTurn(ctx context.Context) (params BetaMessageNewParams, done bool, err error) {
+	// Check iteration limit
+	if b.Params.MaxIterations > 0 && b.iterationCount >= b.Params.MaxIterations {
+		return params, true, b.complete(ctx)
+	}
+
 	// Execute any pending tool calls from the last message
-	if r.lastMessage != nil {
-		toolMessage, err := r.executeTools(ctx, r.lastMessage)
+	if b.lastMessage != nil && !b.toolsAnswered {
+		toolMessage, err := b.executeTools(ctx, b.lastMessage)
 		if err != nil {
-			r.err = err
-			return nil, err
+			return params, false, err
 		}
 		if toolMessage == nil {
 			// No tools to execute, conversation is complete
-			r.completed = true
-			return r.lastMessage, nil
+			return params, true, b.complete(ctx)
+		}
+		if err := b.appendToolResults(ctx, *toolMessage); err != nil {
+			return params, false, err
 		}
-		r.Params.Messages = append(r.Params.Messages, *toolMessage)
 	}
 
-	// Make API call
-	r.iterationCount++
-	messageParams := r.Params.BetaMessageNewParams
-	messageParams.Messages = r.Params.Messages
-
-	message, err := r.messageService.New(ctx, messageParams, r.opts...)
-	if err != nil {
-		r.err = err
-		return nil, fmt.Errorf("failed to get next message: %w", err)
+	if err := b.compactHistory(ctx); err != nil {
+		return params, false, err
 	}
 
-	r.lastMessage = message
-	r.Params.Messages = append(r.Params.Messages, message.ToParam())
-
-	return message, nil
+	b.iterationCount++
+	params = b.Params.BetaMessageNewParams
+	params.Messages = b.Params.Messages
+	return params, false, nil
 }
 
 // RunToCompletion repeatedly calls NextMessage until the conversation is complete,
@@ -368,33 +630,16 @@ func (r *BetaToolRunnerStreaming) NextStreaming(ctx context.Context) iter.Seq2[B
 			return
 		}
 
-		// Check iteration limit
-		if r.Params.MaxIterations > 0 && r.iterationCount >= r.Params.MaxIterations {
-			r.completed = true
+		streamParams, done, err := r.prepareTurn(ctx)
+		if err != nil {
+			r.err = err
+			yield(BetaRawMessageStreamEventUnion{}, err)
 			return
 		}
-
-		// Execute any pending tool calls from the last message
-		if r.lastMessage != nil {
-			toolMessage, err := r.executeTools(ctx, r.lastMessage)
-			if err != nil {
-				r.err = err
-				yield(BetaRawMessageStreamEventUnion{}, err)
-				return
-			}
-			if toolMessage == nil {
-				// No tools to execute, conversation is complete
-				r.completed = true
-				return
-			}
-			r.Params.Messages = append(r.Params.Messages, *toolMessage)
+		if done {
+			return
 		}
 
-		// Make streaming API call
-		r.iterationCount++
-		streamParams := r.Params.BetaMessageNewParams
-		streamParams.Messages = r.Params.Messages
-
 		stream := r.messageService.NewStreaming(ctx, streamParams, r.opts...)
 		defer stream.Close()
 
@@ -421,8 +666,10 @@ func (r *BetaToolRunnerStreaming) NextStreaming(ctx context.Context) iter.Seq2[B
 			return
 		}
 
-		r.lastMessage = finalMessage
-		r.Params.Messages = append(r.Params.Messages, finalMessage.ToParam())
+		if err := r.appendAssistantMessage(ctx, finalMessage); err != nil {
+			r.err = err
+			yield(BetaRawMessageStreamEventUnion{}, err)
+		}
 	}
 }
 
@@ -0,0 +1,261 @@
+package anthropic
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"slices"
+
+	"github.com/anthropics/anthropic-sdk-go/internal/stainlessheader"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"golang.org/x/sync/errgroup"
+)
+
+// BetaBatchToolRunnerParams configures a [BetaBatchToolRunner].
+type BetaBatchToolRunnerParams struct {
+	// Job configures how each turn's batch is split and polled. Betas sent
+	// with the batch requests are added to those of the conversations.
+	Job BetaMessageBatchJobParams
+	// MaxParallelConversations limits how many conversations run their tool
+	// calls at once between turns. When set to 0 (the default), all of them
+	// do.
+	MaxParallelConversations int
+}
+
+// BetaBatchToolRunner advances many tool runner conversations at once through
+// the Message Batches API, trading latency for the batch discount. Each model
+// turn of every active conversation is submitted in one batch job; when the
+// results come back, each conversation's tool calls run locally, as they
+// would for a [BetaToolRunner], and the next turn is submitted.
+//
+// Each conversation is a [BetaToolRunner], added with [BetaBatchToolRunner.Add],
+// whose params, hooks, checkpointing, compaction and usage tracking apply as
+// usual. Drive them with NextTurn or RunToCompletion rather than the runners'
+// own methods.
+//
+// A BetaBatchToolRunner is NOT safe for concurrent use.
+type BetaBatchToolRunner struct {
+	service *BetaMessageService
+	params  BetaBatchToolRunnerParams
+	opts    []option.RequestOption
+	ids     []string
+	runners map[string]*BetaToolRunner
+
+	// inflight is the turn whose batch job was submitted but whose results
+	// have not all been recorded, because waiting for them failed.
+	inflight *betaBatchTurn
+}
+
+type betaBatchTurn struct {
+	job      *BetaMessageBatchJob
+	requests []BetaMessageBatchJobRequest
+	answered map[string]bool
+}
+
+// BetaBatchTurnError is the error of a conversation whose batched turn did not
+// succeed: the request errored, expired or was canceled.
+type BetaBatchTurnError struct {
+	// ConversationID is the ID the conversation was added with.
+	ConversationID string
+	BatchID        string
+	Result         BetaMessageBatchResultUnion
+}
+
+func (e *BetaBatchTurnError) Error() string {
+	if e.Result.Type == "errored" {
+		return fmt.Sprintf("batched turn of conversation %s errored: %s", e.ConversationID, e.Result.Error.Error.Message)
+	}
+	return fmt.Sprintf("batched turn of conversation %s %s", e.ConversationID, e.Result.Type)
+}
+
+// NewBatchToolRunner creates a BetaBatchToolRunner with no conversations.
+func (r *BetaMessageService) NewBatchToolRunner(params BetaBatchToolRunnerParams, opts ...option.RequestOption) *BetaBatchToolRunner {
+	return &BetaBatchToolRunner{
+		service: r,
+		params:  params,
+		opts:    append([]option.RequestOption{stainlessheader.With(stainlessheader.BetaToolRunner)}, opts...),
+		runners: map[string]*BetaToolRunner{},
+	}
+}
+
+// Add starts a conversation with the given tools and params and returns its
+// runner. id identifies the conversation's requests in each batch, so it must
+// be unique and a valid custom_id: 1 to 64 letters, digits, hyphens and
+// underscores.
+func (b *BetaBatchToolRunner) Add(id string, tools []BetaTool, params BetaToolRunnerParams) (*BetaToolRunner, error) {
+	if !validBatchCustomID(id) {
+		return nil, fmt.Errorf("anthropic: invalid batch conversation ID %q: must be 1 to 64 letters, digits, hyphens and underscores", id)
+	}
+	if _, dup := b.runners[id]; dup {
+		return nil, fmt.Errorf("anthropic: duplicate batch conversation ID %q", id)
+	}
+	runner := b.service.NewToolRunner(tools, params, b.opts...)
+	b.runners[id] = runner
+	b.ids = append(b.ids, id)
+	return runner, nil
+}
+
+func validBatchCustomID(id string) bool {
+	if len(id) == 0 || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		if !('a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || '0' <= c && c <= '9' || c == '-' || c == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// Runner returns the runner of the conversation added with id, or nil.
+func (b *BetaBatchToolRunner) Runner(id string) *BetaToolRunner {
+	return b.runners[id]
+}
+
+// All yields each conversation's ID and runner, in the order they were added.
+func (b *BetaBatchToolRunner) All() iter.Seq2[string, *BetaToolRunner] {
+	return func(yield func(string, *BetaToolRunner) bool) {
+		for _, id := range b.ids {
+			if !yield(id, b.runners[id]) {
+				return
+			}
+		}
+	}
+}
+
+// NextTurn advances every active conversation by one turn: it answers the tool
+// calls of each conversation's last message, submits the next model turn of
+// those that continue as one batch job, waits for it to end and appends each
+// reply to its conversation. It returns the number of conversations whose turn
+// was submitted, which is 0 once every conversation has completed or failed.
+//
+// A conversation that fails — because a tool-call hook or checkpoint returned
+// an error, or its batched request did not succeed (see [BetaBatchTurnError])
+// — stops, with the error reported by its runner's Err method; the others
+// continue. NextTurn itself returns an error only if the batch job could not
+// be run. Calling NextTurn again retries the turn: if its batches were
+// created, it resumes waiting for them rather than submitting new ones.
+func (b *BetaBatchToolRunner) NextTurn(ctx context.Context) (int, error) {
+	if turn := b.inflight; turn != nil {
+		return len(turn.requests), b.collect(ctx, turn)
+	}
+
+	var active []string
+	for _, id := range b.ids {
+		if runner := b.runners[id]; !runner.completed && runner.err == nil {
+			active = append(active, id)
+		}
+	}
+
+	// Answer the previous turn's tool calls and prepare the next turn.
+	requests := make([]BetaMessageBatchJobRequest, len(active))
+	g, gctx := errgroup.WithContext(ctx)
+	if b.params.MaxParallelConversations > 0 {
+		g.SetLimit(b.params.MaxParallelConversations)
+	}
+	for i, id := range active {
+		runner := b.runners[id]
+		g.Go(func() error {
+			params, done, err := runner.prepareTurn(gctx)
+			switch {
+			case err != nil && gctx.Err() != nil:
+				return err
+			case err != nil:
+				runner.err = err
+			case !done:
+				requests[i] = BetaMessageBatchJobRequest{CustomID: id, Params: params}
+			}
+			return nil
+		})
+	}
+	err := g.Wait()
+	requests = slices.DeleteFunc(requests, func(req BetaMessageBatchJobRequest) bool { return req.CustomID == "" })
+	if err != nil {
+		// The prepared turns are not submitted; the next NextTurn prepares
+		// them again.
+		b.unprepare(requests)
+		return 0, err
+	}
+	return len(requests), b.runTurn(ctx, requests)
+}
+
+// unprepare undoes the iteration count of turns that were prepared but not
+// submitted. Their tool results stay in the conversations, so preparing the
+// turns again does not re-run their tools.
+func (b *BetaBatchToolRunner) unprepare(requests []BetaMessageBatchJobRequest) {
+	for _, req := range requests {
+		b.runners[req.CustomID].iterationCount--
+	}
+}
+
+// runTurn submits requests as a batch job and records each conversation's
+// reply.
+func (b *BetaBatchToolRunner) runTurn(ctx context.Context, requests []BetaMessageBatchJobRequest) error {
+	if len(requests) == 0 {
+		return nil
+	}
+	jobParams := b.params.Job
+	for _, req := range requests {
+		for _, beta := range req.Params.Betas {
+			if !slices.Contains(jobParams.Betas, beta) {
+				jobParams.Betas = append(jobParams.Betas, beta)
+			}
+		}
+	}
+
+	job, err := b.service.Batches.Submit(ctx, slices.Values(requests), jobParams, b.opts...)
+	if err != nil {
+		if job != nil {
+			err = fmt.Errorf("%w (cancel the created batches %v to avoid paying for them)", err, job.BatchIDs())
+		}
+		// No reply will arrive; the next NextTurn prepares the turn again.
+		b.unprepare(requests)
+		return fmt.Errorf("anthropic: batched tool runner turn failed: %w", err)
+	}
+	return b.collect(ctx, &betaBatchTurn{job: job, requests: requests, answered: map[string]bool{}})
+}
+
+// collect waits for turn's batch job and records each conversation's reply. If
+// waiting fails, turn is kept so that the next NextTurn resumes it.
+func (b *BetaBatchToolRunner) collect(ctx context.Context, turn *betaBatchTurn) error {
+	b.inflight = turn
+	for result, err := range turn.job.Results(ctx) {
+		if err != nil {
+			return fmt.Errorf("anthropic: batched tool runner turn failed: %w", err)
+		}
+		runner, ok := b.runners[result.CustomID]
+		if !ok || turn.answered[result.CustomID] {
+			continue
+		}
+		turn.answered[result.CustomID] = true
+		if result.Result.Type != "succeeded" {
+			runner.err = &BetaBatchTurnError{ConversationID: result.CustomID, BatchID: result.BatchID, Result: result.Result}
+			continue
+		}
+		message := result.Result.AsSucceeded().Message
+		if err := runner.appendAssistantMessage(ctx, &message); err != nil {
+			runner.err = err
+		}
+	}
+	b.inflight = nil
+	for _, req := range turn.requests {
+		if !turn.answered[req.CustomID] {
+			b.runners[req.CustomID].err = fmt.Errorf("anthropic: batch job returned no result for conversation %s", req.CustomID)
+		}
+	}
+	return nil
+}
+
+// RunToCompletion calls NextTurn until every conversation has completed or
+// failed. Check each runner's LastMessage and Err for its outcome.
+func (b *BetaBatchToolRunner) RunToCompletion(ctx context.Context) error {
+	for {
+		n, err := b.NextTurn(ctx)
+		if err != nil {
+			return err
+		}
+		if n == 0 {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,361 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// BetaHistoryCompactor shortens a tool runner's conversation history before it
+// is sent to the model. Set it on [BetaToolRunnerParams.HistoryCompactor].
+//
+// Implementations must return a history the API accepts: every tool_use block
+// that is kept must still be answered by a tool_result block in the following
+// user message, and assistant messages, including their thinking blocks, must
+// be kept whole or dropped whole. The built-in strategies only drop complete
+// assistant/user exchanges or replace tool_result content, which preserves both.
+type BetaHistoryCompactor interface {
+	// CompactHistory returns the compacted history, or nil to leave the
+	// history unchanged. It must not modify history.Params.Messages in place.
+	CompactHistory(ctx context.Context, history BetaHistoryCompaction) ([]BetaMessageParam, error)
+}
+
+// BetaHistoryCompactorFunc adapts a function to a [BetaHistoryCompactor].
+type BetaHistoryCompactorFunc func(ctx context.Context, history BetaHistoryCompaction) ([]BetaMessageParam, error)
+
+// CompactHistory implements [BetaHistoryCompactor].
+func (f BetaHistoryCompactorFunc) CompactHistory(ctx context.Context, history BetaHistoryCompaction) ([]BetaMessageParam, error) {
+	return f(ctx, history)
+}
+
+// BetaHistoryCompaction is the input to a [BetaHistoryCompactor].
+type BetaHistoryCompaction struct {
+	// Params is the request about to be sent. Params.Messages is the full
+	// conversation history.
+	Params BetaMessageNewParams
+	// LastMessage is the most recent assistant response, or nil before the
+	// first API call. Its Usage reports how much of the context window the
+	// previous request used.
+	LastMessage *BetaMessage
+	// Messages is the service the runner uses, for strategies that need to
+	// make side calls such as summarizing or counting tokens.
+	Messages *BetaMessageService
+	// Opts are the request options the runner uses.
+	Opts []option.RequestOption
+}
+
+// compactHistory applies Params.HistoryCompactor before an API call.
+func (b *betaToolRunnerBase) compactHistory(ctx context.Context) error {
+	if b.Params.HistoryCompactor == nil {
+		return nil
+	}
+	params := b.Params.BetaMessageNewParams
+	params.Messages = b.Messages()
+	messages, err := b.Params.HistoryCompactor.CompactHistory(ctx, BetaHistoryCompaction{
+		Params:      params,
+		LastMessage: b.lastMessage,
+		Messages:    b.messageService,
+		Opts:        b.opts,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to compact conversation history: %w", err)
+	}
+	if messages == nil {
+		return nil
+	}
+	b.Params.Messages = messages
+	return b.checkpoint(ctx)
+}
+
+// betaContextTokens returns the number of tokens the next request will carry
+// over from the response with the given usage.
+func betaContextTokens(usage BetaUsage) int64 {
+	return usage.InputTokens + usage.CacheCreationInputTokens + usage.CacheReadInputTokens + usage.OutputTokens
+}
+
+// betaExchangeStarts returns the indices of the assistant messages in
+// messages. Each one starts an exchange: the assistant turn and the user turn
+// that answers it.
+func betaExchangeStarts(messages []BetaMessageParam) []int {
+	var starts []int
+	for i, msg := range messages {
+		if msg.Role == BetaMessageParamRoleAssistant {
+			starts = append(starts, i)
+		}
+	}
+	return starts
+}
+
+func betaHasToolResult(msg BetaMessageParam) bool {
+	for _, block := range msg.Content {
+		if block.OfToolResult != nil {
+			return true
+		}
+	}
+	return false
+}
+
+// DefaultToolResultPlaceholder replaces the content of tool results dropped
+// by [BetaDropOldToolResults].
+const DefaultToolResultPlaceholder = "[Tool result removed to save context]"
+
+// BetaDropOldToolResults is a [BetaHistoryCompactor] that replaces the content
+// of tool results older than the most recent KeepTurns tool-result turns with
+// a short placeholder. The tool_result blocks themselves stay, so every
+// tool_use remains answered.
+//
+// Tool output is usually the bulk of a long tool-use conversation and is
+// rarely needed again once the model has acted on it. Rewriting old messages
+// invalidates any prompt cache covering them.
+type BetaDropOldToolResults struct {
+	// KeepTurns is the number of most recent tool-result turns left intact.
+	// Values below 1 are treated as 1.
+	KeepTurns int
+	// Placeholder is the text old tool results are replaced with. Defaults to
+	// [DefaultToolResultPlaceholder].
+	Placeholder string
+}
+
+// CompactHistory implements [BetaHistoryCompactor].
+func (c BetaDropOldToolResults) CompactHistory(ctx context.Context, history BetaHistoryCompaction) ([]BetaMessageParam, error) {
+	keep := max(c.KeepTurns, 1)
+	placeholder := c.Placeholder
+	if placeholder == "" {
+		placeholder = DefaultToolResultPlaceholder
+	}
+
+	messages := history.Params.Messages
+	var compacted []BetaMessageParam
+	seen := 0
+	for i := len(messages) - 1; i >= 0; i-- {
+		msg := messages[i]
+		if msg.Role != BetaMessageParamRoleUser || !betaHasToolResult(msg) {
+			continue
+		}
+		seen++
+		if seen <= keep {
+			continue
+		}
+
+		content := make([]BetaContentBlockParamUnion, len(msg.Content))
+		changed := false
+		for j, block := range msg.Content {
+			content[j] = block
+			result := block.OfToolResult
+			if result == nil || betaIsPlaceholderResult(*result, placeholder) {
+				continue
+			}
+			dropped := BetaToolResultBlockParam{
+				ToolUseID: result.ToolUseID,
+				IsError:   result.IsError,
+				Content: []BetaToolResultBlockParamContentUnion{{
+					OfText: &BetaTextBlockParam{Text: placeholder},
+				}},
+			}
+			content[j] = BetaContentBlockParamUnion{OfToolResult: &dropped}
+			changed = true
+		}
+		if !changed {
+			continue
+		}
+		if compacted == nil {
+			compacted = append([]BetaMessageParam{}, messages...)
+		}
+		msg.Content = content
+		compacted[i] = msg
+	}
+	return compacted, nil
+}
+
+func betaIsPlaceholderResult(result BetaToolResultBlockParam, placeholder string) bool {
+	return len(result.Content) == 1 && result.Content[0].OfText != nil && result.Content[0].OfText.Text == placeholder
+}
+
+// DefaultSummaryPrompt asks the model for the summary that
+// [BetaSummarizeHistory] replaces older turns with.
+const DefaultSummaryPrompt = "Summarize the conversation so far so that the task can be continued from the summary alone. " +
+	"Include the original request, decisions made, important facts and tool results, and what remains to be done. " +
+	"Reply with the summary only."
+
+// BetaSummarizeHistory is a [BetaHistoryCompactor] that, once the conversation
+// reaches TriggerTokens, replaces all but the most recent KeepTurns exchanges
+// with a summary written by the model in a side call.
+type BetaSummarizeHistory struct {
+	// TriggerTokens is the context size, measured from the usage of the most
+	// recent response, at which older turns are summarized. Zero disables
+	// summarization.
+	TriggerTokens int64
+	// KeepTurns is the number of most recent assistant/user exchanges kept
+	// verbatim. Values below 1 are treated as 1.
+	KeepTurns int
+	// Model is the model that writes the summary. Defaults to the runner's
+	// model.
+	Model Model
+	// MaxTokens bounds the summary. Defaults to 2048.
+	MaxTokens int64
+	// Prompt is the instruction sent with the turns to summarize. Defaults to
+	// [DefaultSummaryPrompt].
+	Prompt string
+}
+
+// CompactHistory implements [BetaHistoryCompactor].
+func (c BetaSummarizeHistory) CompactHistory(ctx context.Context, history BetaHistoryCompaction) ([]BetaMessageParam, error) {
+	if c.TriggerTokens <= 0 || history.LastMessage == nil || betaContextTokens(history.LastMessage.Usage) < c.TriggerTokens {
+		return nil, nil
+	}
+	messages := history.Params.Messages
+	starts := betaExchangeStarts(messages)
+	keep := max(c.KeepTurns, 1)
+	if len(starts) <= keep {
+		return nil, nil
+	}
+	// The kept suffix starts with an assistant turn, so it never begins with
+	// tool results whose tool_use blocks were summarized away.
+	cut := starts[len(starts)-keep]
+	if cut < 2 {
+		return nil, nil
+	}
+
+	summary, err := c.summarize(ctx, history, messages[:cut])
+	if err != nil {
+		return nil, err
+	}
+	compacted := make([]BetaMessageParam, 0, len(messages)-cut+1)
+	compacted = append(compacted, NewBetaUserMessage(NewBetaTextBlock("Summary of the earlier conversation:\n\n"+summary)))
+	return append(compacted, messages[cut:]...), nil
+}
+
+func (c BetaSummarizeHistory) summarize(ctx context.Context, history BetaHistoryCompaction, messages []BetaMessageParam) (string, error) {
+	prompt := c.Prompt
+	if prompt == "" {
+		prompt = DefaultSummaryPrompt
+	}
+	model := c.Model
+	if model == "" {
+		model = history.Params.Model
+	}
+	maxTokens := c.MaxTokens
+	if maxTokens <= 0 {
+		maxTokens = 2048
+	}
+
+	// Add the instruction to the final user turn rather than a new message,
+	// keeping the turns alternating.
+	messages = append([]BetaMessageParam{}, messages...)
+	last := messages[len(messages)-1]
+	last.Content = append(append([]BetaContentBlockParamUnion{}, last.Content...), NewBetaTextBlock(prompt))
+	messages[len(messages)-1] = last
+
+	params := BetaMessageNewParams{
+		Model:     model,
+		MaxTokens: maxTokens,
+		Messages:  messages,
+		System:    history.Params.System,
+		Betas:     history.Params.Betas,
+	}
+	// The turns being summarized reference the runner's tools, so the request
+	// must declare them, but the model must not call them.
+	if len(history.Params.Tools) > 0 {
+		params.Tools = history.Params.Tools
+		params.ToolChoice = BetaToolChoiceUnionParam{OfNone: &BetaToolChoiceNoneParam{}}
+	}
+
+	resp, err := history.Messages.New(ctx, params, history.Opts...)
+	if err != nil {
+		return "", fmt.Errorf("failed to summarize conversation history: %w", err)
+	}
+	var text strings.Builder
+	for _, block := range resp.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	if text.Len() == 0 {
+		return "", fmt.Errorf("failed to summarize conversation history: model returned no text")
+	}
+	return text.String(), nil
+}
+
+// BetaTrimHistoryToTokenBudget is a [BetaHistoryCompactor] that drops the
+// oldest assistant/user exchanges, keeping the first message, until the
+// request fits in MaxInputTokens as measured by
+// [BetaMessageService.CountTokens]. It counts tokens before every API call
+// the runner makes.
+type BetaTrimHistoryToTokenBudget struct {
+	// MaxInputTokens is the token budget for the request's input, including
+	// the system prompt and tool definitions. Zero disables trimming.
+	MaxInputTokens int64
+	// KeepTurns is the number of most recent exchanges that are never dropped,
+	// even if the request still exceeds the budget. Values below 1 are
+	// treated as 1.
+	KeepTurns int
+}
+
+// CompactHistory implements [BetaHistoryCompactor].
+func (c BetaTrimHistoryToTokenBudget) CompactHistory(ctx context.Context, history BetaHistoryCompaction) ([]BetaMessageParam, error) {
+	if c.MaxInputTokens <= 0 {
+		return nil, nil
+	}
+	countParams, err := betaCountTokensParams(history.Params)
+	if err != nil {
+		return nil, err
+	}
+	keep := max(c.KeepTurns, 1)
+
+	messages := history.Params.Messages
+	trimmed := false
+	for {
+		countParams.Messages = messages
+		count, err := history.Messages.CountTokens(ctx, countParams, history.Opts...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to count conversation tokens: %w", err)
+		}
+		starts := betaExchangeStarts(messages)
+		if count.InputTokens <= c.MaxInputTokens || len(starts) <= keep {
+			break
+		}
+		// Drop the oldest exchange: its assistant turn and the tool results
+		// answering it.
+		messages = append(append([]BetaMessageParam{}, messages[:starts[0]]...), messages[starts[1]:]...)
+		trimmed = true
+	}
+	if !trimmed {
+		return nil, nil
+	}
+	return messages, nil
+}
+
+// betaCountTokensParams converts a message request into the equivalent token
+// counting request.
+func betaCountTokensParams(params BetaMessageNewParams) (BetaMessageCountTokensParams, error) {
+	count := BetaMessageCountTokensParams{
+		Messages:          params.Messages,
+		Model:             params.Model,
+		CacheControl:      params.CacheControl,
+		ContextManagement: params.ContextManagement,
+		MCPServers:        params.MCPServers,
+		OutputConfig:      params.OutputConfig,
+		OutputFormat:      params.OutputFormat,
+		Thinking:          params.Thinking,
+		ToolChoice:        params.ToolChoice,
+		Betas:             params.Betas,
+	}
+	if len(params.System) > 0 {
+		count.System = BetaMessageCountTokensParamsSystemUnion{OfBetaTextBlockArray: params.System}
+	}
+	for _, tool := range params.Tools {
+		data, err := json.Marshal(tool)
+		if err != nil {
+			return count, err
+		}
+		var countTool BetaMessageCountTokensParamsToolUnion
+		if err := json.Unmarshal(data, &countTool); err != nil {
+			return count, fmt.Errorf("failed to convert tool for token counting: %w", err)
+		}
+		count.Tools = append(count.Tools, countTool)
+	}
+	return count, nil
+}
@@ -0,0 +1,330 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// ErrConversationNotFound is returned by a [ConversationStore] when it holds no
+// checkpoint for the requested conversation.
+var ErrConversationNotFound = errors.New("anthropic: conversation checkpoint not found")
+
+// ConversationStore persists [BetaToolRunner] and [BetaToolRunnerStreaming]
+// checkpoints. Implementations must be safe for concurrent use: the runner
+// saves a checkpoint from each tool call goroutine as results arrive.
+type ConversationStore interface {
+	// Save replaces the stored checkpoint for conversationID.
+	Save(ctx context.Context, conversationID string, checkpoint ConversationCheckpoint) error
+	// Load returns the stored checkpoint for conversationID, or an error
+	// wrapping [ErrConversationNotFound] if there is none.
+	Load(ctx context.Context, conversationID string) (ConversationCheckpoint, error)
+}
+
+// ConversationCheckpoint is a snapshot of a tool runner's conversation, taken
+// after each model turn and each tool result.
+type ConversationCheckpoint struct {
+	// Messages is the conversation history, including the latest assistant
+	// turn and any tool results sent back for it.
+	Messages []BetaMessageParam `json:"messages"`
+	// IterationCount is the number of API calls made so far.
+	IterationCount int `json:"iteration_count"`
+	// StopReason is the stop reason of the latest assistant turn.
+	StopReason BetaStopReason `json:"stop_reason,omitzero"`
+	// Completed reports whether the conversation has finished.
+	Completed bool `json:"completed,omitzero"`
+	// ToolResults holds the results already produced for the latest assistant
+	// turn's tool calls while that turn's tool batch is still in progress. A
+	// resumed runner reuses them instead of running those tools again.
+	ToolResults []BetaToolResultBlockParam `json:"tool_results,omitzero"`
+	// Usage is the runner's cumulative usage, so a resumed runner keeps
+	// accounting for the calls made before the checkpoint.
+	Usage BetaToolRunnerUsage `json:"usage,omitzero"`
+}
+
+// ResumeToolRunner rebuilds a [BetaToolRunner] from the checkpoint stored
+// under params.ConversationID in params.ConversationStore. The checkpointed
+// history replaces params.Messages; the other params, the tools, and opts are
+// used as given, so pass the same ones the original runner was created with.
+//
+// The resumed runner continues where the checkpoint left off: tool calls from
+// the last assistant turn that were already answered are not executed again.
+// Its LastMessage is rebuilt from the history and carries no ID or usage.
+func (r *BetaMessageService) ResumeToolRunner(ctx context.Context, tools []BetaTool, params BetaToolRunnerParams, opts ...option.RequestOption) (*BetaToolRunner, error) {
+	base, err := resumeBetaToolRunnerBase(ctx, r, tools, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &BetaToolRunner{betaToolRunnerBase: base}, nil
+}
+
+// ResumeToolRunnerStreaming is the streaming counterpart of
+// [BetaMessageService.ResumeToolRunner].
+func (r *BetaMessageService) ResumeToolRunnerStreaming(ctx context.Context, tools []BetaTool, params BetaToolRunnerParams, opts ...option.RequestOption) (*BetaToolRunnerStreaming, error) {
+	base, err := resumeBetaToolRunnerBase(ctx, r, tools, params, opts)
+	if err != nil {
+		return nil, err
+	}
+	return &BetaToolRunnerStreaming{betaToolRunnerBase: base}, nil
+}
+
+func resumeBetaToolRunnerBase(ctx context.Context, messageService *BetaMessageService, tools []BetaTool, params BetaToolRunnerParams, opts []option.RequestOption) (betaToolRunnerBase, error) {
+	if params.ConversationStore == nil || params.ConversationID == "" {
+		return betaToolRunnerBase{}, fmt.Errorf("anthropic: ResumeToolRunner requires ConversationStore and ConversationID")
+	}
+	checkpoint, err := params.ConversationStore.Load(ctx, params.ConversationID)
+	if err != nil {
+		return betaToolRunnerBase{}, fmt.Errorf("failed to load conversation %q: %w", params.ConversationID, err)
+	}
+
+	params.Messages = checkpoint.Messages
+	base := newBetaToolRunnerBase(messageService, tools, params, opts)
+	base.iterationCount = checkpoint.IterationCount
+	base.completed = checkpoint.Completed
+	base.usage = checkpoint.Usage
+
+	// Rebuild the latest assistant turn so its tool calls can be answered.
+	for i := len(checkpoint.Messages) - 1; i >= 0; i-- {
+		msg := checkpoint.Messages[i]
+		if msg.Role != BetaMessageParamRoleAssistant {
+			continue
+		}
+		base.lastMessage, err = betaMessageFromParam(msg, checkpoint.StopReason)
+		if err != nil {
+			return betaToolRunnerBase{}, fmt.Errorf("failed to restore conversation %q: %w", params.ConversationID, err)
+		}
+		// Anything after the assistant turn is the tool results sent for it.
+		base.toolsAnswered = i < len(checkpoint.Messages)-1
+		break
+	}
+
+	if len(checkpoint.ToolResults) > 0 {
+		base.toolResults = make(map[string]BetaToolResultBlockParam, len(checkpoint.ToolResults))
+		for _, result := range checkpoint.ToolResults {
+			base.toolResults[result.ToolUseID] = result
+		}
+	}
+	return base, nil
+}
+
+// betaMessageFromParam converts an assistant message param back into the
+// response shape the runner executes tool calls from.
+func betaMessageFromParam(param BetaMessageParam, stopReason BetaStopReason) (*BetaMessage, error) {
+	data, err := json.Marshal(param)
+	if err != nil {
+		return nil, err
+	}
+	message := &BetaMessage{}
+	if err := message.UnmarshalJSON(data); err != nil {
+		return nil, err
+	}
+	message.StopReason = stopReason
+	return message, nil
+}
+
+// checkpoint saves the runner's state to Params.ConversationStore. It is a
+// no-op unless both ConversationStore and ConversationID are set.
+func (b *betaToolRunnerBase) checkpoint(ctx context.Context) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.checkpointLocked(ctx)
+}
+
+func (b *betaToolRunnerBase) checkpointLocked(ctx context.Context) error {
+	if b.Params.ConversationStore == nil || b.Params.ConversationID == "" {
+		return nil
+	}
+	checkpoint := ConversationCheckpoint{
+		Messages:       b.Messages(),
+		IterationCount: b.iterationCount,
+		Completed:      b.completed,
+		Usage:          b.usage,
+	}
+	if b.lastMessage != nil {
+		checkpoint.StopReason = b.lastMessage.StopReason
+	}
+	for _, result := range b.toolResults {
+		checkpoint.ToolResults = append(checkpoint.ToolResults, result)
+	}
+	if err := b.Params.ConversationStore.Save(ctx, b.Params.ConversationID, checkpoint); err != nil {
+		return fmt.Errorf("failed to checkpoint conversation %q: %w", b.Params.ConversationID, err)
+	}
+	return nil
+}
+
+// answeredToolUse returns the result already recorded for a tool call.
+func (b *betaToolRunnerBase) answeredToolUse(toolUseID string) (BetaToolResultBlockParam, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	result, ok := b.toolResults[toolUseID]
+	return result, ok
+}
+
+// recordToolResult records the result of one tool call and checkpoints it, so
+// a resumed runner does not execute the call again.
+func (b *betaToolRunnerBase) recordToolResult(ctx context.Context, result BetaToolResultBlockParam) error {
+	if b.Params.ConversationStore == nil || b.Params.ConversationID == "" {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.toolResults == nil {
+		b.toolResults = make(map[string]BetaToolResultBlockParam)
+	}
+	b.toolResults[result.ToolUseID] = result
+	return b.checkpointLocked(ctx)
+}
+
+// appendToolResults adds the tool result message for lastMessage to the
+// history and checkpoints it.
+func (b *betaToolRunnerBase) appendToolResults(ctx context.Context, toolMessage BetaMessageParam) error {
+	b.Params.Messages = append(b.Params.Messages, toolMessage)
+	b.toolsAnswered = true
+	b.toolResults = nil
+	return b.checkpoint(ctx)
+}
+
+// appendAssistantMessage records a new assistant turn and checkpoints it.
+func (b *betaToolRunnerBase) appendAssistantMessage(ctx context.Context, message *BetaMessage) error {
+	b.lastMessage = message
+	b.recordUsage(message)
+	b.toolsAnswered = false
+	b.Params.Messages = append(b.Params.Messages, message.ToParam())
+	return b.checkpoint(ctx)
+}
+
+// complete marks the conversation finished and checkpoints it.
+func (b *betaToolRunnerBase) complete(ctx context.Context) error {
+	b.completed = true
+	return b.checkpoint(ctx)
+}
+
+// MemoryConversationStore is an in-memory [ConversationStore]. It is useful
+// for tests and for keeping checkpoints only for the lifetime of the process.
+type MemoryConversationStore struct {
+	mu            sync.Mutex
+	conversations map[string][]byte
+}
+
+// NewMemoryConversationStore returns an empty [MemoryConversationStore].
+func NewMemoryConversationStore() *MemoryConversationStore {
+	return &MemoryConversationStore{conversations: make(map[string][]byte)}
+}
+
+// Save implements [ConversationStore]. The checkpoint is stored in serialized
+// form so later changes by the caller do not alias it.
+func (s *MemoryConversationStore) Save(ctx context.Context, conversationID string, checkpoint ConversationCheckpoint) error {
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.conversations[conversationID] = data
+	return nil
+}
+
+// Load implements [ConversationStore].
+func (s *MemoryConversationStore) Load(ctx context.Context, conversationID string) (ConversationCheckpoint, error) {
+	s.mu.Lock()
+	data, ok := s.conversations[conversationID]
+	s.mu.Unlock()
+	if !ok {
+		return ConversationCheckpoint{}, ErrConversationNotFound
+	}
+	var checkpoint ConversationCheckpoint
+	err := json.Unmarshal(data, &checkpoint)
+	return checkpoint, err
+}
+
+// FileConversationStore is a [ConversationStore] that keeps each
+// conversation's checkpoint in Dir as <conversation ID>.json. Writes are
+// atomic, so a crash mid-save leaves the previous checkpoint intact.
+type FileConversationStore struct {
+	Dir string
+	mu  sync.Mutex
+}
+
+// NewFileConversationStore returns a [FileConversationStore] rooted at dir,
+// which is created on first save if it does not exist.
+func NewFileConversationStore(dir string) *FileConversationStore {
+	return &FileConversationStore{Dir: dir}
+}
+
+func (s *FileConversationStore) path(conversationID string) (string, error) {
+	if conversationID == "" || conversationID == "." || conversationID == ".." || strings.ContainsAny(conversationID, `/\`) {
+		return "", fmt.Errorf("anthropic: invalid conversation ID %q", conversationID)
+	}
+	return filepath.Join(s.Dir, conversationID+".json"), nil
+}
+
+// Save implements [ConversationStore].
+func (s *FileConversationStore) Save(ctx context.Context, conversationID string, checkpoint ConversationCheckpoint) error {
+	path, err := s.path(conversationID)
+	if err != nil {
+		return err
+	}
+	data, err := json.Marshal(checkpoint)
+	if err != nil {
+		return err
+	}
+
+	// Serialize writers so checkpoints land in the order they were taken.
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.Dir, 0700); err != nil {
+		return err
+	}
+	f, err := os.CreateTemp(s.Dir, filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// Load implements [ConversationStore].
+func (s *FileConversationStore) Load(ctx context.Context, conversationID string) (ConversationCheckpoint, error) {
+	path, err := s.path(conversationID)
+	if err != nil {
+		return ConversationCheckpoint{}, err
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return ConversationCheckpoint{}, fmt.Errorf("%w: %s", ErrConversationNotFound, path)
+	}
+	if err != nil {
+		return ConversationCheckpoint{}, err
+	}
+	var checkpoint ConversationCheckpoint
+	if err := json.Unmarshal(data, &checkpoint); err != nil {
+		return ConversationCheckpoint{}, fmt.Errorf("failed to parse conversation checkpoint %s: %w", path, err)
+	}
+	return checkpoint, nil
+}
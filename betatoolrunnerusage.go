@@ -0,0 +1,146 @@
+package anthropic
+
+import "strings"
+
+// BetaUsageTotals sums the token and server tool usage of one or more
+// responses.
+type BetaUsageTotals struct {
+	InputTokens              int64 `json:"input_tokens"`
+	OutputTokens             int64 `json:"output_tokens"`
+	CacheCreationInputTokens int64 `json:"cache_creation_input_tokens"`
+	CacheReadInputTokens     int64 `json:"cache_read_input_tokens"`
+	WebSearchRequests        int64 `json:"web_search_requests"`
+	WebFetchRequests         int64 `json:"web_fetch_requests"`
+}
+
+// Add adds the usage reported by a response.
+func (t *BetaUsageTotals) Add(usage BetaUsage) {
+	t.InputTokens += usage.InputTokens
+	t.OutputTokens += usage.OutputTokens
+	t.CacheCreationInputTokens += usage.CacheCreationInputTokens
+	t.CacheReadInputTokens += usage.CacheReadInputTokens
+	t.WebSearchRequests += usage.ServerToolUse.WebSearchRequests
+	t.WebFetchRequests += usage.ServerToolUse.WebFetchRequests
+}
+
+// Cost returns the estimated cost of this usage at the given prices.
+func (t BetaUsageTotals) Cost(pricing ModelPricing) float64 {
+	const million = 1_000_000
+	return float64(t.InputTokens)*pricing.InputPerMTok/million +
+		float64(t.OutputTokens)*pricing.OutputPerMTok/million +
+		float64(t.CacheCreationInputTokens)*pricing.CacheWritePerMTok/million +
+		float64(t.CacheReadInputTokens)*pricing.CacheReadPerMTok/million +
+		float64(t.WebSearchRequests)*pricing.WebSearchPerRequest +
+		float64(t.WebFetchRequests)*pricing.WebFetchPerRequest
+}
+
+// ModelPricing holds the prices used to estimate the cost of a model's usage,
+// in any currency as long as one table uses the same one throughout. The SDK
+// ships no prices; fill them in from your rate card.
+type ModelPricing struct {
+	// InputPerMTok is the price of one million uncached input tokens.
+	InputPerMTok float64 `json:"input_per_mtok"`
+	// OutputPerMTok is the price of one million output tokens.
+	OutputPerMTok float64 `json:"output_per_mtok"`
+	// CacheWritePerMTok is the price of one million tokens written to the
+	// prompt cache.
+	CacheWritePerMTok float64 `json:"cache_write_per_mtok"`
+	// CacheReadPerMTok is the price of one million tokens read from the prompt
+	// cache.
+	CacheReadPerMTok float64 `json:"cache_read_per_mtok"`
+	// WebSearchPerRequest is the price of one server-side web search.
+	WebSearchPerRequest float64 `json:"web_search_per_request"`
+	// WebFetchPerRequest is the price of one server-side web fetch.
+	WebFetchPerRequest float64 `json:"web_fetch_per_request"`
+}
+
+// BetaToolRunnerIterationUsage is the usage of a single API call made by a
+// tool runner.
+type BetaToolRunnerIterationUsage struct {
+	// Iteration is the 1-based number of the API call.
+	Iteration int             `json:"iteration"`
+	MessageID string          `json:"message_id"`
+	Model     Model           `json:"model"`
+	Usage     BetaUsageTotals `json:"usage"`
+	// EstimatedCost is the cost of this call from
+	// [BetaToolRunnerParams.Pricing]. It is only meaningful when Priced is
+	// true.
+	EstimatedCost float64 `json:"estimated_cost,omitzero"`
+	// Priced reports whether the pricing table had an entry for Model.
+	Priced bool `json:"priced,omitzero"`
+}
+
+// BetaToolRunnerUsage is the cumulative usage of a tool runner's API calls.
+type BetaToolRunnerUsage struct {
+	Iterations []BetaToolRunnerIterationUsage `json:"iterations"`
+	Total      BetaUsageTotals                `json:"total"`
+	// EstimatedCost is the sum of the estimated cost of every priced
+	// iteration.
+	EstimatedCost float64 `json:"estimated_cost,omitzero"`
+	// UnpricedIterations counts the iterations whose model had no entry in
+	// [BetaToolRunnerParams.Pricing], and which EstimatedCost therefore
+	// leaves out.
+	UnpricedIterations int `json:"unpriced_iterations,omitzero"`
+}
+
+// Usage returns the cumulative token usage of the runner's API calls so far,
+// per iteration and in total. Side calls made by a
+// [BetaToolRunnerParams.HistoryCompactor] are not included.
+func (b *betaToolRunnerBase) Usage() BetaToolRunnerUsage {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	usage := b.usage
+	usage.Iterations = append([]BetaToolRunnerIterationUsage(nil), b.usage.Iterations...)
+	return usage
+}
+
+// recordUsage adds message's usage to the runner's totals.
+func (b *betaToolRunnerBase) recordUsage(message *BetaMessage) {
+	iteration := BetaToolRunnerIterationUsage{
+		Iteration: b.iterationCount,
+		MessageID: message.ID,
+		Model:     message.Model,
+	}
+	iteration.Usage.Add(message.Usage)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.usage.Total.Add(message.Usage)
+	if b.Params.Pricing != nil {
+		if pricing, ok := lookupPricing(b.Params.Pricing, message.Model); ok {
+			iteration.EstimatedCost = iteration.Usage.Cost(pricing)
+			iteration.Priced = true
+			b.usage.EstimatedCost += iteration.EstimatedCost
+		} else {
+			b.usage.UnpricedIterations++
+		}
+	}
+	b.usage.Iterations = append(b.usage.Iterations, iteration)
+}
+
+// lookupPricing returns the prices of model. The API reports the dated ID of
+// the model that served a call, such as "claude-sonnet-4-5-20250929", so a
+// dated ID missing from pricing falls back to its alias, "claude-sonnet-4-5".
+func lookupPricing(pricing map[Model]ModelPricing, model Model) (ModelPricing, bool) {
+	if p, ok := pricing[model]; ok {
+		return p, true
+	}
+	if i := strings.LastIndexByte(model, '-'); i >= 0 && isSnapshotDate(model[i+1:]) {
+		p, ok := pricing[model[:i]]
+		return p, ok
+	}
+	return ModelPricing{}, false
+}
+
+// isSnapshotDate reports whether s is a YYYYMMDD model snapshot date.
+func isSnapshotDate(s string) bool {
+	if len(s) != 8 {
+		return false
+	}
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,482 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/internal/requestconfig"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	standardwebhooks "github.com/standard-webhooks/standard-webhooks/libraries/go"
+)
+
+// Defaults of [BetaWebhookHandlerParams].
+const (
+	DefaultWebhookMaxBodyBytes = 1 << 20
+	DefaultWebhookTolerance    = 5 * time.Minute
+)
+
+// BetaWebhookHandlerParams configures a [BetaWebhookHandler]: how deliveries
+// are verified and deduplicated, and the callbacks events are dispatched to.
+//
+// Each event goes to the callback for its type, such as OnDeploymentRunFailed.
+// Session status events without one go to OnSessionStatusChanged, and any
+// other event without one to OnEvent. Events with no callback at all are
+// acknowledged and dropped.
+//
+// A callback returning an error fails the delivery with a 500, so that it is
+// retried. Callbacks may be called concurrently.
+type BetaWebhookHandlerParams struct {
+	// MaxBodyBytes caps the size of a delivery's body. Defaults to
+	// [DefaultWebhookMaxBodyBytes].
+	MaxBodyBytes int64
+	// Tolerance is how far a delivery's webhook-timestamp may be from the
+	// current time, in either direction. Defaults to [DefaultWebhookTolerance].
+	Tolerance time.Duration
+	// DedupeStore, if set, records the webhook-id of each delivery so that
+	// redeliveries of an event that was already handled are acknowledged
+	// without calling the callbacks again.
+	DedupeStore WebhookDedupeStore
+	// OnError, if set, is called with the reason each delivery was rejected or
+	// failed, for logging.
+	OnError func(r *http.Request, err error)
+
+	// OnEvent is called for events that have no typed callback.
+	OnEvent func(ctx context.Context, event *UnwrapWebhookEvent) error
+	// OnSessionStatusChanged is called for the session.status_* events that
+	// have no typed callback.
+	OnSessionStatusChanged func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookEventDataUnion) error
+
+	OnSessionCreated                func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionCreatedEventData) error
+	OnSessionPending                func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionPendingEventData) error
+	OnSessionRunning                func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionRunningEventData) error
+	OnSessionIdled                  func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionIdledEventData) error
+	OnSessionRequiresAction         func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionRequiresActionEventData) error
+	OnSessionArchived               func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionArchivedEventData) error
+	OnSessionDeleted                func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionDeletedEventData) error
+	OnSessionStatusRescheduled      func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionStatusRescheduledEventData) error
+	OnSessionStatusRunStarted       func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionStatusRunStartedEventData) error
+	OnSessionStatusIdled            func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionStatusIdledEventData) error
+	OnSessionStatusTerminated       func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionStatusTerminatedEventData) error
+	OnSessionThreadCreated          func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionThreadCreatedEventData) error
+	OnSessionThreadIdled            func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionThreadIdledEventData) error
+	OnSessionThreadTerminated       func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionThreadTerminatedEventData) error
+	OnSessionOutcomeEvaluationEnded func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionOutcomeEvaluationEndedEventData) error
+	OnVaultCreated                  func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookVaultCreatedEventData) error
+	OnVaultArchived                 func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookVaultArchivedEventData) error
+	OnVaultDeleted                  func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookVaultDeletedEventData) error
+	OnVaultCredentialCreated        func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookVaultCredentialCreatedEventData) error
+	OnVaultCredentialArchived       func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookVaultCredentialArchivedEventData) error
+	OnVaultCredentialDeleted        func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookVaultCredentialDeletedEventData) error
+	OnVaultCredentialRefreshFailed  func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookVaultCredentialRefreshFailedEventData) error
+	OnSessionUpdated                func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookSessionUpdatedEventData) error
+	OnAgentCreated                  func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookAgentCreatedEventData) error
+	OnAgentArchived                 func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookAgentArchivedEventData) error
+	OnAgentDeleted                  func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookAgentDeletedEventData) error
+	OnDeploymentPaused              func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookDeploymentPausedEventData) error
+	OnDeploymentRunFailed           func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookDeploymentRunFailedEventData) error
+	OnDeploymentCreated             func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookDeploymentCreatedEventData) error
+	OnDeploymentUpdated             func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookDeploymentUpdatedEventData) error
+	OnDeploymentUnpaused            func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookDeploymentUnpausedEventData) error
+	OnAgentUpdated                  func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookAgentUpdatedEventData) error
+	OnDeploymentArchived            func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookDeploymentArchivedEventData) error
+	OnDeploymentRunStarted          func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookDeploymentRunStartedEventData) error
+	OnDeploymentDeleted             func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookDeploymentDeletedEventData) error
+	OnDeploymentRunSucceeded        func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookDeploymentRunSucceededEventData) error
+	OnEnvironmentCreated            func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookEnvironmentCreatedEventData) error
+	OnEnvironmentUpdated            func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookEnvironmentUpdatedEventData) error
+	OnEnvironmentArchived           func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookEnvironmentArchivedEventData) error
+	OnEnvironmentDeleted            func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookEnvironmentDeletedEventData) error
+	OnMemoryStoreCreated            func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookMemoryStoreCreatedEventData) error
+	OnMemoryStoreArchived           func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookMemoryStoreArchivedEventData) error
+	OnMemoryStoreDeleted            func(ctx context.Context, event *UnwrapWebhookEvent, data BetaWebhookMemoryStoreDeletedEventData) error
+}
+
+// dispatch calls the typed callback for event, reporting whether there was
+// one.
+func (p *BetaWebhookHandlerParams) dispatch(ctx context.Context, event *UnwrapWebhookEvent) (bool, error) {
+	switch event.Data.Type {
+	case "session.created":
+		if p.OnSessionCreated != nil {
+			return true, p.OnSessionCreated(ctx, event, event.Data.AsSessionCreated())
+		}
+	case "session.pending":
+		if p.OnSessionPending != nil {
+			return true, p.OnSessionPending(ctx, event, event.Data.AsSessionPending())
+		}
+	case "session.running":
+		if p.OnSessionRunning != nil {
+			return true, p.OnSessionRunning(ctx, event, event.Data.AsSessionRunning())
+		}
+	case "session.idled":
+		if p.OnSessionIdled != nil {
+			return true, p.OnSessionIdled(ctx, event, event.Data.AsSessionIdled())
+		}
+	case "session.requires_action":
+		if p.OnSessionRequiresAction != nil {
+			return true, p.OnSessionRequiresAction(ctx, event, event.Data.AsSessionRequiresAction())
+		}
+	case "session.archived":
+		if p.OnSessionArchived != nil {
+			return true, p.OnSessionArchived(ctx, event, event.Data.AsSessionArchived())
+		}
+	case "session.deleted":
+		if p.OnSessionDeleted != nil {
+			return true, p.OnSessionDeleted(ctx, event, event.Data.AsSessionDeleted())
+		}
+	case "session.status_rescheduled":
+		if p.OnSessionStatusRescheduled != nil {
+			return true, p.OnSessionStatusRescheduled(ctx, event, event.Data.AsSessionStatusRescheduled())
+		}
+	case "session.status_run_started":
+		if p.OnSessionStatusRunStarted != nil {
+			return true, p.OnSessionStatusRunStarted(ctx, event, event.Data.AsSessionStatusRunStarted())
+		}
+	case "session.status_idled":
+		if p.OnSessionStatusIdled != nil {
+			return true, p.OnSessionStatusIdled(ctx, event, event.Data.AsSessionStatusIdled())
+		}
+	case "session.status_terminated":
+		if p.OnSessionStatusTerminated != nil {
+			return true, p.OnSessionStatusTerminated(ctx, event, event.Data.AsSessionStatusTerminated())
+		}
+	case "session.thread_created":
+		if p.OnSessionThreadCreated != nil {
+			return true, p.OnSessionThreadCreated(ctx, event, event.Data.AsSessionThreadCreated())
+		}
+	case "session.thread_idled":
+		if p.OnSessionThreadIdled != nil {
+			return true, p.OnSessionThreadIdled(ctx, event, event.Data.AsSessionThreadIdled())
+		}
+	case "session.thread_terminated":
+		if p.OnSessionThreadTerminated != nil {
+			return true, p.OnSessionThreadTerminated(ctx, event, event.Data.AsSessionThreadTerminated())
+		}
+	case "session.outcome_evaluation_ended":
+		if p.OnSessionOutcomeEvaluationEnded != nil {
+			return true, p.OnSessionOutcomeEvaluationEnded(ctx, event, event.Data.AsSessionOutcomeEvaluationEnded())
+		}
+	case "vault.created":
+		if p.OnVaultCreated != nil {
+			return true, p.OnVaultCreated(ctx, event, event.Data.AsVaultCreated())
+		}
+	case "vault.archived":
+		if p.OnVaultArchived != nil {
+			return true, p.OnVaultArchived(ctx, event, event.Data.AsVaultArchived())
+		}
+	case "vault.deleted":
+		if p.OnVaultDeleted != nil {
+			return true, p.OnVaultDeleted(ctx, event, event.Data.AsVaultDeleted())
+		}
+	case "vault_credential.created":
+		if p.OnVaultCredentialCreated != nil {
+			return true, p.OnVaultCredentialCreated(ctx, event, event.Data.AsVaultCredentialCreated())
+		}
+	case "vault_credential.archived":
+		if p.OnVaultCredentialArchived != nil {
+			return true, p.OnVaultCredentialArchived(ctx, event, event.Data.AsVaultCredentialArchived())
+		}
+	case "vault_credential.deleted":
+		if p.OnVaultCredentialDeleted != nil {
+			return true, p.OnVaultCredentialDeleted(ctx, event, event.Data.AsVaultCredentialDeleted())
+		}
+	case "vault_credential.refresh_failed":
+		if p.OnVaultCredentialRefreshFailed != nil {
+			return true, p.OnVaultCredentialRefreshFailed(ctx, event, event.Data.AsVaultCredentialRefreshFailed())
+		}
+	case "session.updated":
+		if p.OnSessionUpdated != nil {
+			return true, p.OnSessionUpdated(ctx, event, event.Data.AsSessionUpdated())
+		}
+	case "agent.created":
+		if p.OnAgentCreated != nil {
+			return true, p.OnAgentCreated(ctx, event, event.Data.AsAgentCreated())
+		}
+	case "agent.archived":
+		if p.OnAgentArchived != nil {
+			return true, p.OnAgentArchived(ctx, event, event.Data.AsAgentArchived())
+		}
+	case "agent.deleted":
+		if p.OnAgentDeleted != nil {
+			return true, p.OnAgentDeleted(ctx, event, event.Data.AsAgentDeleted())
+		}
+	case "deployment.paused":
+		if p.OnDeploymentPaused != nil {
+			return true, p.OnDeploymentPaused(ctx, event, event.Data.AsDeploymentPaused())
+		}
+	case "deployment_run.failed":
+		if p.OnDeploymentRunFailed != nil {
+			return true, p.OnDeploymentRunFailed(ctx, event, event.Data.AsDeploymentRunFailed())
+		}
+	case "deployment.created":
+		if p.OnDeploymentCreated != nil {
+			return true, p.OnDeploymentCreated(ctx, event, event.Data.AsDeploymentCreated())
+		}
+	case "deployment.updated":
+		if p.OnDeploymentUpdated != nil {
+			return true, p.OnDeploymentUpdated(ctx, event, event.Data.AsDeploymentUpdated())
+		}
+	case "deployment.unpaused":
+		if p.OnDeploymentUnpaused != nil {
+			return true, p.OnDeploymentUnpaused(ctx, event, event.Data.AsDeploymentUnpaused())
+		}
+	case "agent.updated":
+		if p.OnAgentUpdated != nil {
+			return true, p.OnAgentUpdated(ctx, event, event.Data.AsAgentUpdated())
+		}
+	case "deployment.archived":
+		if p.OnDeploymentArchived != nil {
+			return true, p.OnDeploymentArchived(ctx, event, event.Data.AsDeploymentArchived())
+		}
+	case "deployment_run.started":
+		if p.OnDeploymentRunStarted != nil {
+			return true, p.OnDeploymentRunStarted(ctx, event, event.Data.AsDeploymentRunStarted())
+		}
+	case "deployment.deleted":
+		if p.OnDeploymentDeleted != nil {
+			return true, p.OnDeploymentDeleted(ctx, event, event.Data.AsDeploymentDeleted())
+		}
+	case "deployment_run.succeeded":
+		if p.OnDeploymentRunSucceeded != nil {
+			return true, p.OnDeploymentRunSucceeded(ctx, event, event.Data.AsDeploymentRunSucceeded())
+		}
+	case "environment.created":
+		if p.OnEnvironmentCreated != nil {
+			return true, p.OnEnvironmentCreated(ctx, event, event.Data.AsEnvironmentCreated())
+		}
+	case "environment.updated":
+		if p.OnEnvironmentUpdated != nil {
+			return true, p.OnEnvironmentUpdated(ctx, event, event.Data.AsEnvironmentUpdated())
+		}
+	case "environment.archived":
+		if p.OnEnvironmentArchived != nil {
+			return true, p.OnEnvironmentArchived(ctx, event, event.Data.AsEnvironmentArchived())
+		}
+	case "environment.deleted":
+		if p.OnEnvironmentDeleted != nil {
+			return true, p.OnEnvironmentDeleted(ctx, event, event.Data.AsEnvironmentDeleted())
+		}
+	case "memory_store.created":
+		if p.OnMemoryStoreCreated != nil {
+			return true, p.OnMemoryStoreCreated(ctx, event, event.Data.AsMemoryStoreCreated())
+		}
+	case "memory_store.archived":
+		if p.OnMemoryStoreArchived != nil {
+			return true, p.OnMemoryStoreArchived(ctx, event, event.Data.AsMemoryStoreArchived())
+		}
+	case "memory_store.deleted":
+		if p.OnMemoryStoreDeleted != nil {
+			return true, p.OnMemoryStoreDeleted(ctx, event, event.Data.AsMemoryStoreDeleted())
+		}
+	}
+	return false, nil
+}
+
+// BetaWebhookHandler is an [http.Handler] that receives webhook deliveries:
+// it verifies each one's signature and timestamp, decodes it to an
+// [UnwrapWebhookEvent] and dispatches it to the callbacks of its
+// [BetaWebhookHandlerParams]. Create one with [BetaWebhookService.NewHandler].
+//
+// It responds with:
+//   - 204 once the event is handled, ignored or found to be a duplicate
+//   - 400 if the payload cannot be decoded
+//   - 401 if the signature or timestamp does not verify
+//   - 405 if the method is not POST
+//   - 413 if the body exceeds MaxBodyBytes
+//   - 500 if a callback or the DedupeStore failed, so the delivery is retried
+type BetaWebhookHandler struct {
+	params  BetaWebhookHandlerParams
+	webhook *standardwebhooks.Webhook
+}
+
+// NewHandler returns an [http.Handler] for webhook deliveries, verified with the
+// WebhookKey option.
+//
+//	handler, err := client.Beta.Webhooks.NewHandler(anthropic.BetaWebhookHandlerParams{
+//		DedupeStore: anthropic.NewMemoryWebhookDedupeStore(24 * time.Hour),
+//		OnDeploymentRunFailed: func(ctx context.Context, event *anthropic.UnwrapWebhookEvent, data anthropic.BetaWebhookDeploymentRunFailedEventData) error {
+//			return alert(ctx, data.ID)
+//		},
+//	})
+//	if err != nil { ... }
+//	http.Handle("/webhooks/anthropic", handler)
+func (r *BetaWebhookService) NewHandler(params BetaWebhookHandlerParams, opts ...option.RequestOption) (*BetaWebhookHandler, error) {
+	cfg, err := requestconfig.PreRequestOptions(slices.Concat(r.Options, opts)...)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.WebhookKey == "" {
+		return nil, errors.New("The WebhookKey option must be set in order to verify webhook headers")
+	}
+	wh, err := standardwebhooks.NewWebhook(cfg.WebhookKey)
+	if err != nil {
+		return nil, err
+	}
+	if params.MaxBodyBytes <= 0 {
+		params.MaxBodyBytes = DefaultWebhookMaxBodyBytes
+	}
+	if params.Tolerance <= 0 {
+		params.Tolerance = DefaultWebhookTolerance
+	}
+	return &BetaWebhookHandler{params: params, webhook: wh}, nil
+}
+
+// ServeHTTP implements [http.Handler].
+func (h *BetaWebhookHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	status, err := h.serve(r)
+	if err != nil && h.params.OnError != nil {
+		h.params.OnError(r, err)
+	}
+	if status == http.StatusMethodNotAllowed {
+		w.Header().Set("Allow", http.MethodPost)
+	}
+	if err != nil {
+		http.Error(w, http.StatusText(status), status)
+		return
+	}
+	w.WriteHeader(status)
+}
+
+func (h *BetaWebhookHandler) serve(r *http.Request) (int, error) {
+	if r.Method != http.MethodPost {
+		return http.StatusMethodNotAllowed, fmt.Errorf("anthropic: webhook delivery with method %s", r.Method)
+	}
+	payload, err := io.ReadAll(io.LimitReader(r.Body, h.params.MaxBodyBytes+1))
+	if err != nil {
+		return http.StatusBadRequest, fmt.Errorf("anthropic: reading webhook delivery: %w", err)
+	}
+	if int64(len(payload)) > h.params.MaxBodyBytes {
+		return http.StatusRequestEntityTooLarge, fmt.Errorf("anthropic: webhook delivery exceeds %d bytes", h.params.MaxBodyBytes)
+	}
+	if err := h.verify(payload, r.Header); err != nil {
+		return http.StatusUnauthorized, err
+	}
+
+	event := &UnwrapWebhookEvent{}
+	if !json.Valid(payload) {
+		return http.StatusBadRequest, errors.New("anthropic: webhook payload is not valid JSON")
+	}
+	if err := event.UnmarshalJSON(payload); err != nil {
+		return http.StatusBadRequest, fmt.Errorf("anthropic: decoding webhook event: %w", err)
+	}
+
+	ctx := r.Context()
+	id := r.Header.Get("webhook-id")
+	if h.params.DedupeStore != nil {
+		first, err := h.params.DedupeStore.Claim(ctx, id)
+		if err != nil {
+			return http.StatusInternalServerError, fmt.Errorf("anthropic: claiming webhook %s: %w", id, err)
+		}
+		if !first {
+			return http.StatusNoContent, nil
+		}
+	}
+	if err := h.handle(ctx, event); err != nil {
+		err = fmt.Errorf("anthropic: handling webhook %s (%s): %w", id, event.Data.Type, err)
+		if h.params.DedupeStore != nil {
+			// Forget the delivery so that its retry is handled.
+			if releaseErr := h.params.DedupeStore.Release(context.WithoutCancel(ctx), id); releaseErr != nil {
+				err = errors.Join(err, fmt.Errorf("anthropic: releasing webhook %s: %w", id, releaseErr))
+			}
+		}
+		return http.StatusInternalServerError, err
+	}
+	return http.StatusNoContent, nil
+}
+
+// verify checks the delivery's signature and that its timestamp is within the
+// tolerance.
+func (h *BetaWebhookHandler) verify(payload []byte, headers http.Header) error {
+	if err := h.webhook.VerifyIgnoringTimestamp(payload, headers); err != nil {
+		return fmt.Errorf("anthropic: verifying webhook: %w", err)
+	}
+	sec, err := strconv.ParseInt(headers.Get("webhook-timestamp"), 10, 64)
+	if err != nil {
+		return fmt.Errorf("anthropic: invalid webhook-timestamp %q", headers.Get("webhook-timestamp"))
+	}
+	if skew := time.Since(time.Unix(sec, 0)); skew > h.params.Tolerance || skew < -h.params.Tolerance {
+		return fmt.Errorf("anthropic: webhook-timestamp is %s from now, outside the %s tolerance", skew.Round(time.Second), h.params.Tolerance)
+	}
+	return nil
+}
+
+func (h *BetaWebhookHandler) handle(ctx context.Context, event *UnwrapWebhookEvent) error {
+	if ok, err := h.params.dispatch(ctx, event); ok {
+		return err
+	}
+	if strings.HasPrefix(event.Data.Type, "session.status_") && h.params.OnSessionStatusChanged != nil {
+		return h.params.OnSessionStatusChanged(ctx, event, event.Data)
+	}
+	if h.params.OnEvent != nil {
+		return h.params.OnEvent(ctx, event)
+	}
+	return nil
+}
+
+// WebhookDedupeStore records which webhook deliveries have been handled.
+// Webhooks are delivered at least once, so the same event may arrive more than
+// once, with the same webhook-id. Implementations must be safe for concurrent
+// use.
+type WebhookDedupeStore interface {
+	// Claim records that the delivery with the given webhook-id is being
+	// handled. It reports false if the ID was already claimed.
+	Claim(ctx context.Context, webhookID string) (bool, error)
+	// Release forgets a claimed webhook-id whose handling failed, so that the
+	// delivery's retry is handled.
+	Release(ctx context.Context, webhookID string) error
+}
+
+// MemoryWebhookDedupeStore is a [WebhookDedupeStore] that keeps webhook IDs in
+// memory for a fixed time. It only deduplicates deliveries received by the same
+// process.
+type MemoryWebhookDedupeStore struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu        sync.Mutex
+	claims    map[string]time.Time
+	nextSweep time.Time
+}
+
+// NewMemoryWebhookDedupeStore returns a [MemoryWebhookDedupeStore] that
+// remembers each webhook ID for ttl, which should exceed the sender's retry
+// window.
+func NewMemoryWebhookDedupeStore(ttl time.Duration) *MemoryWebhookDedupeStore {
+	return &MemoryWebhookDedupeStore{ttl: ttl, now: time.Now, claims: make(map[string]time.Time)}
+}
+
+// Claim implements [WebhookDedupeStore].
+func (s *MemoryWebhookDedupeStore) Claim(ctx context.Context, webhookID string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	if now.After(s.nextSweep) {
+		for id, expires := range s.claims {
+			if now.After(expires) {
+				delete(s.claims, id)
+			}
+		}
+		s.nextSweep = now.Add(s.ttl)
+	}
+	if expires, ok := s.claims[webhookID]; ok && !now.After(expires) {
+		return false, nil
+	}
+	s.claims[webhookID] = now.Add(s.ttl)
+	return true, nil
+}
+
+// Release implements [WebhookDedupeStore].
+func (s *MemoryWebhookDedupeStore) Release(ctx context.Context, webhookID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.claims, webhookID)
+	return nil
+}
@@ -0,0 +1,171 @@
+package anthropic_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	standardwebhooks "github.com/standard-webhooks/standard-webhooks/libraries/go"
+)
+
+const testWebhookKey = "whsec_c2VjcmV0Cg=="
+
+func webhookPayload(eventType string) []byte {
+	return []byte(fmt.Sprintf(`{"id":"whe_01","created_at":"2026-03-15T10:00:00Z","data":{"id":"res_01","organization_id":"org_01","type":%q,"workspace_id":"wrkspc_01"},"type":"event"}`, eventType))
+}
+
+func signedDelivery(t *testing.T, webhookID string, timestamp time.Time, payload []byte) *http.Request {
+	t.Helper()
+	wh, err := standardwebhooks.NewWebhook(testWebhookKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, err := wh.Sign(webhookID, timestamp, payload)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(string(payload)))
+	req.Header.Set("webhook-id", webhookID)
+	req.Header.Set("webhook-timestamp", strconv.FormatInt(timestamp.Unix(), 10))
+	req.Header.Set("webhook-signature", sig)
+	return req
+}
+
+func serveWebhook(handler http.Handler, req *http.Request) int {
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	return rec.Code
+}
+
+func TestBetaWebhookHandlerDispatch(t *testing.T) {
+	client := anthropic.NewClient(option.WithWebhookKey(testWebhookKey), option.WithAPIKey("my-anthropic-api-key"))
+	var got []string
+	handler, err := client.Beta.Webhooks.NewHandler(anthropic.BetaWebhookHandlerParams{
+		OnDeploymentRunFailed: func(ctx context.Context, event *anthropic.UnwrapWebhookEvent, data anthropic.BetaWebhookDeploymentRunFailedEventData) error {
+			got = append(got, "run_failed:"+data.ID+":"+event.ID)
+			return nil
+		},
+		OnSessionStatusIdled: func(ctx context.Context, event *anthropic.UnwrapWebhookEvent, data anthropic.BetaWebhookSessionStatusIdledEventData) error {
+			got = append(got, "idled:"+data.ID)
+			return nil
+		},
+		OnSessionStatusChanged: func(ctx context.Context, event *anthropic.UnwrapWebhookEvent, data anthropic.BetaWebhookEventDataUnion) error {
+			got = append(got, "status:"+data.Type)
+			return nil
+		},
+		OnEvent: func(ctx context.Context, event *anthropic.UnwrapWebhookEvent) error {
+			got = append(got, "other:"+event.Data.Type)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, eventType := range []string{"deployment_run.failed", "session.status_idled", "session.status_terminated", "vault.created"} {
+		req := signedDelivery(t, fmt.Sprintf("msg_%d", i), time.Now(), webhookPayload(eventType))
+		if code := serveWebhook(handler, req); code != http.StatusNoContent {
+			t.Errorf("%s: expected 204, got %d", eventType, code)
+		}
+	}
+	want := "run_failed:res_01:whe_01 idled:res_01 status:session.status_terminated other:vault.created"
+	if strings.Join(got, " ") != want {
+		t.Errorf("expected callbacks %q, got %q", want, strings.Join(got, " "))
+	}
+}
+
+func TestBetaWebhookHandlerRejects(t *testing.T) {
+	client := anthropic.NewClient(option.WithWebhookKey(testWebhookKey), option.WithAPIKey("my-anthropic-api-key"))
+	called := false
+	var rejections []error
+	handler, err := client.Beta.Webhooks.NewHandler(anthropic.BetaWebhookHandlerParams{
+		MaxBodyBytes: 512,
+		Tolerance:    time.Minute,
+		OnEvent: func(ctx context.Context, event *anthropic.UnwrapWebhookEvent) error {
+			called = true
+			return nil
+		},
+		OnError: func(r *http.Request, err error) { rejections = append(rejections, err) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tampered := signedDelivery(t, "msg_1", time.Now(), webhookPayload("vault.created"))
+	tampered.Body = http.NoBody
+	get := httptest.NewRequest(http.MethodGet, "/webhooks", nil)
+	cases := map[string]struct {
+		req  *http.Request
+		want int
+	}{
+		"tampered":   {tampered, http.StatusUnauthorized},
+		"unsigned":   {httptest.NewRequest(http.MethodPost, "/webhooks", strings.NewReader(string(webhookPayload("vault.created")))), http.StatusUnauthorized},
+		"stale":      {signedDelivery(t, "msg_2", time.Now().Add(-2*time.Minute), webhookPayload("vault.created")), http.StatusUnauthorized},
+		"future":     {signedDelivery(t, "msg_3", time.Now().Add(2*time.Minute), webhookPayload("vault.created")), http.StatusUnauthorized},
+		"too large":  {signedDelivery(t, "msg_4", time.Now(), []byte(`{"pad":"`+strings.Repeat("x", 600)+`"}`)), http.StatusRequestEntityTooLarge},
+		"not json":   {signedDelivery(t, "msg_5", time.Now(), []byte(`not json`)), http.StatusBadRequest},
+		"wrong verb": {get, http.StatusMethodNotAllowed},
+	}
+	for name, tc := range cases {
+		if code := serveWebhook(handler, tc.req); code != tc.want {
+			t.Errorf("%s: expected %d, got %d", name, tc.want, code)
+		}
+	}
+	if called {
+		t.Error("expected rejected deliveries not to reach the callbacks")
+	}
+	if len(rejections) != len(cases) {
+		t.Errorf("expected OnError for each rejection, got %d", len(rejections))
+	}
+}
+
+func TestBetaWebhookHandlerDedupe(t *testing.T) {
+	client := anthropic.NewClient(option.WithWebhookKey(testWebhookKey), option.WithAPIKey("my-anthropic-api-key"))
+	calls := 0
+	fail := true
+	handler, err := client.Beta.Webhooks.NewHandler(anthropic.BetaWebhookHandlerParams{
+		DedupeStore: anthropic.NewMemoryWebhookDedupeStore(time.Hour),
+		OnAgentCreated: func(ctx context.Context, event *anthropic.UnwrapWebhookEvent, data anthropic.BetaWebhookAgentCreatedEventData) error {
+			calls++
+			if fail {
+				return errors.New("database unavailable")
+			}
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deliver := func() int {
+		return serveWebhook(handler, signedDelivery(t, "msg_1", time.Now(), webhookPayload("agent.created")))
+	}
+	if code := deliver(); code != http.StatusInternalServerError {
+		t.Errorf("expected a failing callback to return 500, got %d", code)
+	}
+	fail = false
+	if code := deliver(); code != http.StatusNoContent {
+		t.Errorf("expected the retry to be handled, got %d", code)
+	}
+	if code := deliver(); code != http.StatusNoContent {
+		t.Errorf("expected the duplicate to be acknowledged, got %d", code)
+	}
+	if calls != 2 {
+		t.Errorf("expected the callback to run for the failed delivery and its retry only, got %d calls", calls)
+	}
+}
+
+func TestBetaWebhookHandlerRequiresKey(t *testing.T) {
+	client := anthropic.NewClient(option.WithAPIKey("my-anthropic-api-key"))
+	if _, err := client.Beta.Webhooks.NewHandler(anthropic.BetaWebhookHandlerParams{}); err == nil {
+		t.Error("expected an error without a WebhookKey")
+	}
+}
@@ -65,6 +65,12 @@ type Config struct {
 	// oidc_federation profiles it is sent as workspace_id in the jwt-bearer
 	// exchange body instead (the minted token is already workspace-scoped).
 	WorkspaceID string `json:"workspace_id,omitempty"`
+
+	// CredentialStore names the [CredentialStore] that holds this profile's
+	// credentials: [CredentialStoreFile] (the default when empty),
+	// [CredentialStoreEncryptedFile], or a name registered with
+	// [RegisterCredentialStore] such as [CredentialStoreKeychain].
+	CredentialStore string `json:"credential_store,omitempty"`
 }
 
 // AuthenticationType is the discriminator for [AuthenticationInfo].
@@ -184,13 +190,62 @@ const (
 	// which supports rotated tokens (e.g. Kubernetes projected service
 	// account tokens).
 	IdentityTokenSourceFile IdentityTokenSource = "file"
+
+	// IdentityTokenSourceEnv reads the token from the environment variable
+	// named by [IdentityTokenConfig.EnvVar] on every exchange.
+	IdentityTokenSourceEnv IdentityTokenSource = "env"
+
+	// IdentityTokenSourceExec runs [IdentityTokenConfig.Command], a
+	// credential helper that prints the token on stdout, either bare or as a
+	// JSON object {"token": "...", "expires_at": <unix seconds>}. The token
+	// is reused until shortly before it expires.
+	IdentityTokenSourceExec IdentityTokenSource = "exec"
+
+	// IdentityTokenSourceGitHubActions requests a token from the GitHub
+	// Actions OIDC provider, using the ACTIONS_ID_TOKEN_REQUEST_URL and
+	// ACTIONS_ID_TOKEN_REQUEST_TOKEN variables of a job granted the
+	// id-token: write permission.
+	IdentityTokenSourceGitHubActions IdentityTokenSource = "github_actions"
+
+	// IdentityTokenSourceGCPMetadata requests an identity token for the
+	// default service account from the GCP metadata server. Set
+	// GCE_METADATA_HOST to reach a metadata server at another address.
+	IdentityTokenSourceGCPMetadata IdentityTokenSource = "gcp_metadata"
+
+	// IdentityTokenSourceKubernetes reads a Kubernetes projected service
+	// account token, re-reading the file whenever the kubelet rotates it.
+	IdentityTokenSourceKubernetes IdentityTokenSource = "kubernetes"
 )
 
+// DefaultKubernetesTokenPath is where [IdentityTokenSourceKubernetes] reads
+// the token when [IdentityTokenConfig.Path] is empty: the pod's service
+// account token.
+const DefaultKubernetesTokenPath = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
 // IdentityTokenConfig specifies how to obtain an OIDC identity token for
-// federation exchange.
+// federation exchange. Which fields apply depends on Source.
 type IdentityTokenConfig struct {
 	Source IdentityTokenSource `json:"source"`
-	Path   string              `json:"path,omitempty"`
+
+	// Path is the token file for [IdentityTokenSourceFile] (required) and
+	// [IdentityTokenSourceKubernetes] (defaults to
+	// [DefaultKubernetesTokenPath]).
+	Path string `json:"path,omitempty"`
+
+	// EnvVar is the environment variable holding the token for
+	// [IdentityTokenSourceEnv]. Required for that source.
+	EnvVar string `json:"env_var,omitempty"`
+
+	// Command is the credential helper and its arguments for
+	// [IdentityTokenSourceExec]. Required for that source. It is run
+	// directly, not through a shell.
+	Command []string `json:"command,omitempty"`
+
+	// Audience is the aud claim requested from
+	// [IdentityTokenSourceGitHubActions] (optional; GitHub defaults to the
+	// repository owner's URL) and [IdentityTokenSourceGCPMetadata]
+	// (required). It must match the audience the federation rule expects.
+	Audience string `json:"audience,omitempty"`
 }
 
 // NewUserOAuthAuthentication returns a populated [AuthenticationInfo] for
@@ -486,6 +541,7 @@ const (
 	envServiceAccountID  = "ANTHROPIC_SERVICE_ACCOUNT_ID"
 	envScope             = "ANTHROPIC_SCOPE"
 	envIdentityTokenFile = "ANTHROPIC_IDENTITY_TOKEN_FILE"
+	envCredentialStore   = "ANTHROPIC_CREDENTIAL_STORE"
 )
 
 // fillMissingFromEnv populates cfg fields from ANTHROPIC_* environment
@@ -515,6 +571,11 @@ func fillMissingFromEnv(cfg *Config) {
 			cfg.WorkspaceID = v
 		}
 	}
+	if cfg.CredentialStore == "" {
+		if v, ok := lookupNonEmpty(envCredentialStore); ok {
+			cfg.CredentialStore = v
+		}
+	}
 
 	switch cfg.AuthenticationInfo.Type {
 	case AuthenticationTypeOIDCFederation:
@@ -0,0 +1,462 @@
+package config
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Built-in [CredentialStore] names, as used in [Config.CredentialStore].
+const (
+	// CredentialStoreFile stores credentials as plain JSON at the profile's
+	// [AuthenticationInfo.CredentialsPath]. It is the default.
+	CredentialStoreFile = "file"
+
+	// CredentialStoreEncryptedFile stores credentials at the profile's
+	// CredentialsPath encrypted with a passphrase taken from
+	// ANTHROPIC_CREDENTIALS_PASSPHRASE or ANTHROPIC_CREDENTIALS_PASSPHRASE_FILE.
+	// See [EncryptedFileCredentialStore].
+	CredentialStoreEncryptedFile = "encrypted_file"
+
+	// CredentialStoreKeychain is the conventional name for an OS keychain
+	// store. The SDK does not link against any OS keychain API, so nothing
+	// is registered under this name by default; register one with
+	// [RegisterCredentialStore] and [NewKeyringCredentialStore].
+	CredentialStoreKeychain = "keychain"
+)
+
+// Environment variables read by the default [CredentialStoreEncryptedFile]
+// store. The _FILE variant suits secrets mounted into a build container.
+const (
+	EnvCredentialsPassphrase     = "ANTHROPIC_CREDENTIALS_PASSPHRASE"
+	EnvCredentialsPassphraseFile = "ANTHROPIC_CREDENTIALS_PASSPHRASE_FILE"
+)
+
+// CredentialStore persists [Credentials]. Every method takes the profile's
+// resolved [AuthenticationInfo.CredentialsPath] as key: file-based stores
+// write there, other stores use it as an opaque per-profile identifier.
+//
+// ReadCredentials must return an error wrapping [fs.ErrNotExist] when no
+// credentials are stored under key, and DeleteCredentials must not fail in
+// that case. Implementations must be safe for concurrent use.
+type CredentialStore interface {
+	ReadCredentials(key string) (*Credentials, error)
+	WriteCredentials(key string, creds Credentials) error
+	DeleteCredentials(key string) error
+}
+
+var (
+	credentialStoresMu sync.RWMutex
+	credentialStores   = map[string]CredentialStore{
+		CredentialStoreFile:          FileCredentialStore{},
+		CredentialStoreEncryptedFile: &EncryptedFileCredentialStore{},
+	}
+)
+
+// RegisterCredentialStore makes store available under name for
+// [Config.CredentialStore], replacing any store registered under the same
+// name, including the built-in ones. A nil store unregisters name.
+func RegisterCredentialStore(name string, store CredentialStore) {
+	credentialStoresMu.Lock()
+	defer credentialStoresMu.Unlock()
+	if store == nil {
+		delete(credentialStores, name)
+		return
+	}
+	credentialStores[name] = store
+}
+
+// LookupCredentialStore returns the store registered under name. An empty
+// name returns the [CredentialStoreFile] store.
+func LookupCredentialStore(name string) (CredentialStore, error) {
+	if name == "" {
+		name = CredentialStoreFile
+	}
+	credentialStoresMu.RLock()
+	store, ok := credentialStores[name]
+	credentialStoresMu.RUnlock()
+	if ok {
+		return store, nil
+	}
+	if name == CredentialStoreKeychain {
+		return nil, fmt.Errorf("credential store %q is not registered; register an OS keychain with RegisterCredentialStore(%q, NewKeyringCredentialStore(...))", name, name)
+	}
+	return nil, fmt.Errorf("unknown credential store %q (registered: %s)", name, strings.Join(registeredCredentialStores(), ", "))
+}
+
+func registeredCredentialStores() []string {
+	credentialStoresMu.RLock()
+	defer credentialStoresMu.RUnlock()
+	names := make([]string, 0, len(credentialStores))
+	for name := range credentialStores {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// FileCredentialStore is the [CredentialStoreFile] store: plain JSON files
+// written with [WriteCredentials]. Reads refuse symlinks and files that are
+// accessible by group or other.
+type FileCredentialStore struct{}
+
+func (FileCredentialStore) ReadCredentials(path string) (*Credentials, error) {
+	data, err := readCredentialsFileBytes(path)
+	if err != nil {
+		return nil, err
+	}
+	var creds Credentials
+	if err := json.Unmarshal(data, &creds); err != nil {
+		return nil, fmt.Errorf("parse credentials file %q: %w", path, err)
+	}
+	return &creds, nil
+}
+
+func (FileCredentialStore) WriteCredentials(path string, creds Credentials) error {
+	return WriteCredentials(path, creds)
+}
+
+func (FileCredentialStore) DeleteCredentials(path string) error {
+	return removeCredentialsFile(path)
+}
+
+// readCredentialsFileBytes reads path after [checkCredentialsFileSafety].
+func readCredentialsFileBytes(path string) ([]byte, error) {
+	if path == "" {
+		return nil, fmt.Errorf("credentials path is empty")
+	}
+	if err := checkCredentialsFileSafety(path); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(path)
+}
+
+// checkCredentialsFileSafety refuses credentials files exposed to other
+// UIDs on the host. Symlinks could redirect a refresh-token write; any
+// mode&0o077 bit lets another UID read or inject a token. Mode bits are
+// not meaningful on Windows, so that check is skipped there.
+func checkCredentialsFileSafety(path string) error {
+	info, err := os.Lstat(path)
+	if err != nil {
+		return err
+	}
+	if info.Mode().Type()&fs.ModeSymlink != 0 {
+		return fmt.Errorf("credentials file %q is a symlink; refusing to read (set ANTHROPIC_CREDENTIALS_PATH to the real file)", path)
+	}
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	mode := info.Mode().Perm()
+	if mode&0o077 != 0 {
+		return fmt.Errorf("credentials file %q has unsafe permissions %#o; refusing to read (must not be accessible by group or other; chmod 600 %s)", path, mode, path)
+	}
+	return nil
+}
+
+func removeCredentialsFile(path string) error {
+	if path == "" {
+		return fmt.Errorf("credentials path is empty")
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// DefaultCredentialsKDFIterations is the PBKDF2-HMAC-SHA256 iteration count
+// [EncryptedFileCredentialStore] uses for new files (the OWASP 2023
+// recommendation).
+const DefaultCredentialsKDFIterations = 600_000
+
+// maxCredentialsKDFIterations bounds the iteration count accepted from a
+// file so a tampered file cannot stall the process.
+const maxCredentialsKDFIterations = 10_000_000
+
+const (
+	credentialsTypeEncrypted = "encrypted"
+	credentialsKDFPBKDF2     = "pbkdf2-sha256"
+)
+
+type encryptedCredentialsWireShape struct {
+	Version    string `json:"version"`
+	Type       string `json:"type"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// EncryptedFileCredentialStore stores credentials at the key path as a JSON
+// envelope whose payload is the credentials JSON sealed with AES-256-GCM.
+// The key is derived from a passphrase with PBKDF2-HMAC-SHA256 and a random
+// per-file salt; the envelope's header is bound to the ciphertext as
+// additional data, so it cannot be altered without detection.
+//
+// Derived keys are cached per salt, so the passphrase is stretched once per
+// process rather than on every token read. Files are written 0600 and read
+// with the same safety checks as [FileCredentialStore].
+//
+// A plaintext credentials file is not read through this store. To encrypt
+// an existing profile, read it with [FileCredentialStore] and write it back
+// with this store.
+type EncryptedFileCredentialStore struct {
+	// Passphrase returns the passphrase. When nil, it is read from
+	// ANTHROPIC_CREDENTIALS_PASSPHRASE or, failing that, from the file named
+	// by ANTHROPIC_CREDENTIALS_PASSPHRASE_FILE.
+	Passphrase func() (string, error)
+
+	// Iterations is the PBKDF2 iteration count for new files. Zero means
+	// [DefaultCredentialsKDFIterations]. Existing files keep their own.
+	Iterations int
+
+	mu   sync.Mutex
+	keys map[string][]byte
+}
+
+func (s *EncryptedFileCredentialStore) ReadCredentials(path string) (*Credentials, error) {
+	data, err := readCredentialsFileBytes(path)
+	if err != nil {
+		return nil, err
+	}
+	var envelope encryptedCredentialsWireShape
+	if err := json.Unmarshal(data, &envelope); err != nil {
+		return nil, fmt.Errorf("parse credentials file %q: %w", path, err)
+	}
+	if envelope.Type != credentialsTypeEncrypted {
+		return nil, fmt.Errorf("credentials file %q is not encrypted; log in again or re-write it with the %s credential store", path, CredentialStoreEncryptedFile)
+	}
+	if envelope.KDF != credentialsKDFPBKDF2 {
+		return nil, fmt.Errorf("credentials file %q: unsupported kdf %q", path, envelope.KDF)
+	}
+	if envelope.Iterations <= 0 || envelope.Iterations > maxCredentialsKDFIterations {
+		return nil, fmt.Errorf("credentials file %q: invalid kdf iteration count %d", path, envelope.Iterations)
+	}
+	key, err := s.key(envelope.Salt, envelope.Iterations)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := newCredentialsAEAD(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(envelope.Nonce) != aead.NonceSize() {
+		return nil, fmt.Errorf("credentials file %q: invalid nonce", path)
+	}
+	plaintext, err := aead.Open(nil, envelope.Nonce, envelope.Ciphertext, envelope.additionalData())
+	if err != nil {
+		return nil, fmt.Errorf("credentials file %q: decryption failed (wrong passphrase or corrupted file)", path)
+	}
+	var creds Credentials
+	if err := json.Unmarshal(plaintext, &creds); err != nil {
+		return nil, fmt.Errorf("parse credentials file %q: %w", path, err)
+	}
+	return &creds, nil
+}
+
+func (s *EncryptedFileCredentialStore) WriteCredentials(path string, creds Credentials) error {
+	if path == "" {
+		return fmt.Errorf("WriteCredentials: path is empty")
+	}
+	plaintext, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("WriteCredentials: marshal: %w", err)
+	}
+	envelope := encryptedCredentialsWireShape{
+		Version:    CredentialsFileVersion,
+		Type:       credentialsTypeEncrypted,
+		KDF:        credentialsKDFPBKDF2,
+		Iterations: s.Iterations,
+	}
+	if envelope.Iterations <= 0 {
+		envelope.Iterations = DefaultCredentialsKDFIterations
+	}
+	envelope.Salt = s.reusableSalt(path, envelope.Iterations)
+	if envelope.Salt == nil {
+		envelope.Salt = make([]byte, 16)
+		if _, err := rand.Read(envelope.Salt); err != nil {
+			return fmt.Errorf("WriteCredentials: %w", err)
+		}
+	}
+	key, err := s.key(envelope.Salt, envelope.Iterations)
+	if err != nil {
+		return fmt.Errorf("WriteCredentials: %w", err)
+	}
+	aead, err := newCredentialsAEAD(key)
+	if err != nil {
+		return fmt.Errorf("WriteCredentials: %w", err)
+	}
+	envelope.Nonce = make([]byte, aead.NonceSize())
+	if _, err := rand.Read(envelope.Nonce); err != nil {
+		return fmt.Errorf("WriteCredentials: %w", err)
+	}
+	envelope.Ciphertext = aead.Seal(nil, envelope.Nonce, plaintext, envelope.additionalData())
+
+	body, err := json.MarshalIndent(envelope, "", "  ")
+	if err != nil {
+		return fmt.Errorf("WriteCredentials: marshal: %w", err)
+	}
+	if err := writeFileAtomic(path, body, secretDirMode, secretFileMode); err != nil {
+		return fmt.Errorf("WriteCredentials: write %q: %w", path, err)
+	}
+	return nil
+}
+
+func (s *EncryptedFileCredentialStore) DeleteCredentials(path string) error {
+	return removeCredentialsFile(path)
+}
+
+// reusableSalt returns the salt of the file already at path when its key is
+// cached, so rewriting a refreshed token does not pay for a new derivation.
+func (s *EncryptedFileCredentialStore) reusableSalt(path string, iterations int) []byte {
+	data, err := readCredentialsFileBytes(path)
+	if err != nil {
+		return nil
+	}
+	var envelope encryptedCredentialsWireShape
+	if json.Unmarshal(data, &envelope) != nil || envelope.Iterations != iterations || len(envelope.Salt) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.keys[credentialsKeyCacheID(envelope.Salt, iterations)]; !ok {
+		return nil
+	}
+	return envelope.Salt
+}
+
+func (s *EncryptedFileCredentialStore) key(salt []byte, iterations int) ([]byte, error) {
+	id := credentialsKeyCacheID(salt, iterations)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if key, ok := s.keys[id]; ok {
+		return key, nil
+	}
+	passphrase, err := s.passphrase()
+	if err != nil {
+		return nil, err
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, iterations, 32)
+	if err != nil {
+		return nil, fmt.Errorf("derive credentials key: %w", err)
+	}
+	if s.keys == nil {
+		s.keys = make(map[string][]byte)
+	}
+	s.keys[id] = key
+	return key, nil
+}
+
+func (s *EncryptedFileCredentialStore) passphrase() (string, error) {
+	if s.Passphrase != nil {
+		passphrase, err := s.Passphrase()
+		if err != nil {
+			return "", fmt.Errorf("credentials passphrase: %w", err)
+		}
+		if passphrase == "" {
+			return "", errors.New("credentials passphrase is empty")
+		}
+		return passphrase, nil
+	}
+	if v, ok := lookupNonEmpty(EnvCredentialsPassphrase); ok {
+		return v, nil
+	}
+	if path, ok := lookupNonEmpty(EnvCredentialsPassphraseFile); ok {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", fmt.Errorf("credentials passphrase: %w", err)
+		}
+		if passphrase := strings.TrimRight(string(data), "\r\n"); passphrase != "" {
+			return passphrase, nil
+		}
+		return "", fmt.Errorf("credentials passphrase file %q is empty", path)
+	}
+	return "", fmt.Errorf("the %s credential store requires %s or %s to be set", CredentialStoreEncryptedFile, EnvCredentialsPassphrase, EnvCredentialsPassphraseFile)
+}
+
+// additionalData is the envelope header that the ciphertext is bound to.
+func (e *encryptedCredentialsWireShape) additionalData() []byte {
+	return fmt.Appendf(nil, "%s\x00%s\x00%s\x00%d\x00%x", e.Version, e.Type, e.KDF, e.Iterations, e.Salt)
+}
+
+func credentialsKeyCacheID(salt []byte, iterations int) string {
+	return fmt.Sprintf("%x:%d", salt, iterations)
+}
+
+func newCredentialsAEAD(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// Keyring is the minimal secret-storage API of an OS keychain (macOS
+// Keychain, Windows Credential Manager, the Secret Service on Linux).
+// github.com/zalando/go-keyring, for example, matches it after mapping its
+// ErrNotFound to [fs.ErrNotExist]. Get must return an error wrapping
+// [fs.ErrNotExist] when no secret is stored for service and account.
+type Keyring interface {
+	Get(service, account string) (string, error)
+	Set(service, account, secret string) error
+	Delete(service, account string) error
+}
+
+// KeyringService is the service name [NewKeyringCredentialStore] stores
+// secrets under.
+const KeyringService = "anthropic-sdk-go"
+
+// NewKeyringCredentialStore returns a [CredentialStore] that keeps each
+// profile's credentials JSON in keyring under [KeyringService], with the
+// profile's CredentialsPath as the account. Register it to select it from a
+// profile:
+//
+//	config.RegisterCredentialStore(config.CredentialStoreKeychain, config.NewKeyringCredentialStore(kr))
+func NewKeyringCredentialStore(keyring Keyring) CredentialStore {
+	return keyringCredentialStore{keyring: keyring}
+}
+
+type keyringCredentialStore struct {
+	keyring Keyring
+}
+
+func (s keyringCredentialStore) ReadCredentials(key string) (*Credentials, error) {
+	secret, err := s.keyring.Get(KeyringService, key)
+	if err != nil {
+		return nil, fmt.Errorf("keychain credentials %q: %w", key, err)
+	}
+	var creds Credentials
+	if err := json.Unmarshal([]byte(secret), &creds); err != nil {
+		return nil, fmt.Errorf("parse keychain credentials %q: %w", key, err)
+	}
+	return &creds, nil
+}
+
+func (s keyringCredentialStore) WriteCredentials(key string, creds Credentials) error {
+	body, err := json.Marshal(creds)
+	if err != nil {
+		return fmt.Errorf("WriteCredentials: marshal: %w", err)
+	}
+	if err := s.keyring.Set(KeyringService, key, string(body)); err != nil {
+		return fmt.Errorf("WriteCredentials: keychain %q: %w", key, err)
+	}
+	return nil
+}
+
+func (s keyringCredentialStore) DeleteCredentials(key string) error {
+	if err := s.keyring.Delete(KeyringService, key); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("keychain %q: %w", key, err)
+	}
+	return nil
+}
@@ -0,0 +1,258 @@
+package config_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/config"
+)
+
+func TestEncryptedFileCredentialStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds.json")
+	var derivations int
+	store := &config.EncryptedFileCredentialStore{
+		Passphrase: func() (string, error) { derivations++; return "correct horse", nil },
+		Iterations: 1000,
+	}
+
+	exp := time.Unix(1900000000, 0)
+	want := config.Credentials{AccessToken: "access-secret", RefreshToken: "refresh-secret", ExpiresAt: &exp, AccountEmail: "dev@example.com"}
+	if err := store.WriteCredentials(path, want); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "secret") || strings.Contains(string(data), "dev@example.com") {
+		t.Fatalf("credentials stored in plaintext: %s", data)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0o600 {
+		t.Errorf("mode %#o, want 0600", info.Mode().Perm())
+	}
+
+	got, err := store.ReadCredentials(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.AccessToken != want.AccessToken || got.RefreshToken != want.RefreshToken || !got.ExpiresAt.Equal(exp) || got.AccountEmail != want.AccountEmail {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	// A refresh rewrites the file without another key derivation.
+	if err := store.WriteCredentials(path, config.Credentials{AccessToken: "rotated", RefreshToken: "refresh-secret"}); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := store.ReadCredentials(path); err != nil || got.AccessToken != "rotated" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+	if derivations != 1 {
+		t.Errorf("expected the passphrase to be stretched once, got %d", derivations)
+	}
+
+	wrong := &config.EncryptedFileCredentialStore{Passphrase: func() (string, error) { return "wrong", nil }}
+	if _, err := wrong.ReadCredentials(path); err == nil || !strings.Contains(err.Error(), "decryption failed") {
+		t.Errorf("expected a wrong passphrase to fail, got %v", err)
+	}
+
+	// Tampering with the header is detected even though the header is not
+	// encrypted.
+	tampered := strings.Replace(string(data), `"version": "1.0"`, `"version": "1.1"`, 1)
+	os.WriteFile(path, []byte(tampered), 0o600)
+	if _, err := (&config.EncryptedFileCredentialStore{Passphrase: store.Passphrase}).ReadCredentials(path); err == nil {
+		t.Error("expected a tampered header to fail")
+	}
+
+	if err := store.DeleteCredentials(path); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.ReadCredentials(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected fs.ErrNotExist after delete, got %v", err)
+	}
+	if err := store.DeleteCredentials(path); err != nil {
+		t.Errorf("deleting missing credentials: %v", err)
+	}
+}
+
+func TestEncryptedFileCredentialStore_RejectsPlaintext(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "creds.json")
+	if err := config.WriteCredentials(path, config.Credentials{AccessToken: "tok"}); err != nil {
+		t.Fatal(err)
+	}
+	store := &config.EncryptedFileCredentialStore{Passphrase: func() (string, error) { return "pw", nil }}
+	if _, err := store.ReadCredentials(path); err == nil || !strings.Contains(err.Error(), "not encrypted") {
+		t.Errorf("expected a plaintext file to be rejected, got %v", err)
+	}
+}
+
+func TestEncryptedFileCredentialStore_PassphraseFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "creds.json")
+	store := &config.EncryptedFileCredentialStore{Iterations: 1000}
+
+	t.Setenv(config.EnvCredentialsPassphrase, "")
+	t.Setenv(config.EnvCredentialsPassphraseFile, "")
+	if err := store.WriteCredentials(path, config.Credentials{AccessToken: "tok"}); err == nil || !strings.Contains(err.Error(), config.EnvCredentialsPassphrase) {
+		t.Fatalf("expected a missing passphrase to name the env var, got %v", err)
+	}
+
+	passphraseFile := filepath.Join(dir, "passphrase")
+	os.WriteFile(passphraseFile, []byte("from-file\n"), 0o600)
+	t.Setenv(config.EnvCredentialsPassphraseFile, passphraseFile)
+	if err := store.WriteCredentials(path, config.Credentials{AccessToken: "tok"}); err != nil {
+		t.Fatal(err)
+	}
+	reader := &config.EncryptedFileCredentialStore{Passphrase: func() (string, error) { return "from-file", nil }}
+	if got, err := reader.ReadCredentials(path); err != nil || got.AccessToken != "tok" {
+		t.Fatalf("got %+v, %v", got, err)
+	}
+}
+
+func TestFileCredentialStore_RejectsUnsafePermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("mode bits are not enforced on Windows")
+	}
+	path := filepath.Join(t.TempDir(), "creds.json")
+	if err := config.WriteCredentials(path, config.Credentials{AccessToken: "tok"}); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(path, 0o644)
+	if _, err := (config.FileCredentialStore{}).ReadCredentials(path); err == nil || !strings.Contains(err.Error(), "unsafe permissions") {
+		t.Errorf("expected unsafe-permissions error, got %v", err)
+	}
+}
+
+// memoryKeyring is an in-memory [config.Keyring].
+type memoryKeyring struct {
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+func (k *memoryKeyring) Get(service, account string) (string, error) {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	secret, ok := k.secrets[service+"/"+account]
+	if !ok {
+		return "", fmt.Errorf("secret not found: %w", fs.ErrNotExist)
+	}
+	return secret, nil
+}
+
+func (k *memoryKeyring) Set(service, account, secret string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if k.secrets == nil {
+		k.secrets = map[string]string{}
+	}
+	k.secrets[service+"/"+account] = secret
+	return nil
+}
+
+func (k *memoryKeyring) Delete(service, account string) error {
+	k.mu.Lock()
+	defer k.mu.Unlock()
+	if _, ok := k.secrets[service+"/"+account]; !ok {
+		return fs.ErrNotExist
+	}
+	delete(k.secrets, service+"/"+account)
+	return nil
+}
+
+func TestKeyringCredentialStore(t *testing.T) {
+	if _, err := config.LookupCredentialStore(config.CredentialStoreKeychain); err == nil || !strings.Contains(err.Error(), "RegisterCredentialStore") {
+		t.Fatalf("expected an unregistered keychain to explain how to register one, got %v", err)
+	}
+	if _, err := config.LookupCredentialStore("vault"); err == nil || !strings.Contains(err.Error(), config.CredentialStoreEncryptedFile) {
+		t.Fatalf("expected an unknown store to list the registered ones, got %v", err)
+	}
+
+	keyring := &memoryKeyring{}
+	config.RegisterCredentialStore(config.CredentialStoreKeychain, config.NewKeyringCredentialStore(keyring))
+	t.Cleanup(func() { config.RegisterCredentialStore(config.CredentialStoreKeychain, nil) })
+
+	dir := t.TempDir()
+	server := newFakeOAuthServer(t)
+	creds, err := config.Login(context.Background(), dir, "work", config.LoginOptions{
+		ClientID:        "client-abc",
+		BaseURL:         server.URL,
+		Flow:            config.LoginFlowDeviceCode,
+		Prompt:          func(config.LoginPrompt) {},
+		CredentialStore: config.CredentialStoreKeychain,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	credPath := config.ProfileCredentialsPath(dir, "work")
+	if _, err := os.Stat(credPath); !os.IsNotExist(err) {
+		t.Errorf("expected no credentials file, got %v", err)
+	}
+	if !strings.Contains(keyring.secrets[config.KeyringService+"/"+credPath], creds.RefreshToken) {
+		t.Errorf("expected the tokens in the keychain, got %v", keyring.secrets)
+	}
+
+	cfg, err := config.LoadProfile(dir, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CredentialStore != config.CredentialStoreKeychain {
+		t.Errorf("expected the store to be saved on the profile, got %q", cfg.CredentialStore)
+	}
+
+	if err := config.Logout(context.Background(), dir, "work", config.LogoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if revoked := server.received(config.RevocationEndpoint); len(revoked) != 1 || revoked[0]["token"] != creds.RefreshToken {
+		t.Errorf("unexpected revocation requests %v", revoked)
+	}
+	if len(keyring.secrets) != 0 {
+		t.Errorf("expected the keychain entry to be deleted, got %v", keyring.secrets)
+	}
+}
+
+func TestDeleteProfile_DeletesFromCredentialStore(t *testing.T) {
+	keyring := &memoryKeyring{}
+	config.RegisterCredentialStore("test-keyring", config.NewKeyringCredentialStore(keyring))
+	t.Cleanup(func() { config.RegisterCredentialStore("test-keyring", nil) })
+
+	dir := t.TempDir()
+	if err := config.SaveProfile(dir, "work", &config.Config{
+		AuthenticationInfo: config.NewUserOAuthAuthentication("client-abc"),
+		CredentialStore:    "test-keyring",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	store, _ := config.LookupCredentialStore("test-keyring")
+	if err := store.WriteCredentials(config.ProfileCredentialsPath(dir, "work"), config.Credentials{AccessToken: "tok"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := config.DeleteProfile(dir, "work"); err != nil {
+		t.Fatal(err)
+	}
+	if len(keyring.secrets) != 0 {
+		t.Errorf("expected the keychain entry to be deleted, got %v", keyring.secrets)
+	}
+}
+
+func TestLoadProfile_CredentialStoreFromEnv(t *testing.T) {
+	dir := t.TempDir()
+	if err := config.SaveProfile(dir, "ci", &config.Config{AuthenticationInfo: config.NewUserOAuthAuthentication("client-abc")}); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("ANTHROPIC_CREDENTIAL_STORE", config.CredentialStoreEncryptedFile)
+	cfg, err := config.LoadProfile(dir, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.CredentialStore != config.CredentialStoreEncryptedFile {
+		t.Errorf("got %q, want the env store", cfg.CredentialStore)
+	}
+}
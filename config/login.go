@@ -0,0 +1,705 @@
+package config
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/internal"
+)
+
+// OAuth 2.0 endpoints and grant types used by [Login] and [Logout]. The
+// token endpoint is [TokenEndpoint]; the authorize page is served by the
+// console rather than the API.
+const (
+	// AuthorizeEndpoint is the path of the interactive authorization page,
+	// relative to [UserOAuth.ConsoleURL].
+	AuthorizeEndpoint = "/oauth/authorize"
+
+	// DeviceAuthorizationEndpoint is the path of the RFC 8628 device
+	// authorization endpoint.
+	DeviceAuthorizationEndpoint = "/v1/oauth/device_authorization"
+
+	// RevocationEndpoint is the path of the RFC 7009 token revocation
+	// endpoint.
+	RevocationEndpoint = "/v1/oauth/revoke"
+
+	// GrantTypeAuthorizationCode is the RFC 6749 §4.1 grant type string used
+	// to redeem the code returned to the loopback redirect.
+	GrantTypeAuthorizationCode = "authorization_code"
+
+	// GrantTypeDeviceCode is the RFC 8628 grant type string used to poll for
+	// the outcome of a device authorization.
+	GrantTypeDeviceCode = "urn:ietf:params:oauth:grant-type:device_code"
+)
+
+const (
+	defaultConsoleURL = "https://console.anthropic.com"
+
+	// loopbackCallbackPath is the path of the loopback redirect URI.
+	loopbackCallbackPath = "/callback"
+
+	// defaultDevicePollInterval is the RFC 8628 §3.2 default when the device
+	// authorization response omits interval.
+	defaultDevicePollInterval = 5 * time.Second
+
+	// slowDownIncrement is how much a slow_down response lengthens the
+	// polling interval (RFC 8628 §3.5).
+	slowDownIncrement = 5 * time.Second
+)
+
+// devicePollIntervalForTest, when set, replaces the device-code polling
+// interval so tests don't wait on the server-requested one.
+var devicePollIntervalForTest time.Duration
+
+// SetDevicePollIntervalForTest makes [Login] poll for device-code approval
+// every d, ignoring the interval the server asks for, until the returned
+// function is called. Exported for test use only; not part of the stable
+// API.
+func SetDevicePollIntervalForTest(d time.Duration) (restore func()) {
+	previous := devicePollIntervalForTest
+	devicePollIntervalForTest = d
+	return func() { devicePollIntervalForTest = previous }
+}
+
+// LoginFlow selects how [Login] obtains the user's authorization.
+type LoginFlow string
+
+const (
+	// LoginFlowBrowser runs the authorization-code flow with PKCE (RFC 7636):
+	// the user approves access in a browser, which redirects back to a
+	// listener on the loopback interface.
+	LoginFlowBrowser LoginFlow = "browser"
+
+	// LoginFlowDeviceCode runs the device authorization flow (RFC 8628): the
+	// user enters a short code on any device with a browser while the SDK
+	// polls for approval. Use it on headless machines and over SSH.
+	LoginFlowDeviceCode LoginFlow = "device_code"
+)
+
+// LoginPrompt is what [Login] asks the user to do.
+type LoginPrompt struct {
+	// URL is the page the user must open: the authorize page for
+	// [LoginFlowBrowser], or the verification page for [LoginFlowDeviceCode].
+	URL string
+	// UserCode is the code the user enters on the verification page. Empty
+	// for [LoginFlowBrowser], and for device flows where URL already
+	// embeds it.
+	UserCode string
+	// ExpiresAt is when the device code expires. Zero for [LoginFlowBrowser].
+	ExpiresAt time.Time
+}
+
+// LoginOptions configures [Login].
+type LoginOptions struct {
+	// ClientID is the OAuth client ID of the application logging in.
+	// Required. It is saved on the profile so the access token can be
+	// refreshed.
+	ClientID string
+
+	// Scope is the space-delimited scope to request. When empty, the
+	// server grants the client's default scope.
+	Scope string
+
+	// Flow selects the login flow. Defaults to [LoginFlowBrowser].
+	Flow LoginFlow
+
+	// ConsoleURL is the base URL of the authorize page for
+	// [LoginFlowBrowser]. Defaults to the profile's existing
+	// [UserOAuth.ConsoleURL], then https://console.anthropic.com.
+	ConsoleURL string
+
+	// BaseURL overrides the API base URL, where the token, device
+	// authorization and revocation endpoints live. Defaults to the profile's
+	// existing [Config.BaseURL], then https://api.anthropic.com. It is
+	// saved on the profile.
+	BaseURL string
+
+	// OrganizationID and WorkspaceID, when set, are saved on the profile.
+	// Otherwise the profile's existing values are kept.
+	OrganizationID string
+	WorkspaceID    string
+
+	// RedirectPort is the loopback port to listen on for
+	// [LoginFlowBrowser]. When 0, a free port is chosen. Set it when the
+	// OAuth client only allows a fixed redirect URI, which is then
+	// http://127.0.0.1:<port>/callback.
+	RedirectPort int
+
+	// Prompt tells the user what to do. The default prints the URL, and the
+	// user code if any, to standard error.
+	Prompt func(LoginPrompt)
+
+	// OpenURL opens the authorize page for [LoginFlowBrowser]. The default
+	// uses the platform's browser launcher; failures are ignored, since the
+	// URL has also been shown by Prompt.
+	OpenURL func(url string) error
+
+	// SetActive makes the profile the active one with [SetActiveProfile]
+	// once login succeeds.
+	SetActive bool
+
+	// CredentialStore selects the [CredentialStore] the tokens are written
+	// to and is saved on the profile. When empty, an existing profile's
+	// store is kept, falling back to [CredentialStoreFile].
+	CredentialStore string
+
+	// HTTPClient overrides the default HTTP client used for the token
+	// requests. When nil, a client with a 30s timeout is used.
+	HTTPClient *http.Client
+
+	// UserAgent overrides the outgoing User-Agent header. When empty, the
+	// SDK sends "anthropic-sdk-go/<version> Login".
+	UserAgent string
+}
+
+// LoginError is returned by [Login] and [Logout] when an OAuth endpoint
+// responds with an error. As with [FederationExchangeError], the server
+// body is kept verbatim and redacted when formatted.
+type LoginError struct {
+	StatusCode int
+	Body       string
+	RequestID  string
+}
+
+func (e *LoginError) Error() string {
+	redacted := RedactOAuthErrorBody(e.Body)
+	if e.RequestID != "" {
+		return fmt.Sprintf("oauth request failed (status %d, request-id %s): %s",
+			e.StatusCode, e.RequestID, redacted)
+	}
+	return fmt.Sprintf("oauth request failed (status %d): %s", e.StatusCode, redacted)
+}
+
+// oauthErrorCode returns the RFC 6749 §5.2 error code of the response.
+func (e *LoginError) oauthErrorCode() string {
+	var body struct {
+		Error string `json:"error"`
+	}
+	json.Unmarshal([]byte(e.Body), &body)
+	return body.Error
+}
+
+// Login runs an interactive OAuth login for profile under dir and stores the
+// result: the tokens are written to the profile's [CredentialStore] and the
+// profile is saved with [SaveProfile] as a user_oauth profile, so that
+// [LoadProfile] and option.WithConfig pick it up and refresh it. An existing
+// profile's base URL, organization, workspace, credentials path and
+// credential store are kept unless opts overrides them.
+//
+// Login blocks until the user approves or denies access, or ctx is done.
+func Login(ctx context.Context, dir, profile string, opts LoginOptions) (*Credentials, error) {
+	if err := validateDirAndProfile(dir, profile); err != nil {
+		return nil, fmt.Errorf("Login: %w", err)
+	}
+	if opts.ClientID == "" {
+		return nil, errors.New("Login: ClientID is required")
+	}
+
+	existing, err := readProfileFile(dir, profile)
+	if err != nil {
+		return nil, fmt.Errorf("Login: %w", err)
+	}
+	cfg := &Config{}
+	var previous *UserOAuth
+	if existing != nil {
+		cfg.BaseURL = existing.BaseURL
+		cfg.OrganizationID = existing.OrganizationID
+		cfg.WorkspaceID = existing.WorkspaceID
+		cfg.CredentialStore = existing.CredentialStore
+		if existing.AuthenticationInfo != nil && existing.AuthenticationInfo.Type == AuthenticationTypeUserOAuth {
+			previous = existing.AuthenticationInfo.UserOAuth
+		}
+	}
+	cfg.AuthenticationInfo = NewUserOAuthAuthentication(opts.ClientID)
+	if existing != nil && existing.AuthenticationInfo != nil {
+		cfg.AuthenticationInfo.CredentialsPath = existing.AuthenticationInfo.CredentialsPath
+	}
+	if opts.BaseURL != "" {
+		cfg.BaseURL = opts.BaseURL
+	}
+	if opts.OrganizationID != "" {
+		cfg.OrganizationID = opts.OrganizationID
+	}
+	if opts.WorkspaceID != "" {
+		cfg.WorkspaceID = opts.WorkspaceID
+	}
+	if opts.CredentialStore != "" {
+		cfg.CredentialStore = opts.CredentialStore
+	}
+	// Resolve the store before the user goes through the flow, not after.
+	store, err := LookupCredentialStore(cfg.CredentialStore)
+	if err != nil {
+		return nil, fmt.Errorf("Login: %w", err)
+	}
+	consoleURL := opts.ConsoleURL
+	if consoleURL == "" && previous != nil {
+		consoleURL = previous.ConsoleURL
+	}
+	cfg.AuthenticationInfo.UserOAuth.ConsoleURL = consoleURL
+
+	c := newOAuthClient(cfg.BaseURL, opts.HTTPClient, opts.UserAgent, "Login")
+	if opts.Prompt == nil {
+		opts.Prompt = printLoginPrompt
+	}
+
+	var creds *Credentials
+	switch opts.Flow {
+	case LoginFlowBrowser, "":
+		if consoleURL == "" {
+			consoleURL = defaultConsoleURL
+		}
+		if opts.OpenURL == nil {
+			opts.OpenURL = openBrowser
+		}
+		creds, err = loginWithBrowser(ctx, c, consoleURL, opts)
+	case LoginFlowDeviceCode:
+		creds, err = loginWithDeviceCode(ctx, c, opts)
+	default:
+		return nil, fmt.Errorf("Login: unknown flow %q", opts.Flow)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Login: %w", err)
+	}
+	cfg.AuthenticationInfo.UserOAuth.Scope = creds.Scope
+
+	credPath := cfg.AuthenticationInfo.CredentialsPath
+	if credPath == "" {
+		credPath = ProfileCredentialsPath(dir, profile)
+	}
+	if err := store.WriteCredentials(credPath, *creds); err != nil {
+		return nil, fmt.Errorf("Login: %w", err)
+	}
+	if err := SaveProfile(dir, profile, cfg); err != nil {
+		return nil, fmt.Errorf("Login: %w", err)
+	}
+	if opts.SetActive {
+		if err := SetActiveProfile(dir, profile); err != nil {
+			return nil, fmt.Errorf("Login: %w", err)
+		}
+	}
+	return creds, nil
+}
+
+// LogoutOptions configures [Logout].
+type LogoutOptions struct {
+	// DeleteProfile also removes the profile with [DeleteProfile]. By
+	// default configs/<profile>.json is kept, so a later [Login] reuses its
+	// settings.
+	DeleteProfile bool
+
+	// HTTPClient overrides the default HTTP client used for the revocation
+	// request. When nil, a client with a 30s timeout is used.
+	HTTPClient *http.Client
+
+	// UserAgent overrides the outgoing User-Agent header. When empty, the
+	// SDK sends "anthropic-sdk-go/<version> Logout".
+	UserAgent string
+}
+
+// Logout revokes the tokens of the user_oauth profile under dir and deletes
+// its credentials from the profile's [CredentialStore]. The refresh token, if any, is revoked, which per RFC
+// 7009 also invalidates the access tokens minted from it; otherwise the
+// access token is.
+//
+// The credentials are deleted even if revocation fails, so the user is
+// always logged out locally; the revocation error is still returned. A
+// profile without credentials is already logged out and is not an error.
+func Logout(ctx context.Context, dir, profile string, opts LogoutOptions) error {
+	cfg, err := LoadProfile(dir, profile)
+	if err != nil {
+		return fmt.Errorf("Logout: %w", err)
+	}
+	auth := cfg.AuthenticationInfo
+	if auth.Type != AuthenticationTypeUserOAuth || auth.UserOAuth == nil {
+		return fmt.Errorf("Logout: profile %q is not a %s profile", profile, AuthenticationTypeUserOAuth)
+	}
+
+	store, err := LookupCredentialStore(cfg.CredentialStore)
+	if err != nil {
+		return fmt.Errorf("Logout: %w", err)
+	}
+
+	var revokeErr error
+	creds, err := store.ReadCredentials(auth.CredentialsPath)
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("Logout: read %q: %w", auth.CredentialsPath, err)
+	default:
+		token, hint := creds.RefreshToken, "refresh_token"
+		if token == "" {
+			token, hint = creds.AccessToken, "access_token"
+		}
+		if token != "" {
+			c := newOAuthClient(cfg.BaseURL, opts.HTTPClient, opts.UserAgent, "Logout")
+			revokeErr = c.revoke(ctx, auth.UserOAuth.ClientID, token, hint)
+		}
+		if err := store.DeleteCredentials(auth.CredentialsPath); err != nil {
+			return fmt.Errorf("Logout: remove %q: %w", auth.CredentialsPath, err)
+		}
+	}
+
+	if opts.DeleteProfile {
+		if err := DeleteProfile(dir, profile); err != nil {
+			return fmt.Errorf("Logout: %w", err)
+		}
+	}
+	if revokeErr != nil {
+		return fmt.Errorf("Logout: revoke: %w", revokeErr)
+	}
+	return nil
+}
+
+// readProfileFile reads configs/<profile>.json as written, without the
+// defaults and environment fallbacks of [LoadProfile]. A missing file
+// returns nil.
+func readProfileFile(dir, profile string) (*Config, error) {
+	path := ProfilePath(dir, profile)
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read %q: %w", path, err)
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("parse %q: %w", path, err)
+	}
+	return &cfg, nil
+}
+
+func loginWithBrowser(ctx context.Context, c *oauthClient, consoleURL string, opts LoginOptions) (*Credentials, error) {
+	ln, err := net.Listen("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(opts.RedirectPort)))
+	if err != nil {
+		return nil, fmt.Errorf("listen for redirect: %w", err)
+	}
+	defer ln.Close()
+	redirectURI := fmt.Sprintf("http://127.0.0.1:%d%s", ln.Addr().(*net.TCPAddr).Port, loopbackCallbackPath)
+
+	verifier := randomToken()
+	challenge := sha256.Sum256([]byte(verifier))
+	state := randomToken()
+
+	query := url.Values{
+		"response_type":         {"code"},
+		"client_id":             {opts.ClientID},
+		"redirect_uri":          {redirectURI},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(challenge[:])},
+		"code_challenge_method": {"S256"},
+		"state":                 {state},
+	}
+	if opts.Scope != "" {
+		query.Set("scope", opts.Scope)
+	}
+	authorizeURL := strings.TrimRight(consoleURL, "/") + AuthorizeEndpoint + "?" + query.Encode()
+
+	type callback struct {
+		code string
+		err  error
+	}
+	results := make(chan callback, 1)
+	server := &http.Server{
+		ReadHeaderTimeout: 10 * time.Second,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != loopbackCallbackPath {
+				http.NotFound(w, r)
+				return
+			}
+			q := r.URL.Query()
+			// Ignore requests that don't carry our state: they are not the
+			// redirect for this login, and must not end it.
+			if q.Get("state") != state {
+				http.Error(w, "Invalid state.", http.StatusBadRequest)
+				return
+			}
+			var result callback
+			if code := q.Get("error"); code != "" {
+				body, _ := json.Marshal(map[string]string{"error": code, "error_description": q.Get("error_description")})
+				result.err = &LoginError{StatusCode: http.StatusBadRequest, Body: string(body)}
+				writeLoginPage(w, http.StatusBadRequest, "Login failed", "Access was not granted. You can close this window.")
+			} else if result.code = q.Get("code"); result.code == "" {
+				http.Error(w, "Missing code.", http.StatusBadRequest)
+				return
+			} else {
+				writeLoginPage(w, http.StatusOK, "Login complete", "You can close this window and return to the terminal.")
+			}
+			select {
+			case results <- result:
+			default:
+			}
+		}),
+	}
+	go server.Serve(ln)
+	defer server.Close()
+
+	opts.Prompt(LoginPrompt{URL: authorizeURL})
+	_ = opts.OpenURL(authorizeURL)
+
+	var result callback
+	select {
+	case result = <-results:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	if result.err != nil {
+		return nil, result.err
+	}
+	return c.token(ctx, map[string]string{
+		"grant_type":    GrantTypeAuthorizationCode,
+		"code":          result.code,
+		"redirect_uri":  redirectURI,
+		"client_id":     opts.ClientID,
+		"code_verifier": verifier,
+		"state":         state,
+	})
+}
+
+func loginWithDeviceCode(ctx context.Context, c *oauthClient, opts LoginOptions) (*Credentials, error) {
+	request := map[string]string{"client_id": opts.ClientID}
+	if opts.Scope != "" {
+		request["scope"] = opts.Scope
+	}
+	body, err := c.post(ctx, DeviceAuthorizationEndpoint, request)
+	if err != nil {
+		return nil, err
+	}
+	var device struct {
+		DeviceCode              string `json:"device_code"`
+		UserCode                string `json:"user_code"`
+		VerificationURI         string `json:"verification_uri"`
+		VerificationURIComplete string `json:"verification_uri_complete"`
+		ExpiresIn               int    `json:"expires_in"`
+		Interval                int    `json:"interval"`
+	}
+	if err := json.Unmarshal(body, &device); err != nil {
+		return nil, fmt.Errorf("parse device authorization response: %w", err)
+	}
+	if device.DeviceCode == "" || device.VerificationURI == "" {
+		return nil, errors.New("device authorization response missing device_code or verification_uri")
+	}
+
+	prompt := LoginPrompt{URL: device.VerificationURI, UserCode: device.UserCode}
+	if device.VerificationURIComplete != "" {
+		prompt.URL = device.VerificationURIComplete
+	}
+	if device.ExpiresIn > 0 {
+		prompt.ExpiresAt = time.Now().Add(time.Duration(device.ExpiresIn) * time.Second)
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithDeadline(ctx, prompt.ExpiresAt)
+		defer cancel()
+	}
+	opts.Prompt(prompt)
+
+	interval := defaultDevicePollInterval
+	if device.Interval > 0 {
+		interval = time.Duration(device.Interval) * time.Second
+	}
+	if devicePollIntervalForTest > 0 {
+		interval = devicePollIntervalForTest
+	}
+	for {
+		select {
+		case <-time.After(interval):
+		case <-ctx.Done():
+			if errors.Is(ctx.Err(), context.DeadlineExceeded) && !prompt.ExpiresAt.IsZero() && !time.Now().Before(prompt.ExpiresAt) {
+				return nil, errors.New("device code expired before access was granted")
+			}
+			return nil, ctx.Err()
+		}
+		creds, err := c.token(ctx, map[string]string{
+			"grant_type":  GrantTypeDeviceCode,
+			"device_code": device.DeviceCode,
+			"client_id":   opts.ClientID,
+		})
+		var loginErr *LoginError
+		if errors.As(err, &loginErr) {
+			// RFC 8628 §3.5: keep polling while the user has not yet
+			// decided, backing off when asked to.
+			switch loginErr.oauthErrorCode() {
+			case "authorization_pending":
+				continue
+			case "slow_down":
+				interval += slowDownIncrement
+				continue
+			}
+		}
+		return creds, err
+	}
+}
+
+// oauthClient sends requests to the OAuth endpoints of the API.
+type oauthClient struct {
+	base      string
+	client    *http.Client
+	userAgent string
+}
+
+func newOAuthClient(baseURL string, client *http.Client, userAgent, caller string) *oauthClient {
+	base := strings.TrimRight(baseURL, "/")
+	if base == "" {
+		base = defaultAPIBaseURL
+	}
+	if client == nil {
+		client = &http.Client{Timeout: 30 * time.Second}
+	}
+	if userAgent == "" {
+		userAgent = "anthropic-sdk-go/" + internal.PackageVersion + " " + caller
+	}
+	return &oauthClient{base: base, client: client, userAgent: userAgent}
+}
+
+// post sends request as JSON to the endpoint at path and returns the body
+// of a 2xx response. Other responses are returned as a [*LoginError].
+func (c *oauthClient) post(ctx context.Context, path string, request map[string]string) ([]byte, error) {
+	bodyJSON, err := json.Marshal(request)
+	if err != nil {
+		return nil, fmt.Errorf("marshal body: %w", err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.base+path, bytes.NewReader(bodyJSON))
+	if err != nil {
+		return nil, fmt.Errorf("build request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	// The user-facing grants are served by the oauth-server, like
+	// refresh_token grants: send the OAuth beta, and never the federation
+	// one.
+	req.Header.Set("anthropic-beta", OAuthAPIBetaHeader)
+	req.Header.Set("User-Agent", c.userAgent)
+
+	resp, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("post %s: %w", path, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, &LoginError{
+			StatusCode: resp.StatusCode,
+			Body:       string(body),
+			RequestID:  resp.Header.Get("Request-Id"),
+		}
+	}
+	return body, nil
+}
+
+// token redeems a grant at [TokenEndpoint].
+func (c *oauthClient) token(ctx context.Context, request map[string]string) (*Credentials, error) {
+	body, err := c.post(ctx, TokenEndpoint, request)
+	if err != nil {
+		return nil, err
+	}
+	var parsed struct {
+		AccessToken  string `json:"access_token"`
+		TokenType    string `json:"token_type,omitempty"`
+		RefreshToken string `json:"refresh_token,omitempty"`
+		ExpiresIn    *int   `json:"expires_in,omitempty"`
+		Scope        string `json:"scope,omitempty"`
+		Account      struct {
+			EmailAddress string `json:"email_address"`
+		} `json:"account"`
+		Organization struct {
+			UUID string `json:"uuid"`
+			Name string `json:"name"`
+		} `json:"organization"`
+		Workspace struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		} `json:"workspace"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return nil, fmt.Errorf("parse token response: %w", err)
+	}
+	if parsed.AccessToken == "" {
+		return nil, errors.New("token response missing access_token")
+	}
+	if parsed.TokenType != "" && !strings.EqualFold(parsed.TokenType, "Bearer") {
+		return nil, fmt.Errorf("unsupported token_type %q (want Bearer)", parsed.TokenType)
+	}
+
+	creds := &Credentials{
+		AccessToken:      parsed.AccessToken,
+		RefreshToken:     parsed.RefreshToken,
+		Scope:            parsed.Scope,
+		OrganizationUUID: parsed.Organization.UUID,
+		OrganizationName: parsed.Organization.Name,
+		AccountEmail:     parsed.Account.EmailAddress,
+		WorkspaceID:      parsed.Workspace.ID,
+		WorkspaceName:    parsed.Workspace.Name,
+	}
+	if parsed.ExpiresIn != nil {
+		exp := time.Now().Add(time.Duration(*parsed.ExpiresIn) * time.Second)
+		creds.ExpiresAt = &exp
+	}
+	return creds, nil
+}
+
+// revoke revokes token at [RevocationEndpoint].
+func (c *oauthClient) revoke(ctx context.Context, clientID, token, tokenTypeHint string) error {
+	request := map[string]string{"token": token, "token_type_hint": tokenTypeHint}
+	if clientID != "" {
+		request["client_id"] = clientID
+	}
+	_, err := c.post(ctx, RevocationEndpoint, request)
+	return err
+}
+
+// randomToken returns 32 random bytes, base64url-encoded: a PKCE code
+// verifier (RFC 7636 §4.1) or a state value.
+func randomToken() string {
+	b := make([]byte, 32)
+	rand.Read(b)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func printLoginPrompt(p LoginPrompt) {
+	if p.UserCode != "" {
+		fmt.Fprintf(os.Stderr, "To log in, open %s and enter the code %s\n", p.URL, p.UserCode)
+		return
+	}
+	fmt.Fprintf(os.Stderr, "To log in, open this URL in your browser:\n\n  %s\n\n", p.URL)
+}
+
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait()
+	return nil
+}
+
+func writeLoginPage(w http.ResponseWriter, status int, title, message string) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	fmt.Fprintf(w, "<!doctype html><title>%s</title><p>%s</p>\n", html.EscapeString(title), html.EscapeString(message))
+}
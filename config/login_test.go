@@ -0,0 +1,333 @@
+package config_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/config"
+)
+
+// fakeOAuthServer serves the token, device authorization and revocation
+// endpoints, recording the requests it receives.
+type fakeOAuthServer struct {
+	*httptest.Server
+
+	mu       sync.Mutex
+	requests map[string][]map[string]string
+	// pending is the number of device-code polls answered with
+	// authorization_pending before the grant succeeds.
+	pending int
+	// verify checks an authorization_code request.
+	verify func(req map[string]string) bool
+}
+
+func newFakeOAuthServer(t *testing.T) *fakeOAuthServer {
+	t.Cleanup(config.SetDevicePollIntervalForTest(time.Millisecond))
+	f := &fakeOAuthServer{requests: map[string][]map[string]string{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("anthropic-beta") != config.OAuthAPIBetaHeader {
+			t.Errorf("%s: unexpected anthropic-beta %q", r.URL.Path, r.Header.Get("anthropic-beta"))
+		}
+		var req map[string]string
+		json.NewDecoder(r.Body).Decode(&req)
+		f.mu.Lock()
+		f.requests[r.URL.Path] = append(f.requests[r.URL.Path], req)
+		f.mu.Unlock()
+
+		w.Header().Set("Content-Type", "application/json")
+		switch r.URL.Path {
+		case config.DeviceAuthorizationEndpoint:
+			json.NewEncoder(w).Encode(map[string]any{
+				"device_code":      "dev-123",
+				"user_code":        "ABCD-EFGH",
+				"verification_uri": "https://console.example/device",
+				"expires_in":       60,
+				"interval":         0,
+			})
+		case config.TokenEndpoint:
+			switch req["grant_type"] {
+			case config.GrantTypeDeviceCode:
+				f.mu.Lock()
+				pending := f.pending > 0
+				f.pending--
+				f.mu.Unlock()
+				if pending {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"error":"authorization_pending"}`))
+					return
+				}
+			case config.GrantTypeAuthorizationCode:
+				if f.verify != nil && !f.verify(req) {
+					w.WriteHeader(http.StatusBadRequest)
+					w.Write([]byte(`{"error":"invalid_grant"}`))
+					return
+				}
+			}
+			json.NewEncoder(w).Encode(map[string]any{
+				"access_token":  "access-" + req["grant_type"],
+				"refresh_token": "refresh-token",
+				"token_type":    "Bearer",
+				"expires_in":    3600,
+				"scope":         "user:inference",
+				"account":       map[string]any{"email_address": "user@example.com"},
+				"organization":  map[string]any{"uuid": "org-uuid", "name": "Example"},
+			})
+		case config.RevocationEndpoint:
+			w.WriteHeader(http.StatusOK)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeOAuthServer) received(path string) []map[string]string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.requests[path]
+}
+
+func TestLogin_Browser(t *testing.T) {
+	dir := t.TempDir()
+	server := newFakeOAuthServer(t)
+
+	var challenge string
+	server.verify = func(req map[string]string) bool {
+		sum := sha256.Sum256([]byte(req["code_verifier"]))
+		return req["code"] == "auth-code" && base64.RawURLEncoding.EncodeToString(sum[:]) == challenge
+	}
+
+	var prompted string
+	creds, err := config.Login(context.Background(), dir, "work", config.LoginOptions{
+		ClientID:   "client-abc",
+		Scope:      "user:inference",
+		ConsoleURL: "https://console.example",
+		BaseURL:    server.URL,
+		Prompt:     func(p config.LoginPrompt) { prompted = p.URL },
+		// Stand in for the browser: approve and follow the redirect.
+		OpenURL: func(authorize string) error {
+			u, err := url.Parse(authorize)
+			if err != nil {
+				return err
+			}
+			q := u.Query()
+			if u.Path != config.AuthorizeEndpoint || q.Get("client_id") != "client-abc" || q.Get("code_challenge_method") != "S256" || q.Get("scope") != "user:inference" {
+				t.Errorf("unexpected authorize URL %s", authorize)
+			}
+			challenge = q.Get("code_challenge")
+			if redirect, _ := url.Parse(q.Get("redirect_uri")); redirect == nil || redirect.Hostname() != "127.0.0.1" {
+				t.Errorf("expected a redirect URI on the literal loopback IP, got %q", q.Get("redirect_uri"))
+			}
+
+			// A redirect with the wrong state is rejected and does not end
+			// the login.
+			res, err := http.Get(q.Get("redirect_uri") + "?code=stolen&state=wrong")
+			if err != nil {
+				return err
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusBadRequest {
+				t.Errorf("expected a bad state to be rejected, got %d", res.StatusCode)
+			}
+
+			res, err = http.Get(q.Get("redirect_uri") + "?code=auth-code&state=" + url.QueryEscape(q.Get("state")))
+			if err != nil {
+				return err
+			}
+			res.Body.Close()
+			if res.StatusCode != http.StatusOK {
+				t.Errorf("redirect: status %d", res.StatusCode)
+			}
+			return nil
+		},
+		SetActive: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompted == "" {
+		t.Error("expected the user to be prompted with the authorize URL")
+	}
+	if creds.AccessToken != "access-authorization_code" || creds.RefreshToken != "refresh-token" || creds.AccountEmail != "user@example.com" || creds.ExpiresAt == nil {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+	if got := server.received(config.TokenEndpoint)[0]["client_id"]; got != "client-abc" {
+		t.Errorf("token request client_id: %q", got)
+	}
+
+	t.Setenv("ANTHROPIC_CONFIG_DIR", dir)
+	t.Setenv("ANTHROPIC_PROFILE", "")
+	os.Unsetenv("ANTHROPIC_PROFILE")
+	cfg, err := config.LoadConfig()
+	if err != nil {
+		t.Fatal(err)
+	}
+	oauth := cfg.AuthenticationInfo.UserOAuth
+	if cfg.AuthenticationInfo.Type != config.AuthenticationTypeUserOAuth || oauth.ClientID != "client-abc" || oauth.Scope != "user:inference" || oauth.ConsoleURL != "https://console.example" || cfg.BaseURL != server.URL {
+		t.Errorf("unexpected saved profile %+v %+v", cfg, oauth)
+	}
+	data, err := os.ReadFile(config.ProfileCredentialsPath(dir, "work"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var saved config.Credentials
+	if err := json.Unmarshal(data, &saved); err != nil || saved.RefreshToken != "refresh-token" {
+		t.Errorf("unexpected saved credentials %s (%v)", data, err)
+	}
+}
+
+func TestLogin_BrowserDenied(t *testing.T) {
+	server := newFakeOAuthServer(t)
+	_, err := config.Login(context.Background(), t.TempDir(), "default", config.LoginOptions{
+		ClientID: "client-abc",
+		BaseURL:  server.URL,
+		Prompt:   func(config.LoginPrompt) {},
+		OpenURL: func(authorize string) error {
+			u, _ := url.Parse(authorize)
+			res, err := http.Get(u.Query().Get("redirect_uri") + "?error=access_denied&state=" + url.QueryEscape(u.Query().Get("state")))
+			if err == nil {
+				res.Body.Close()
+			}
+			return err
+		},
+	})
+	var loginErr *config.LoginError
+	if !errors.As(err, &loginErr) {
+		t.Fatalf("expected a LoginError, got %v", err)
+	}
+	if len(server.received(config.TokenEndpoint)) != 0 {
+		t.Error("expected no token request after a denied authorization")
+	}
+}
+
+func TestLogin_DeviceCode(t *testing.T) {
+	dir := t.TempDir()
+	server := newFakeOAuthServer(t)
+	server.pending = 2
+
+	// An existing profile's settings are kept.
+	if err := config.SaveProfile(dir, "ci", &config.Config{
+		AuthenticationInfo: config.NewUserOAuthAuthentication("old-client"),
+		BaseURL:            server.URL,
+		WorkspaceID:        "wrkspc_keep",
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	var prompt config.LoginPrompt
+	creds, err := config.Login(context.Background(), dir, "ci", config.LoginOptions{
+		ClientID: "client-abc",
+		Flow:     config.LoginFlowDeviceCode,
+		Prompt:   func(p config.LoginPrompt) { prompt = p },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prompt.UserCode != "ABCD-EFGH" || prompt.URL != "https://console.example/device" || prompt.ExpiresAt.IsZero() {
+		t.Errorf("unexpected prompt %+v", prompt)
+	}
+	if creds.AccessToken != "access-"+config.GrantTypeDeviceCode {
+		t.Errorf("unexpected access token %q", creds.AccessToken)
+	}
+	if polls := server.received(config.TokenEndpoint); len(polls) != 3 || polls[0]["device_code"] != "dev-123" {
+		t.Errorf("expected 3 polls for the device code, got %v", polls)
+	}
+
+	cfg, err := config.LoadProfile(dir, "ci")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.AuthenticationInfo.UserOAuth.ClientID != "client-abc" || cfg.WorkspaceID != "wrkspc_keep" {
+		t.Errorf("unexpected saved profile %+v", cfg)
+	}
+}
+
+func TestLogin_DeviceCodeCanceled(t *testing.T) {
+	server := newFakeOAuthServer(t)
+	server.pending = 1 << 30
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := config.Login(ctx, t.TempDir(), "default", config.LoginOptions{
+		ClientID: "client-abc",
+		BaseURL:  server.URL,
+		Flow:     config.LoginFlowDeviceCode,
+		Prompt:   func(config.LoginPrompt) {},
+	})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("expected the login to stop with its context, got %v", err)
+	}
+}
+
+func TestLogout(t *testing.T) {
+	dir := t.TempDir()
+	server := newFakeOAuthServer(t)
+	cfg := &config.Config{AuthenticationInfo: config.NewUserOAuthAuthentication("client-abc"), BaseURL: server.URL}
+	if err := config.SaveProfile(dir, "work", cfg); err != nil {
+		t.Fatal(err)
+	}
+	credPath := config.ProfileCredentialsPath(dir, "work")
+	if err := config.WriteCredentials(credPath, config.Credentials{AccessToken: "access", RefreshToken: "refresh"}); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := config.Logout(context.Background(), dir, "work", config.LogoutOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	revoked := server.received(config.RevocationEndpoint)
+	if len(revoked) != 1 || revoked[0]["token"] != "refresh" || revoked[0]["token_type_hint"] != "refresh_token" || revoked[0]["client_id"] != "client-abc" {
+		t.Errorf("unexpected revocation requests %v", revoked)
+	}
+	if _, err := os.Stat(credPath); !os.IsNotExist(err) {
+		t.Errorf("expected the credentials to be deleted, got %v", err)
+	}
+	if _, err := config.LoadProfile(dir, "work"); err != nil {
+		t.Errorf("expected the profile to be kept, got %v", err)
+	}
+
+	// Logging out again is a no-op.
+	if err := config.Logout(context.Background(), dir, "work", config.LogoutOptions{DeleteProfile: true}); err != nil {
+		t.Fatal(err)
+	}
+	if len(server.received(config.RevocationEndpoint)) != 1 {
+		t.Error("expected no revocation without credentials")
+	}
+	if _, err := os.Stat(config.ProfilePath(dir, "work")); !os.IsNotExist(err) {
+		t.Errorf("expected DeleteProfile to remove the profile, got %v", err)
+	}
+}
+
+func TestLogout_RevocationFailureStillDeletes(t *testing.T) {
+	dir := t.TempDir()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+		w.Write([]byte(`{"error":"temporarily_unavailable"}`))
+	}))
+	defer server.Close()
+	if err := config.SaveProfile(dir, "work", &config.Config{AuthenticationInfo: config.NewUserOAuthAuthentication(""), BaseURL: server.URL}); err != nil {
+		t.Fatal(err)
+	}
+	credPath := config.ProfileCredentialsPath(dir, "work")
+	if err := config.WriteCredentials(credPath, config.Credentials{AccessToken: "access"}); err != nil {
+		t.Fatal(err)
+	}
+
+	err := config.Logout(context.Background(), dir, "work", config.LogoutOptions{})
+	var loginErr *config.LoginError
+	if !errors.As(err, &loginErr) || loginErr.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected the revocation error, got %v", err)
+	}
+	if _, err := os.Stat(credPath); !os.IsNotExist(err) {
+		t.Errorf("expected the credentials to be deleted, got %v", err)
+	}
+}
@@ -344,12 +344,28 @@ func SetActiveProfile(dir, profile string) error {
 // DeleteProfile removes configs/<profile>.json and credentials/<profile>.json
 // under dir. If active_config currently points at profile, the pointer file
 // is also cleared so the next [LoadConfig] call falls back to "default".
-// Missing files are not an error — DeleteProfile is idempotent.
+// When the profile names a [Config.CredentialStore], its credentials are
+// deleted from that store as well. Missing files are not an error —
+// DeleteProfile is idempotent.
 func DeleteProfile(dir, profile string) error {
 	if err := validateDirAndProfile(dir, profile); err != nil {
 		return fmt.Errorf("DeleteProfile: %w", err)
 	}
 
+	if cfg, err := readProfileFile(dir, profile); err == nil && cfg != nil && cfg.CredentialStore != "" {
+		store, err := LookupCredentialStore(cfg.CredentialStore)
+		if err != nil {
+			return fmt.Errorf("DeleteProfile: %w", err)
+		}
+		key := ProfileCredentialsPath(dir, profile)
+		if cfg.AuthenticationInfo != nil && cfg.AuthenticationInfo.CredentialsPath != "" {
+			key = cfg.AuthenticationInfo.CredentialsPath
+		}
+		if err := store.DeleteCredentials(key); err != nil {
+			return fmt.Errorf("DeleteProfile: %w", err)
+		}
+	}
+
 	for _, p := range []string{
 		ProfilePath(dir, profile),
 		ProfileCredentialsPath(dir, profile),
@@ -9,6 +9,7 @@ import (
 	"net/http/httputil"
 
 	"github.com/anthropics/anthropic-sdk-go/internal/apijson"
+	"github.com/anthropics/anthropic-sdk-go/packages/ratelimit"
 	"github.com/anthropics/anthropic-sdk-go/packages/respjson"
 	"github.com/anthropics/anthropic-sdk-go/shared"
 )
@@ -35,6 +36,16 @@ type Error struct {
 // response body did not contain a recognized error type.
 func (r *Error) Type() shared.ErrorType { return r.errorType }
 
+// RateLimit returns the rate-limit state reported by the error response's
+// anthropic-ratelimit-* and retry-after headers. It reports false if the
+// response carried none.
+func (r *Error) RateLimit() (ratelimit.Snapshot, bool) {
+	if r.Response == nil {
+		return ratelimit.Snapshot{}, false
+	}
+	return ratelimit.FromHeader(r.Response.Header)
+}
+
 // Returns the unmodified JSON received from the API
 func (r Error) RawJSON() string { return r.JSON.raw }
 
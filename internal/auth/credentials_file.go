@@ -4,12 +4,11 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"io/fs"
 	"net/http"
-	"os"
-	"runtime"
 	"strings"
 	"time"
 
@@ -17,9 +16,10 @@ import (
 	"github.com/anthropics/anthropic-sdk-go/internal"
 )
 
-// Credentials file schema — credentials/<name>.json. Reads only; all writes
-// go through config.WriteCredentials for a single-source-of-truth on the
-// atomic + fsync + Chmod guarantees.
+// credentialsTokenData is the subset of the stored credentials the
+// providers below use. Reads and writes go through the profile's
+// [config.CredentialStore]; the file store keeps the atomic + fsync + Chmod
+// guarantees of config.WriteCredentials and refuses unsafe files on read.
 type credentialsTokenData struct {
 	Type         string `json:"type"`
 	AccessToken  string `json:"access_token"`
@@ -28,40 +28,24 @@ type credentialsTokenData struct {
 }
 
 func readCredentialsFile(path string) (*credentialsTokenData, error) {
-	if err := checkCredentialsFileSafety(path); err != nil {
-		return nil, err
-	}
-	data, err := os.ReadFile(path)
-	if err != nil {
-		return nil, err
-	}
-	var cred credentialsTokenData
-	if err := json.Unmarshal(data, &cred); err != nil {
-		return nil, err
-	}
-	return &cred, nil
+	return readStoredCredentials(config.FileCredentialStore{}, path)
 }
 
-// checkCredentialsFileSafety refuses credentials files exposed to other
-// UIDs on the host. Symlinks could redirect a refresh-token write; any
-// mode&0o077 bit lets another UID read or inject a token. Mode bits are
-// not meaningful on Windows, so that check is skipped there.
-func checkCredentialsFileSafety(path string) error {
-	info, err := os.Lstat(path)
+func readStoredCredentials(store config.CredentialStore, key string) (*credentialsTokenData, error) {
+	creds, err := store.ReadCredentials(key)
 	if err != nil {
-		return err
-	}
-	if info.Mode().Type()&fs.ModeSymlink != 0 {
-		return fmt.Errorf("credentials file %q is a symlink; refusing to read (set ANTHROPIC_CREDENTIALS_PATH to the real file)", path)
+		return nil, err
 	}
-	if runtime.GOOS == "windows" {
-		return nil
+	cred := &credentialsTokenData{
+		Type:         "oauth_token",
+		AccessToken:  creds.AccessToken,
+		RefreshToken: creds.RefreshToken,
 	}
-	mode := info.Mode().Perm()
-	if mode&0o077 != 0 {
-		return fmt.Errorf("credentials file %q has unsafe permissions %#o; refusing to read (must not be accessible by group or other; chmod 600 %s)", path, mode, path)
+	if creds.ExpiresAt != nil {
+		exp := creds.ExpiresAt.Unix()
+		cred.ExpiresAt = &exp
 	}
-	return nil
+	return cred, nil
 }
 
 // freshAccessToken returns a non-nil [AccessToken] only when the credentials
@@ -90,11 +74,15 @@ func ResolveCredentials(cfg *config.Config) (*CredentialsResult, error) {
 			Message: "config is missing authentication",
 		}
 	}
+	store, err := config.LookupCredentialStore(cfg.CredentialStore)
+	if err != nil {
+		return nil, &CredentialResolutionError{Message: "invalid credential_store", Err: err}
+	}
 	switch cfg.AuthenticationInfo.Type {
 	case config.AuthenticationTypeOIDCFederation:
-		return loadOIDCFederationProfile(cfg)
+		return loadOIDCFederationProfile(cfg, store)
 	case config.AuthenticationTypeUserOAuth:
-		return loadUserOAuthProfile(cfg)
+		return loadUserOAuthProfile(cfg, store)
 	default:
 		return nil, &CredentialResolutionError{
 			Message: fmt.Sprintf("unknown authentication.type %q", cfg.AuthenticationInfo.Type),
@@ -102,7 +90,7 @@ func ResolveCredentials(cfg *config.Config) (*CredentialsResult, error) {
 	}
 }
 
-func loadOIDCFederationProfile(cfg *config.Config) (*CredentialsResult, error) {
+func loadOIDCFederationProfile(cfg *config.Config, store config.CredentialStore) (*CredentialsResult, error) {
 	oidc := cfg.AuthenticationInfo.OIDCFederation
 	if oidc == nil {
 		return nil, &CredentialResolutionError{
@@ -118,17 +106,13 @@ func loadOIDCFederationProfile(cfg *config.Config) (*CredentialsResult, error) {
 	var identityProvider IdentityTokenProvider
 	switch {
 	case oidc.IdentityToken != nil:
-		if oidc.IdentityToken.Source != config.IdentityTokenSourceFile {
-			return nil, &CredentialResolutionError{
-				Message: fmt.Sprintf("oidc_federation identity_token.source %q is not supported (only %q)", oidc.IdentityToken.Source, config.IdentityTokenSourceFile),
-			}
-		}
-		if oidc.IdentityToken.Path == "" {
+		provider, err := identityProviderFromConfig(oidc.IdentityToken)
+		if err != nil {
 			return nil, &CredentialResolutionError{
-				Message: fmt.Sprintf("oidc_federation identity_token.source %q requires a non-empty path", config.IdentityTokenSourceFile),
+				Message: "oidc_federation " + err.Error(),
 			}
 		}
-		identityProvider = &IdentityTokenFile{Path: oidc.IdentityToken.Path}
+		identityProvider = provider
 	default:
 		identityProvider = identityProviderFromEnv()
 		if identityProvider == nil {
@@ -154,11 +138,11 @@ func loadOIDCFederationProfile(cfg *config.Config) (*CredentialsResult, error) {
 		// Try cached credentials file, unless the caller signaled a force-
 		// refresh (e.g. after a 401 invalidation in the auth middleware).
 		if !isForceRefresh(ctx) {
-			if cred, err := readCredentialsFile(credPath); err == nil {
+			if cred, err := readStoredCredentials(store, credPath); err == nil {
 				if token := cred.freshAccessToken(); token != nil {
 					return token, nil
 				}
-			} else if !os.IsNotExist(err) {
+			} else if !errors.Is(err, fs.ErrNotExist) {
 				warnOnce("workload-cache-read:"+credPath,
 					"failed to read workload-identity token cache %q: %v (continuing with fresh exchange)", credPath, err)
 			}
@@ -177,7 +161,7 @@ func loadOIDCFederationProfile(cfg *config.Config) (*CredentialsResult, error) {
 				AccessToken: token.Token,
 				ExpiresAt:   token.ExpiresAt,
 			}
-			if writeErr := config.WriteCredentials(credPath, cacheData); writeErr != nil {
+			if writeErr := store.WriteCredentials(credPath, cacheData); writeErr != nil {
 				warnOnce("workload-cache-write:"+credPath,
 					"failed to write workload-identity token cache %q: %v", credPath, writeErr)
 			}
@@ -196,7 +180,7 @@ func loadOIDCFederationProfile(cfg *config.Config) (*CredentialsResult, error) {
 	}, nil
 }
 
-func loadUserOAuthProfile(cfg *config.Config) (*CredentialsResult, error) {
+func loadUserOAuthProfile(cfg *config.Config, store config.CredentialStore) (*CredentialsResult, error) {
 	userOAuth := cfg.AuthenticationInfo.UserOAuth
 	if userOAuth == nil {
 		return nil, &CredentialResolutionError{
@@ -205,7 +189,7 @@ func loadUserOAuthProfile(cfg *config.Config) (*CredentialsResult, error) {
 	}
 
 	credPath := cfg.AuthenticationInfo.CredentialsPath
-	cred, err := readCredentialsFile(credPath)
+	cred, err := readStoredCredentials(store, credPath)
 	if err != nil {
 		return nil, &CredentialResolutionError{
 			Message: fmt.Sprintf("failed to read credentials file %q", credPath),
@@ -231,7 +215,7 @@ func loadUserOAuthProfile(cfg *config.Config) (*CredentialsResult, error) {
 		// Re-read on every call so externally-rotated tokens (e.g. from a
 		// credential daemon) are picked up.
 		staticProvider := func(_ context.Context, _ string, _ func(*http.Request) (*http.Response, error)) (*AccessToken, error) {
-			current, err := readCredentialsFile(credPath)
+			current, err := readStoredCredentials(store, credPath)
 			if err != nil {
 				return nil, fmt.Errorf("failed to read credentials file %q: %w", credPath, err)
 			}
@@ -265,7 +249,7 @@ func loadUserOAuthProfile(cfg *config.Config) (*CredentialsResult, error) {
 		// Re-read credentials file to check if token is still fresh. Skip
 		// the freshness shortcut when force-refresh is signaled (e.g. after
 		// a 401 in the auth middleware).
-		current, err := readCredentialsFile(credPath)
+		current, err := readStoredCredentials(store, credPath)
 		if err != nil {
 			return nil, fmt.Errorf("failed to read credentials file %q: %w", credPath, err)
 		}
@@ -349,7 +333,7 @@ func loadUserOAuthProfile(cfg *config.Config) (*CredentialsResult, error) {
 			token.ExpiresAt = &exp
 		}
 
-		if err := config.WriteCredentials(credPath, updated); err != nil {
+		if err := store.WriteCredentials(credPath, updated); err != nil {
 			return nil, fmt.Errorf("failed to write updated credentials: %w", err)
 		}
 		return token, nil
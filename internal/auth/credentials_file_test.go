@@ -618,3 +618,66 @@ func TestResolveCredentials_UserOAuthRefresh_SetsUserAgent(t *testing.T) {
 		t.Errorf("got User-Agent %q, want context user-oauth-refresh", receivedUA)
 	}
 }
+
+func TestResolveCredentials_UserOAuthEncryptedStore(t *testing.T) {
+	store := &config.EncryptedFileCredentialStore{
+		Passphrase: func() (string, error) { return "build-host-secret", nil },
+		Iterations: 1000,
+	}
+	config.RegisterCredentialStore("test-encrypted", store)
+	t.Cleanup(func() { config.RegisterCredentialStore("test-encrypted", nil) })
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body map[string]string
+		json.NewDecoder(r.Body).Decode(&body)
+		if body["refresh_token"] != "old-refresh" {
+			t.Errorf("refresh_token = %q, want the decrypted one", body["refresh_token"])
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"access_token":  "refreshed-tok",
+			"refresh_token": "new-refresh-tok",
+			"expires_in":    3600,
+		})
+	}))
+	defer server.Close()
+
+	credPath := filepath.Join(t.TempDir(), "creds.json")
+	expired := time.Now().Add(-time.Minute)
+	if err := store.WriteCredentials(credPath, config.Credentials{AccessToken: "old-tok", RefreshToken: "old-refresh", ExpiresAt: &expired}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &config.Config{
+		CredentialStore: "test-encrypted",
+		AuthenticationInfo: &config.AuthenticationInfo{
+			Type:            config.AuthenticationTypeUserOAuth,
+			CredentialsPath: credPath,
+			UserOAuth:       &config.UserOAuth{ClientID: "my-client"},
+		},
+	}
+	result, err := ResolveCredentials(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tok, err := result.Provider(context.Background(), server.URL, http.DefaultClient.Do)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tok.Token != "refreshed-tok" {
+		t.Fatalf("got %q, want %q", tok.Token, "refreshed-tok")
+	}
+
+	data, _ := os.ReadFile(credPath)
+	if strings.Contains(string(data), "new-refresh-tok") {
+		t.Fatal("refreshed credentials were written in plaintext")
+	}
+	updated, err := store.ReadCredentials(credPath)
+	if err != nil || updated.RefreshToken != "new-refresh-tok" {
+		t.Fatalf("got %+v, %v", updated, err)
+	}
+
+	cfg.CredentialStore = "vault"
+	if _, err := ResolveCredentials(cfg); err == nil {
+		t.Error("expected an unknown credential store to be rejected")
+	}
+}
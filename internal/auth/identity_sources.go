@@ -0,0 +1,364 @@
+package auth
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/config"
+)
+
+// identityTokenRefreshMargin is how long before its expiry a cached identity
+// token is fetched again, so the token exchange never presents one that
+// expires in flight.
+const identityTokenRefreshMargin = time.Minute
+
+const (
+	EnvGitHubActionsTokenRequestURL   = "ACTIONS_ID_TOKEN_REQUEST_URL"
+	EnvGitHubActionsTokenRequestToken = "ACTIONS_ID_TOKEN_REQUEST_TOKEN"
+	EnvGCEMetadataHost                = "GCE_METADATA_HOST"
+
+	defaultGCEMetadataHost = "metadata.google.internal"
+	defaultExecTimeout     = 30 * time.Second
+)
+
+// identityProviderFromConfig builds the [IdentityTokenProvider] described by
+// a profile's identity_token object.
+func identityProviderFromConfig(c *config.IdentityTokenConfig) (IdentityTokenProvider, error) {
+	switch c.Source {
+	case config.IdentityTokenSourceFile:
+		if c.Path == "" {
+			return nil, fmt.Errorf("identity_token.source %q requires a non-empty path", c.Source)
+		}
+		return &IdentityTokenFile{Path: c.Path}, nil
+	case config.IdentityTokenSourceEnv:
+		if c.EnvVar == "" {
+			return nil, fmt.Errorf("identity_token.source %q requires a non-empty env_var", c.Source)
+		}
+		return &IdentityTokenEnv{Name: c.EnvVar}, nil
+	case config.IdentityTokenSourceExec:
+		if len(c.Command) == 0 || c.Command[0] == "" {
+			return nil, fmt.Errorf("identity_token.source %q requires a non-empty command", c.Source)
+		}
+		return &IdentityTokenExec{Command: c.Command}, nil
+	case config.IdentityTokenSourceGitHubActions:
+		return &IdentityTokenGitHubActions{Audience: c.Audience}, nil
+	case config.IdentityTokenSourceGCPMetadata:
+		if c.Audience == "" {
+			return nil, fmt.Errorf("identity_token.source %q requires a non-empty audience", c.Source)
+		}
+		return &IdentityTokenGCPMetadata{Audience: c.Audience}, nil
+	case config.IdentityTokenSourceKubernetes:
+		return &IdentityTokenKubernetes{Path: c.Path}, nil
+	default:
+		return nil, fmt.Errorf("identity_token.source %q is not supported", c.Source)
+	}
+}
+
+// IdentityTokenEnv reads a JWT from an environment variable on each call.
+type IdentityTokenEnv struct {
+	Name string
+}
+
+func (p *IdentityTokenEnv) GetIdentityToken(_ context.Context) (string, error) {
+	if p.Name == "" {
+		return "", fmt.Errorf("identity token environment variable name is empty")
+	}
+	token := strings.TrimSpace(os.Getenv(p.Name))
+	if token == "" {
+		return "", fmt.Errorf("identity token environment variable %s is unset or empty", p.Name)
+	}
+	return token, nil
+}
+
+// IdentityTokenExec runs a credential helper that prints a JWT on stdout,
+// either bare or as a JSON object {"token": "...", "expires_at": <unix
+// seconds>}. The token is reused until shortly before it expires, taken
+// from expires_at or else the JWT's exp claim; a token with no known expiry
+// is fetched again on every call.
+type IdentityTokenExec struct {
+	Command []string
+	// Timeout bounds each run of the helper. Defaults to 30s.
+	Timeout time.Duration
+
+	cache identityTokenCache
+}
+
+func (p *IdentityTokenExec) GetIdentityToken(ctx context.Context) (string, error) {
+	return p.cache.get(ctx, p.run)
+}
+
+func (p *IdentityTokenExec) run(ctx context.Context) (string, time.Time, error) {
+	if len(p.Command) == 0 || p.Command[0] == "" {
+		return "", time.Time{}, fmt.Errorf("identity token command is empty")
+	}
+	timeout := p.Timeout
+	if timeout == 0 {
+		timeout = defaultExecTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, p.Command[0], p.Command[1:]...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		msg := strings.TrimSpace(stderr.String())
+		if len(msg) > 512 {
+			msg = msg[:512] + "...[truncated]"
+		}
+		if msg != "" {
+			return "", time.Time{}, fmt.Errorf("identity token command %q failed: %w: %s", p.Command[0], err, msg)
+		}
+		return "", time.Time{}, fmt.Errorf("identity token command %q failed: %w", p.Command[0], err)
+	}
+
+	out := bytes.TrimSpace(stdout.Bytes())
+	if len(out) > 0 && out[0] == '{' {
+		var parsed struct {
+			Token     string `json:"token"`
+			ExpiresAt int64  `json:"expires_at"`
+		}
+		if err := json.Unmarshal(out, &parsed); err != nil {
+			return "", time.Time{}, fmt.Errorf("identity token command %q: parse output: %w", p.Command[0], err)
+		}
+		if parsed.Token == "" {
+			return "", time.Time{}, fmt.Errorf("identity token command %q: output missing token", p.Command[0])
+		}
+		if parsed.ExpiresAt > 0 {
+			return parsed.Token, time.Unix(parsed.ExpiresAt, 0), nil
+		}
+		return parsed.Token, jwtExpiry(parsed.Token), nil
+	}
+	if len(out) == 0 {
+		return "", time.Time{}, fmt.Errorf("identity token command %q printed no token", p.Command[0])
+	}
+	token := string(out)
+	return token, jwtExpiry(token), nil
+}
+
+// IdentityTokenGitHubActions requests a JWT from the GitHub Actions OIDC
+// provider. RequestURL and RequestToken default to the
+// ACTIONS_ID_TOKEN_REQUEST_URL and ACTIONS_ID_TOKEN_REQUEST_TOKEN variables
+// GitHub sets for jobs with the id-token: write permission.
+type IdentityTokenGitHubActions struct {
+	Audience     string
+	RequestURL   string
+	RequestToken string
+	HTTPClient   *http.Client
+
+	cache identityTokenCache
+}
+
+func (p *IdentityTokenGitHubActions) GetIdentityToken(ctx context.Context) (string, error) {
+	return p.cache.get(ctx, p.fetch)
+}
+
+func (p *IdentityTokenGitHubActions) fetch(ctx context.Context) (string, time.Time, error) {
+	requestURL, requestToken := p.RequestURL, p.RequestToken
+	if requestURL == "" {
+		requestURL = os.Getenv(EnvGitHubActionsTokenRequestURL)
+	}
+	if requestToken == "" {
+		requestToken = os.Getenv(EnvGitHubActionsTokenRequestToken)
+	}
+	if requestURL == "" || requestToken == "" {
+		return "", time.Time{}, fmt.Errorf("GitHub Actions OIDC is unavailable: %s and %s must be set (does the job have the id-token: write permission?)", EnvGitHubActionsTokenRequestURL, EnvGitHubActionsTokenRequestToken)
+	}
+	u, err := url.Parse(requestURL)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("invalid %s: %w", EnvGitHubActionsTokenRequestURL, err)
+	}
+	if p.Audience != "" {
+		q := u.Query()
+		q.Set("audience", p.Audience)
+		u.RawQuery = q.Encode()
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Authorization", "Bearer "+requestToken)
+	req.Header.Set("Accept", "application/json")
+
+	body, err := fetchIdentityToken(p.HTTPClient, req, "GitHub Actions OIDC")
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	var parsed struct {
+		Value string `json:"value"`
+	}
+	if err := json.Unmarshal(body, &parsed); err != nil {
+		return "", time.Time{}, fmt.Errorf("GitHub Actions OIDC: parse response: %w", err)
+	}
+	if parsed.Value == "" {
+		return "", time.Time{}, fmt.Errorf("GitHub Actions OIDC: response missing value")
+	}
+	return parsed.Value, jwtExpiry(parsed.Value), nil
+}
+
+// IdentityTokenGCPMetadata requests an identity token for the instance's
+// default service account from the GCP metadata server. Host defaults to
+// GCE_METADATA_HOST, then metadata.google.internal.
+type IdentityTokenGCPMetadata struct {
+	Audience   string
+	Host       string
+	HTTPClient *http.Client
+
+	cache identityTokenCache
+}
+
+func (p *IdentityTokenGCPMetadata) GetIdentityToken(ctx context.Context) (string, error) {
+	return p.cache.get(ctx, p.fetch)
+}
+
+func (p *IdentityTokenGCPMetadata) fetch(ctx context.Context) (string, time.Time, error) {
+	if p.Audience == "" {
+		return "", time.Time{}, fmt.Errorf("GCP metadata identity token requires an audience")
+	}
+	host := p.Host
+	if host == "" {
+		host = os.Getenv(EnvGCEMetadataHost)
+	}
+	if host == "" {
+		host = defaultGCEMetadataHost
+	}
+	u := url.URL{
+		Scheme:   "http",
+		Host:     host,
+		Path:     "/computeMetadata/v1/instance/service-accounts/default/identity",
+		RawQuery: url.Values{"audience": {p.Audience}, "format": {"full"}}.Encode(),
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	req.Header.Set("Metadata-Flavor", "Google")
+
+	body, err := fetchIdentityToken(p.HTTPClient, req, "GCP metadata")
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	token := strings.TrimSpace(string(body))
+	if token == "" {
+		return "", time.Time{}, fmt.Errorf("GCP metadata: empty identity token")
+	}
+	return token, jwtExpiry(token), nil
+}
+
+// IdentityTokenKubernetes reads a Kubernetes projected service account
+// token. The kubelet rotates the file in place, so it is re-read whenever it
+// changes on disk or the token it held nears expiry. Path defaults to
+// [config.DefaultKubernetesTokenPath].
+type IdentityTokenKubernetes struct {
+	Path string
+
+	mu      sync.Mutex
+	token   string
+	modTime time.Time
+}
+
+func (p *IdentityTokenKubernetes) GetIdentityToken(ctx context.Context) (string, error) {
+	path := p.Path
+	if path == "" {
+		path = config.DefaultKubernetesTokenPath
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to read Kubernetes service account token %q: %w", path, err)
+	}
+	if p.token != "" && info.ModTime().Equal(p.modTime) && !expiresSoon(jwtExpiry(p.token)) {
+		return p.token, nil
+	}
+	token, err := (&IdentityTokenFile{Path: path}).GetIdentityToken(ctx)
+	if err != nil {
+		return "", err
+	}
+	p.token, p.modTime = token, info.ModTime()
+	return token, nil
+}
+
+// identityTokenCache holds a fetched identity token until shortly before it
+// expires. Tokens with no known expiry are not cached.
+type identityTokenCache struct {
+	mu        sync.Mutex
+	token     string
+	expiresAt time.Time
+}
+
+func (c *identityTokenCache) get(ctx context.Context, fetch func(context.Context) (string, time.Time, error)) (string, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.token != "" && !c.expiresAt.IsZero() && !expiresSoon(c.expiresAt) {
+		return c.token, nil
+	}
+	token, expiresAt, err := fetch(ctx)
+	if err != nil {
+		return "", err
+	}
+	c.token, c.expiresAt = token, expiresAt
+	return token, nil
+}
+
+func expiresSoon(expiresAt time.Time) bool {
+	return !expiresAt.IsZero() && time.Until(expiresAt) < identityTokenRefreshMargin
+}
+
+// fetchIdentityToken sends req and returns the body of a 200 response.
+func fetchIdentityToken(client *http.Client, req *http.Request, source string) ([]byte, error) {
+	if client == nil {
+		client = &http.Client{Timeout: 10 * time.Second}
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s: request failed: %w", source, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("%s: read response: %w", source, err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%s: status %d: %s", source, resp.StatusCode, config.RedactOAuthErrorBody(string(body)))
+	}
+	return body, nil
+}
+
+// jwtExpiry returns the time of a JWT's exp claim, or the zero time if the
+// token is not a JWT or has none. The signature is not checked: the expiry
+// only decides when to fetch a new token.
+func jwtExpiry(token string) time.Time {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return time.Time{}
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return time.Time{}
+	}
+	var claims struct {
+		Exp json.Number `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return time.Time{}
+	}
+	exp, err := claims.Exp.Float64()
+	if err != nil || exp <= 0 {
+		return time.Time{}
+	}
+	return time.Unix(int64(exp), 0)
+}
@@ -0,0 +1,249 @@
+package auth
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/config"
+)
+
+// fakeJWT returns an unsigned JWT with the given subject and expiry.
+func fakeJWT(sub string, exp time.Time) string {
+	enc := base64.RawURLEncoding
+	claims, _ := json.Marshal(map[string]any{"sub": sub, "exp": exp.Unix()})
+	return enc.EncodeToString([]byte(`{"alg":"none"}`)) + "." + enc.EncodeToString(claims) + ".sig"
+}
+
+func TestJWTExpiry(t *testing.T) {
+	exp := time.Unix(1900000000, 0)
+	if got := jwtExpiry(fakeJWT("a", exp)); !got.Equal(exp) {
+		t.Errorf("got %v, want %v", got, exp)
+	}
+	for _, token := range []string{"", "opaque-token", "a.!!!.c", "a." + base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"x"}`)) + ".c"} {
+		if got := jwtExpiry(token); !got.IsZero() {
+			t.Errorf("jwtExpiry(%q) = %v, want zero", token, got)
+		}
+	}
+}
+
+func TestIdentityTokenEnv(t *testing.T) {
+	t.Setenv("TEST_OIDC_TOKEN", " env-jwt\n")
+	p := &IdentityTokenEnv{Name: "TEST_OIDC_TOKEN"}
+	if got, err := p.GetIdentityToken(context.Background()); err != nil || got != "env-jwt" {
+		t.Fatalf("got %q, %v", got, err)
+	}
+	t.Setenv("TEST_OIDC_TOKEN", "")
+	if _, err := p.GetIdentityToken(context.Background()); err == nil {
+		t.Fatal("expected an empty variable to fail")
+	}
+}
+
+func TestIdentityTokenExec(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("uses a shell script as the credential helper")
+	}
+	dir := t.TempDir()
+	counter := filepath.Join(dir, "runs")
+	helper := func(name, output string) string {
+		path := filepath.Join(dir, name)
+		script := fmt.Sprintf("#!/bin/sh\necho run >> %s\ncat <<'EOF'\n%s\nEOF\n", counter, output)
+		if err := os.WriteFile(path, []byte(script), 0o700); err != nil {
+			t.Fatal(err)
+		}
+		return path
+	}
+	runs := func() int {
+		data, _ := os.ReadFile(counter)
+		return strings.Count(string(data), "run")
+	}
+
+	t.Run("JSON with expiry is cached", func(t *testing.T) {
+		os.Remove(counter)
+		out, _ := json.Marshal(map[string]any{"token": "exec-jwt", "expires_at": time.Now().Add(time.Hour).Unix()})
+		p := &IdentityTokenExec{Command: []string{helper("json.sh", string(out))}}
+		for range 2 {
+			if got, err := p.GetIdentityToken(context.Background()); err != nil || got != "exec-jwt" {
+				t.Fatalf("got %q, %v", got, err)
+			}
+		}
+		if runs() != 1 {
+			t.Errorf("expected the helper to run once, ran %d times", runs())
+		}
+	})
+
+	t.Run("bare JWT near expiry is refetched", func(t *testing.T) {
+		os.Remove(counter)
+		token := fakeJWT("svc", time.Now().Add(30*time.Second))
+		p := &IdentityTokenExec{Command: []string{helper("bare.sh", token)}}
+		for range 2 {
+			if got, err := p.GetIdentityToken(context.Background()); err != nil || got != token {
+				t.Fatalf("got %q, %v", got, err)
+			}
+		}
+		if runs() != 2 {
+			t.Errorf("expected the helper to run on each call, ran %d times", runs())
+		}
+	})
+
+	t.Run("failure includes stderr", func(t *testing.T) {
+		path := filepath.Join(dir, "fail.sh")
+		os.WriteFile(path, []byte("#!/bin/sh\necho 'not logged in' >&2\nexit 3\n"), 0o700)
+		_, err := (&IdentityTokenExec{Command: []string{path}}).GetIdentityToken(context.Background())
+		if err == nil || !strings.Contains(err.Error(), "not logged in") {
+			t.Errorf("expected the helper's stderr in the error, got %v", err)
+		}
+	})
+}
+
+func TestIdentityTokenGitHubActions(t *testing.T) {
+	token := fakeJWT("repo:org/repo:ref:refs/heads/main", time.Now().Add(5*time.Minute))
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if r.Header.Get("Authorization") != "Bearer request-token" {
+			t.Errorf("Authorization: %q", r.Header.Get("Authorization"))
+		}
+		if r.URL.Query().Get("api-version") != "2.0" || r.URL.Query().Get("audience") != "https://api.anthropic.com" {
+			t.Errorf("query: %q", r.URL.RawQuery)
+		}
+		json.NewEncoder(w).Encode(map[string]string{"value": token})
+	}))
+	defer server.Close()
+
+	t.Setenv(EnvGitHubActionsTokenRequestURL, server.URL+"/token?api-version=2.0")
+	t.Setenv(EnvGitHubActionsTokenRequestToken, "request-token")
+	p := &IdentityTokenGitHubActions{Audience: "https://api.anthropic.com"}
+	for range 2 {
+		if got, err := p.GetIdentityToken(context.Background()); err != nil || got != token {
+			t.Fatalf("got %q, %v", got, err)
+		}
+	}
+	if requests.Load() != 1 {
+		t.Errorf("expected the token to be cached, got %d requests", requests.Load())
+	}
+
+	t.Setenv(EnvGitHubActionsTokenRequestURL, "")
+	if _, err := (&IdentityTokenGitHubActions{}).GetIdentityToken(context.Background()); err == nil || !strings.Contains(err.Error(), "id-token: write") {
+		t.Errorf("expected a hint about the job permission, got %v", err)
+	}
+}
+
+func TestIdentityTokenGCPMetadata(t *testing.T) {
+	token := fakeJWT("1234567890", time.Now().Add(time.Hour))
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Metadata-Flavor") != "Google" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+		if r.URL.Path != "/computeMetadata/v1/instance/service-accounts/default/identity" || r.URL.Query().Get("audience") != "anthropic" {
+			t.Errorf("unexpected request %s", r.URL)
+		}
+		w.Write([]byte(token))
+	}))
+	defer server.Close()
+
+	t.Setenv(EnvGCEMetadataHost, strings.TrimPrefix(server.URL, "http://"))
+	got, err := (&IdentityTokenGCPMetadata{Audience: "anthropic"}).GetIdentityToken(context.Background())
+	if err != nil || got != token {
+		t.Fatalf("got %q, %v", got, err)
+	}
+}
+
+func TestIdentityTokenKubernetes(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "token")
+	first := fakeJWT("system:serviceaccount:ns:sa", time.Now().Add(time.Hour))
+	os.WriteFile(path, []byte(first), 0o600)
+
+	p := &IdentityTokenKubernetes{Path: path}
+	if got, err := p.GetIdentityToken(context.Background()); err != nil || got != first {
+		t.Fatalf("got %q, %v", got, err)
+	}
+
+	// The kubelet rotates the token by replacing the file.
+	second := fakeJWT("system:serviceaccount:ns:sa", time.Now().Add(2*time.Hour))
+	os.WriteFile(path, []byte(second), 0o600)
+	later := time.Now().Add(time.Minute)
+	os.Chtimes(path, later, later)
+	if got, err := p.GetIdentityToken(context.Background()); err != nil || got != second {
+		t.Fatalf("expected the rotated token, got %q, %v", got, err)
+	}
+}
+
+func TestResolveCredentials_OIDCFederationIdentitySources(t *testing.T) {
+	t.Setenv("PROFILE_OIDC_TOKEN", "env-jwt")
+	var assertion string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var body tokenExchangeRequest
+		json.NewDecoder(r.Body).Decode(&body)
+		assertion = body.Assertion
+		json.NewEncoder(w).Encode(tokenExchangeResponse{AccessToken: "exchanged"})
+	}))
+	defer server.Close()
+
+	resolve := func(identity *config.IdentityTokenConfig) (*CredentialsResult, error) {
+		return ResolveCredentials(&config.Config{
+			OrganizationID: "org-1",
+			AuthenticationInfo: &config.AuthenticationInfo{
+				Type:            config.AuthenticationTypeOIDCFederation,
+				CredentialsPath: filepath.Join(t.TempDir(), "creds.json"),
+				OIDCFederation:  &config.OIDCFederation{FederationRuleID: "fdrl_1", IdentityToken: identity},
+			},
+		})
+	}
+
+	result, err := resolve(&config.IdentityTokenConfig{Source: config.IdentityTokenSourceEnv, EnvVar: "PROFILE_OIDC_TOKEN"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := result.Provider(context.Background(), server.URL, http.DefaultClient.Do); err != nil {
+		t.Fatal(err)
+	}
+	if assertion != "env-jwt" {
+		t.Errorf("got assertion %q, want the env token", assertion)
+	}
+
+	for _, identity := range []*config.IdentityTokenConfig{
+		{Source: config.IdentityTokenSourceEnv},
+		{Source: config.IdentityTokenSourceExec},
+		{Source: config.IdentityTokenSourceGCPMetadata},
+		{Source: "vault"},
+	} {
+		if _, err := resolve(identity); err == nil {
+			t.Errorf("expected %+v to be rejected", identity)
+		}
+	}
+}
+
+func TestIdentityTokenConfigJSON(t *testing.T) {
+	var cfg config.Config
+	err := json.Unmarshal([]byte(`{
+		"organization_id": "org-1",
+		"authentication": {
+			"type": "oidc_federation",
+			"federation_rule_id": "fdrl_1",
+			"identity_token": {"source": "exec", "command": ["/usr/local/bin/get-token", "--audience", "anthropic"]}
+		}
+	}`), &cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	provider, err := identityProviderFromConfig(cfg.AuthenticationInfo.OIDCFederation.IdentityToken)
+	if err != nil {
+		t.Fatal(err)
+	}
+	exec, ok := provider.(*IdentityTokenExec)
+	if !ok || strings.Join(exec.Command, " ") != "/usr/local/bin/get-token --audience anthropic" {
+		t.Errorf("unexpected provider %#v", provider)
+	}
+}
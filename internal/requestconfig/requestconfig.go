@@ -22,6 +22,7 @@ import (
 	"github.com/anthropics/anthropic-sdk-go/internal/apierror"
 	"github.com/anthropics/anthropic-sdk-go/internal/apiform"
 	"github.com/anthropics/anthropic-sdk-go/internal/apiquery"
+	"github.com/anthropics/anthropic-sdk-go/packages/ratelimit"
 )
 
 func getDefaultHeaders() map[string]string {
@@ -229,6 +230,25 @@ type RequestConfig struct {
 	// given address
 	ResponseInto **http.Response
 	Body         io.Reader
+	// StreamReconnects is the number of times a text/event-stream response
+	// may be resumed with a Last-Event-ID request after its connection drops.
+	// Zero leaves reconnection to RetryPolicy, if set, and otherwise disables
+	// it.
+	StreamReconnects int
+	// RetryPolicy, if set, decides whether and when failed requests are
+	// retried and interrupted streams are resumed, in place of MaxRetries and
+	// the default backoff.
+	RetryPolicy RetryPolicy
+
+	// stream is shared by every connection of one resumable stream so that
+	// reconnection limits apply to the stream as a whole.
+	stream *streamResume
+}
+
+// streamResume tracks the reconnections of one resumable stream.
+type streamResume struct {
+	started  time.Time
+	attempts int
 }
 
 // middleware is exactly the same type as the Middleware type found in the [option] package,
@@ -245,6 +265,13 @@ func applyMiddleware(middleware middleware, next middlewareNext) middlewareNext
 	}
 }
 
+// attemptFailed reports whether an attempt is one a retry policy is asked to
+// judge: a transport error, an error status, or a response that explicitly
+// asks to be retried.
+func attemptFailed(res *http.Response, err error) bool {
+	return err != nil || res == nil || res.StatusCode >= http.StatusBadRequest || res.Header.Get("x-should-retry") == "true"
+}
+
 func shouldRetry(req *http.Request, res *http.Response) bool {
 	// If there is no way to recover the Body, then we shouldn't retry.
 	if req.Body != nil && req.GetBody == nil {
@@ -369,6 +396,18 @@ func retryDelay(res *http.Response, retryCount int) time.Duration {
 	}
 
 	maxDelay := 8 * time.Second
+
+	// A 429 without a Retry-After hint: wait until the exhausted limit resets,
+	// but no longer than the backoff would. Longer waits are left to an
+	// opted-in rate limiter.
+	if res != nil && res.StatusCode == http.StatusTooManyRequests {
+		if snapshot, ok := ratelimit.FromHeader(res.Header); ok {
+			if reset, ok := snapshot.ExhaustedUntil(); ok {
+				return min(maxDelay, max(0, time.Until(reset)))
+			}
+		}
+	}
+
 	delay := time.Duration(0.5 * float64(time.Second) * math.Pow(2, float64(retryCount)))
 	if delay > maxDelay {
 		delay = maxDelay
@@ -379,6 +418,67 @@ func retryDelay(res *http.Response, retryCount int) time.Duration {
 	return delay
 }
 
+func isEventStream(res *http.Response) bool {
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("content-type"))
+	return mediaType == "text/event-stream"
+}
+
+// reconnectableBody is the body of a text/event-stream response that can be
+// resumed by re-issuing its request. It implements the ssestream.Reconnector
+// interface.
+type reconnectableBody struct {
+	io.ReadCloser
+	cfg *RequestConfig
+}
+
+func (b *reconnectableBody) Reconnect(lastEventID string) (*http.Response, error) {
+	stream := b.cfg.stream
+	if b.cfg.StreamReconnects > 0 {
+		if stream.attempts >= b.cfg.StreamReconnects {
+			return nil, fmt.Errorf("requestconfig: stream reconnect limit of %d reached", b.cfg.StreamReconnects)
+		}
+	} else {
+		delay, retry := b.cfg.RetryPolicy.Retry(RetryAttempt{
+			Request:     b.cfg.Request,
+			Err:         io.ErrUnexpectedEOF,
+			Attempt:     stream.attempts,
+			Elapsed:     time.Since(stream.started),
+			LastEventID: lastEventID,
+		})
+		if !retry {
+			return nil, fmt.Errorf("requestconfig: retry policy declined to resume stream")
+		}
+		select {
+		case <-b.cfg.Request.Context().Done():
+			return nil, b.cfg.Request.Context().Err()
+		case <-time.After(delay):
+		}
+	}
+	stream.attempts += 1
+
+	req := b.cfg.Request.Clone(b.cfg.Request.Context())
+	if req.GetBody != nil {
+		var err error
+		req.Body, err = req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+	} else if req.Body != nil && req.Body != http.NoBody {
+		return nil, fmt.Errorf("requestconfig: cannot resume stream, request body cannot be replayed")
+	}
+	req.Header.Set("Last-Event-ID", lastEventID)
+
+	var res *http.Response
+	cfg := *b.cfg
+	cfg.Request = req
+	cfg.ResponseBodyInto = &res
+	cfg.ResponseInto = nil
+	if err := cfg.Execute(); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
 func (cfg *RequestConfig) Execute() (err error) {
 	if cfg.BaseURL == nil {
 		if cfg.DefaultBaseURL != nil {
@@ -427,9 +527,15 @@ func (cfg *RequestConfig) Execute() (err error) {
 	// Don't send the current retry count in the headers if the caller modified the header defaults.
 	shouldSendRetryCount := cfg.Request.Header.Get("X-Stainless-Retry-Count") == "0"
 
+	policy := cfg.RetryPolicy
+	if policy == nil {
+		policy = DefaultRetryPolicy{MaxRetries: cfg.MaxRetries}
+	}
+
 	var res *http.Response
 	var cancel context.CancelFunc
-	for retryCount := 0; retryCount <= cfg.MaxRetries; retryCount += 1 {
+	started := time.Now()
+	for retryCount := 0; ; retryCount += 1 {
 		ctx := cfg.Request.Context()
 		if cfg.RequestTimeout != time.Duration(0) && isBeforeContextDeadline(time.Now().Add(cfg.RequestTimeout), ctx) {
 			ctx, cancel = context.WithTimeout(ctx, cfg.RequestTimeout)
@@ -450,7 +556,17 @@ func (cfg *RequestConfig) Execute() (err error) {
 		if ctx != nil && ctx.Err() != nil {
 			return ctx.Err()
 		}
-		if !shouldRetry(cfg.Request, res) || retryCount >= cfg.MaxRetries {
+		if !attemptFailed(res, err) {
+			break
+		}
+		delay, retry := policy.Retry(RetryAttempt{
+			Request:  req,
+			Response: res,
+			Err:      err,
+			Attempt:  retryCount,
+			Elapsed:  time.Since(started),
+		})
+		if !retry {
 			break
 		}
 
@@ -475,7 +591,7 @@ func (cfg *RequestConfig) Execute() (err error) {
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
-		case <-time.After(retryDelay(res, retryCount)):
+		case <-time.After(delay):
 		}
 	}
 
@@ -524,6 +640,12 @@ func (cfg *RequestConfig) Execute() (err error) {
 			res.Body = &bodyWithTimeout{rc: res.Body, stop: cancel}
 			cancel = nil
 		}
+		if (cfg.StreamReconnects > 0 || cfg.RetryPolicy != nil) && isEventStream(res) {
+			if cfg.stream == nil {
+				cfg.stream = &streamResume{started: started}
+			}
+			res.Body = &reconnectableBody{ReadCloser: res.Body, cfg: cfg}
+		}
 		return nil
 	}
 
@@ -597,6 +719,9 @@ func (cfg *RequestConfig) Clone(ctx context.Context) *RequestConfig {
 		APIKey:         cfg.APIKey,
 		AuthToken:      cfg.AuthToken,
 		WebhookKey:     cfg.WebhookKey,
+
+		StreamReconnects: cfg.StreamReconnects,
+		RetryPolicy:      cfg.RetryPolicy,
 	}
 
 	return new
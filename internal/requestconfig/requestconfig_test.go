@@ -6,6 +6,7 @@ import (
 	"net/http/httptest"
 	"net/url"
 	"testing"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go/internal/apierror"
 	"github.com/anthropics/anthropic-sdk-go/shared"
@@ -112,3 +113,13 @@ func TestErrorTypeAbsent(t *testing.T) {
 	assert.Equal(t, shared.ErrorType(""), apiErr.Type())
 	assert.Equal(t, 500, apiErr.StatusCode)
 }
+
+func TestRetryDelayCapsRateLimitReset(t *testing.T) {
+	reset := time.Now().Add(10 * time.Minute).UTC().Format(time.RFC3339)
+	res := &http.Response{StatusCode: http.StatusTooManyRequests, Header: http.Header{}}
+	res.Header.Set("anthropic-ratelimit-requests-limit", "50")
+	res.Header.Set("anthropic-ratelimit-requests-remaining", "0")
+	res.Header.Set("anthropic-ratelimit-requests-reset", reset)
+
+	assert.Equal(t, 8*time.Second, retryDelay(res, 0), "a far-off reset is capped at the maximum backoff")
+}
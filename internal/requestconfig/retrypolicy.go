@@ -0,0 +1,117 @@
+package requestconfig
+
+import (
+	"math"
+	"math/rand"
+	"net/http"
+	"time"
+)
+
+// RetryAttempt describes a failed attempt for a [RetryPolicy] to judge.
+type RetryAttempt struct {
+	// Request is the request that was sent.
+	Request *http.Request
+	// Response is the response received, or nil if the request failed
+	// without one. Its body must not be read.
+	Response *http.Response
+	// Err is the error returned by the HTTP client, if any.
+	Err error
+	// Attempt is the number of retries made so far, starting at 0 for the
+	// first failure.
+	Attempt int
+	// Elapsed is the time since the first attempt was sent.
+	Elapsed time.Duration
+	// LastEventID is set when the attempt would resume an event stream whose
+	// connection dropped, to the ID of the last event received. Response is
+	// then nil and Err is io.ErrUnexpectedEOF.
+	LastEventID string
+}
+
+// RetryPolicy decides whether a failed attempt is retried and how long to
+// wait first.
+type RetryPolicy interface {
+	Retry(attempt RetryAttempt) (delay time.Duration, retry bool)
+}
+
+// RetryPolicyFunc adapts a function to a [RetryPolicy].
+type RetryPolicyFunc func(attempt RetryAttempt) (time.Duration, bool)
+
+func (f RetryPolicyFunc) Retry(attempt RetryAttempt) (time.Duration, bool) { return f(attempt) }
+
+// DefaultRetryPolicy is the policy used when none is set: it retries
+// connection errors, 408, 409, 429 and 5xx responses (unless the server sends
+// x-should-retry: false) up to MaxRetries times, with exponential backoff from
+// 0.5s capped at 8s unless the response asks for a specific delay.
+type DefaultRetryPolicy struct {
+	MaxRetries int
+}
+
+func (p DefaultRetryPolicy) Retry(a RetryAttempt) (time.Duration, bool) {
+	if a.Attempt >= p.MaxRetries || !shouldRetry(a.Request, a.Response) {
+		return 0, false
+	}
+	return retryDelay(a.Response, a.Attempt), true
+}
+
+// RetryBudgetPolicy retries as Policy does, but only while the retry, after
+// its delay, would start within Budget of the first attempt.
+type RetryBudgetPolicy struct {
+	Budget time.Duration
+	// Policy decides which attempts are retried. If nil, the conditions and
+	// backoff of [DefaultRetryPolicy] are used with no limit on the number
+	// of retries.
+	Policy RetryPolicy
+}
+
+func (p RetryBudgetPolicy) Retry(a RetryAttempt) (time.Duration, bool) {
+	policy := p.Policy
+	if policy == nil {
+		policy = DefaultRetryPolicy{MaxRetries: math.MaxInt}
+	}
+	delay, retry := policy.Retry(a)
+	if !retry || a.Elapsed+delay >= p.Budget {
+		return 0, false
+	}
+	return delay, true
+}
+
+// StatusOverloaded is the status code of an overloaded_error response.
+const StatusOverloaded = 529
+
+// OverloadedRetryPolicy handles 529 overloaded responses separately from other
+// failures, which are left to Policy. Overloads usually last longer than
+// other transient errors, so they warrant more retries and longer waits.
+type OverloadedRetryPolicy struct {
+	// MaxRetries bounds the retries of overloaded responses.
+	MaxRetries int
+	// InitialDelay is the wait before the first retry, doubled for each
+	// later one up to MaxDelay. A Retry-After header takes precedence.
+	InitialDelay time.Duration
+	MaxDelay     time.Duration
+	// Policy handles every other failure. If nil, they are not retried.
+	Policy RetryPolicy
+}
+
+func (p OverloadedRetryPolicy) Retry(a RetryAttempt) (time.Duration, bool) {
+	if a.Response == nil || a.Response.StatusCode != StatusOverloaded {
+		if p.Policy == nil {
+			return 0, false
+		}
+		return p.Policy.Retry(a)
+	}
+	if a.Attempt >= p.MaxRetries || !shouldRetry(a.Request, a.Response) {
+		return 0, false
+	}
+	if delay, ok := parseRetryAfterHeader(a.Response); ok {
+		return max(0, delay), true
+	}
+
+	delay := time.Duration(float64(p.InitialDelay) * math.Pow(2, float64(a.Attempt)))
+	if p.MaxDelay > 0 && (delay > p.MaxDelay || delay < 0) {
+		delay = p.MaxDelay
+	}
+	if delay/4 > 0 {
+		delay -= time.Duration(rand.Int63n(int64(delay / 4)))
+	}
+	return delay, true
+}
@@ -30,6 +30,7 @@ const (
 	EnvironmentsWorker        Value = "environments-worker"
 	FallbackRefusalMiddleware Value = "fallback-refusal-middleware"
 	SessionToolRunner         Value = "session-tool-runner"
+	ToolRunner                Value = "tool-runner"
 )
 
 // With returns a request option (assignable to [option.RequestOption]) that
@@ -0,0 +1,337 @@
+// Package anthropictest provides an in-process fake of the Anthropic API for
+// testing code that uses the SDK without network access or a mock server.
+//
+// A [Server] answers Messages requests, streaming or not, with replies the
+// test scripts in advance, and serves token counting, Message Batches, Files
+// and Models from memory. It records every request it receives so the test
+// can assert on them.
+//
+//	server := anthropictest.NewServer(t)
+//	server.Reply(
+//		anthropictest.ToolUse("get_weather", map[string]any{"city": "Paris"}),
+//		anthropictest.Text("It is sunny in Paris."),
+//	)
+//	client := anthropic.NewClient(server.Options()...)
+//	// ... run the code under test with client ...
+//	if got := len(server.Requests()); got != 2 { ... }
+//
+// Errors, such as [Overloaded], and dropped connections, with
+// [Reply.DisconnectAfter], are scripted the same way. [Server.Fail] injects
+// them into requests to any endpoint.
+package anthropictest
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// Request is a request received by a [Server].
+type Request struct {
+	Method string
+	// Path is the URL path, such as /v1/messages.
+	Path   string
+	Query  url.Values
+	Header http.Header
+	Body   []byte
+}
+
+// Unmarshal decodes the request's JSON body into v, such as an
+// [anthropic.MessageNewParams].
+//
+// [anthropic.MessageNewParams]: https://pkg.go.dev/github.com/anthropics/anthropic-sdk-go#MessageNewParams
+func (r Request) Unmarshal(v any) error {
+	return json.Unmarshal(r.Body, v)
+}
+
+// Server is a fake Anthropic API. Create one with [NewServer]; it is closed
+// when the test ends. Its methods are safe for concurrent use.
+type Server struct {
+	*httptest.Server
+	tb testing.TB
+
+	mu        sync.Mutex
+	requests  []Request
+	replies   []Reply
+	responder func(Request) Reply
+	failures  []Reply
+	countFunc func(Request) int64
+	models    []Model
+	batches   map[string]*batch
+	batchIDs  []string
+	files     map[string]*file
+	fileIDs   []string
+	nextID    int
+}
+
+// NewServer starts a Server, which is closed when tb's test ends.
+func NewServer(tb testing.TB) *Server {
+	s := &Server{
+		tb:      tb,
+		models:  DefaultModels(),
+		batches: map[string]*batch{},
+		files:   map[string]*file{},
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /v1/messages", s.handleMessages)
+	mux.HandleFunc("POST /v1/messages/count_tokens", s.handleCountTokens)
+	mux.HandleFunc("POST /v1/messages/batches", s.handleCreateBatch)
+	mux.HandleFunc("GET /v1/messages/batches", s.handleListBatches)
+	mux.HandleFunc("GET /v1/messages/batches/{id}", s.handleGetBatch)
+	mux.HandleFunc("DELETE /v1/messages/batches/{id}", s.handleDeleteBatch)
+	mux.HandleFunc("GET /v1/messages/batches/{id}/results", s.handleBatchResults)
+	mux.HandleFunc("POST /v1/messages/batches/{id}/cancel", s.handleCancelBatch)
+	mux.HandleFunc("POST /v1/files", s.handleUploadFile)
+	mux.HandleFunc("GET /v1/files", s.handleListFiles)
+	mux.HandleFunc("GET /v1/files/{id}", s.handleGetFile)
+	mux.HandleFunc("DELETE /v1/files/{id}", s.handleDeleteFile)
+	mux.HandleFunc("GET /v1/files/{id}/content", s.handleFileContent)
+	mux.HandleFunc("GET /v1/models", s.handleListModels)
+	mux.HandleFunc("GET /v1/models/{id}", s.handleGetModel)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "not_found_error", fmt.Sprintf("anthropictest: %s %s is not implemented", r.Method, r.URL.Path))
+	})
+	s.Server = httptest.NewServer(s.record(mux))
+	tb.Cleanup(s.Close)
+	return s
+}
+
+// Options returns the request options that point a client at s.
+func (s *Server) Options() []option.RequestOption {
+	return []option.RequestOption{
+		option.WithBaseURL(s.URL),
+		option.WithAPIKey("anthropictest-key"),
+	}
+}
+
+// Reply queues replies to the next Messages requests, in order. Message
+// Batches requests take their replies from the same queue.
+func (s *Server) Reply(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.replies = append(s.replies, replies...)
+}
+
+// RespondWith sets the function that answers Messages requests once the
+// queued replies run out. Without one, such requests fail the test.
+func (s *Server) RespondWith(fn func(Request) Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.responder = fn
+}
+
+// Fail queues error replies to the next requests, to any endpoint, in order.
+// They take precedence over the endpoints' usual responses.
+func (s *Server) Fail(replies ...Reply) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failures = append(s.failures, replies...)
+}
+
+// CountTokensWith sets the function that answers token counting requests.
+// By default, the count is a quarter of the request body's length.
+func (s *Server) CountTokensWith(fn func(Request) int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.countFunc = fn
+}
+
+// Requests returns the requests received so far, in order.
+func (s *Server) Requests() []Request {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Request(nil), s.requests...)
+}
+
+// LastRequest returns the most recent request, or false if there was none.
+func (s *Server) LastRequest() (Request, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.requests) == 0 {
+		return Request{}, false
+	}
+	return s.requests[len(s.requests)-1], true
+}
+
+// PendingReplies returns the number of queued replies not yet sent.
+func (s *Server) PendingReplies() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.replies)
+}
+
+// record stores each request and answers it with a queued failure, if any,
+// before passing it on to next.
+func (s *Server) record(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		r.Body = io.NopCloser(bytes.NewReader(body))
+		req := Request{
+			Method: r.Method,
+			Path:   r.URL.Path,
+			Query:  r.URL.Query(),
+			Header: r.Header.Clone(),
+			Body:   body,
+		}
+		s.mu.Lock()
+		s.requests = append(s.requests, req)
+		var failure *Reply
+		if len(s.failures) > 0 {
+			failure = &s.failures[0]
+			s.failures = s.failures[1:]
+		}
+		s.mu.Unlock()
+		if failure != nil {
+			writeReplyError(w, *failure)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), requestKey{}, req)))
+	})
+}
+
+type requestKey struct{}
+
+// requestOf returns the recorded form of r.
+func requestOf(r *http.Request) Request {
+	req, _ := r.Context().Value(requestKey{}).(Request)
+	return req
+}
+
+// nextReply returns the reply to req, taken from the queue or the responder.
+func (s *Server) nextReply(req Request) (Reply, bool) {
+	s.mu.Lock()
+	if len(s.replies) > 0 {
+		reply := s.replies[0]
+		s.replies = s.replies[1:]
+		s.mu.Unlock()
+		return reply, true
+	}
+	responder := s.responder
+	s.mu.Unlock()
+	if responder != nil {
+		return responder(req), true
+	}
+	return Reply{}, false
+}
+
+// newID returns a unique ID with the given prefix.
+func (s *Server) newID(prefix string) string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.nextID++
+	return fmt.Sprintf("%stest%04d", prefix, s.nextID)
+}
+
+func (s *Server) handleMessages(w http.ResponseWriter, r *http.Request) {
+	req := requestOf(r)
+	reply, ok := s.nextReply(req)
+	if !ok {
+		s.tb.Errorf("anthropictest: no reply scripted for Messages request %s", req.Body)
+		writeError(w, http.StatusInternalServerError, "api_error", "anthropictest: no reply scripted")
+		return
+	}
+	if reply.isZero() {
+		s.tb.Errorf("anthropictest: zero Reply for Messages request %s; build replies with Message, Text, Error or Disconnect", req.Body)
+		writeError(w, http.StatusInternalServerError, "api_error", "anthropictest: zero Reply")
+		return
+	}
+	sleep(r, reply.delay)
+	stream := gjson.GetBytes(req.Body, "stream").Bool()
+	if reply.isError() || reply.disconnect && (reply.disconnectAfter == 0 || !stream) {
+		writeReplyError(w, reply)
+		return
+	}
+	for k, v := range reply.header {
+		w.Header()[k] = v
+	}
+	message := reply.message(gjson.GetBytes(req.Body, "model").String(), s.newID)
+	if !stream {
+		writeJSON(w, http.StatusOK, message)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	for i, event := range streamEvents(message) {
+		if i > 0 {
+			sleep(r, reply.delay)
+		}
+		if reply.disconnect && i == reply.disconnectAfter {
+			panic(http.ErrAbortHandler)
+		}
+		w.Write(event.encode())
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+}
+
+func (s *Server) handleCountTokens(w http.ResponseWriter, r *http.Request) {
+	req := requestOf(r)
+	s.mu.Lock()
+	count := s.countFunc
+	s.mu.Unlock()
+	n := int64(len(req.Body) / 4)
+	if count != nil {
+		n = count(req)
+	}
+	writeJSON(w, http.StatusOK, map[string]int64{"input_tokens": n})
+}
+
+// writeReplyError writes reply, an error or a disconnect, as the response.
+func writeReplyError(w http.ResponseWriter, reply Reply) {
+	if !reply.isError() {
+		panic(http.ErrAbortHandler)
+	}
+	for k, v := range reply.header {
+		w.Header()[k] = v
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(reply.status)
+	w.Write(reply.errorBody())
+}
+
+func writeError(w http.ResponseWriter, status int, errorType, message string) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	w.Write(errorJSON(errorType, message))
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// page returns the list response for items.
+func page[T any](items []T, id func(T) string) map[string]any {
+	res := map[string]any{"data": items, "has_more": false, "first_id": nil, "last_id": nil}
+	if len(items) > 0 {
+		res["first_id"] = id(items[0])
+		res["last_id"] = id(items[len(items)-1])
+	}
+	return res
+}
+
+func sleep(r *http.Request, d time.Duration) {
+	if d <= 0 {
+		return
+	}
+	select {
+	case <-r.Context().Done():
+	case <-time.After(d):
+	}
+}
@@ -0,0 +1,276 @@
+package anthropictest_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/lib/anthropictest"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+func newParams(text string) anthropic.MessageNewParams {
+	return anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaudeSonnet4_5,
+		MaxTokens: 1024,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(text))},
+	}
+}
+
+func TestScriptedMessages(t *testing.T) {
+	server := anthropictest.NewServer(t)
+	client := anthropic.NewClient(server.Options()...)
+	server.Reply(
+		anthropictest.ToolUse("get_weather", map[string]any{"city": "Paris"}),
+		anthropictest.Text("It is sunny in Paris.").WithUsage(30, 7),
+	)
+
+	msg, err := client.Messages.New(context.Background(), newParams("Weather in Paris?"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.StopReason != anthropic.StopReasonToolUse || msg.Content[0].Name != "get_weather" || string(msg.Content[0].Input) != `{"city":"Paris"}` {
+		t.Errorf("unexpected tool use reply: %s", msg.RawJSON())
+	}
+	if msg.Model != anthropic.ModelClaudeSonnet4_5 {
+		t.Errorf("expected the request's model to be echoed, got %s", msg.Model)
+	}
+
+	msg, err = client.Messages.New(context.Background(), newParams("Thanks"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content[0].Text != "It is sunny in Paris." || msg.Usage.InputTokens != 30 || msg.StopReason != anthropic.StopReasonEndTurn {
+		t.Errorf("unexpected text reply: %s", msg.RawJSON())
+	}
+
+	req, _ := server.LastRequest()
+	var params anthropic.MessageNewParams
+	if err := req.Unmarshal(&params); err != nil {
+		t.Fatal(err)
+	}
+	if req.Path != "/v1/messages" || params.Messages[0].Content[0].OfText.Text != "Thanks" {
+		t.Errorf("unexpected recorded request: %s %s", req.Path, req.Body)
+	}
+	if req.Header.Get("X-Api-Key") != "anthropictest-key" {
+		t.Errorf("expected the API key to be sent, got headers %v", req.Header)
+	}
+}
+
+func TestStreamingMessages(t *testing.T) {
+	server := anthropictest.NewServer(t)
+	client := anthropic.NewClient(server.Options()...)
+	server.Reply(anthropictest.Message(
+		anthropictest.TextBlock("Let me check the weather in Zürich for you."),
+		anthropictest.ToolUseBlock("get_weather", map[string]any{"city": "Zürich", "units": "metric"}),
+	))
+
+	stream := client.Messages.NewStreaming(context.Background(), newParams("Weather?"))
+	var msg anthropic.Message
+	events := 0
+	for stream.Next() {
+		events++
+		if err := msg.Accumulate(stream.Current()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if events < 8 {
+		t.Errorf("expected the reply to be streamed in deltas, got %d events", events)
+	}
+	if len(msg.Content) != 2 || msg.Content[0].Text != "Let me check the weather in Zürich for you." {
+		t.Fatalf("unexpected accumulated message: %s", msg.RawJSON())
+	}
+	var input struct{ City, Units string }
+	if err := msg.Content[1].ParsePartialInput(&input); err != nil || input.City != "Zürich" || input.Units != "metric" {
+		t.Errorf("unexpected tool input %s: %v", msg.Content[1].Input, err)
+	}
+	if msg.StopReason != anthropic.StopReasonToolUse || msg.Content[1].ID == "" {
+		t.Errorf("unexpected stop reason %s or tool use ID %q", msg.StopReason, msg.Content[1].ID)
+	}
+}
+
+func TestInjectedFailures(t *testing.T) {
+	server := anthropictest.NewServer(t)
+	client := anthropic.NewClient(append(server.Options(), option.WithMaxRetries(0))...)
+
+	server.Reply(anthropictest.Overloaded())
+	_, err := client.Messages.New(context.Background(), newParams("hi"))
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 529 {
+		t.Errorf("expected a 529, got %v", err)
+	}
+
+	server.Reply(anthropictest.Text("a long reply that will not arrive in full").DisconnectAfter(3))
+	stream := client.Messages.NewStreaming(context.Background(), newParams("hi"))
+	for stream.Next() {
+	}
+	if stream.Err() == nil {
+		t.Error("expected the dropped stream to fail")
+	}
+
+	server.Reply(anthropictest.Disconnect())
+	if _, err := client.Messages.New(context.Background(), newParams("hi")); err == nil {
+		t.Error("expected the dropped connection to fail")
+	}
+
+	retrying := anthropic.NewClient(append(server.Options(), option.WithMaxRetries(2), option.WithRequestTimeout(5*time.Second))...)
+	server.Reply(anthropictest.RateLimited(0), anthropictest.Text("second time lucky"))
+	msg, err := retrying.Messages.New(context.Background(), newParams("hi"))
+	if err != nil || msg.Content[0].Text != "second time lucky" {
+		t.Errorf("expected the retry to succeed, got %v", err)
+	}
+
+	server.Fail(anthropictest.Error(http.StatusInternalServerError, "api_error", "boom"))
+	if _, err := client.Models.List(context.Background(), anthropic.ModelListParams{}); err == nil {
+		t.Error("expected the injected failure on the Models endpoint")
+	}
+	if server.PendingReplies() != 0 {
+		t.Errorf("expected every reply to be used, %d left", server.PendingReplies())
+	}
+}
+
+// recordingTB records the errors a [anthropictest.Server] reports.
+type recordingTB struct {
+	testing.TB
+	mu     sync.Mutex
+	errors []string
+}
+
+func (tb *recordingTB) Errorf(format string, args ...any) {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	tb.errors = append(tb.errors, fmt.Sprintf(format, args...))
+}
+
+func TestZeroReplyFails(t *testing.T) {
+	tb := &recordingTB{TB: t}
+	server := anthropictest.NewServer(tb)
+	client := anthropic.NewClient(append(server.Options(), option.WithMaxRetries(0))...)
+
+	server.RespondWith(func(anthropictest.Request) anthropictest.Reply { return anthropictest.Reply{} })
+	var apiErr *anthropic.Error
+	if _, err := client.Messages.New(context.Background(), newParams("hi")); !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusInternalServerError {
+		t.Errorf("expected a 500 rather than a dropped connection, got %v", err)
+	}
+	if len(tb.errors) != 1 || !strings.Contains(tb.errors[0], "zero Reply") {
+		t.Errorf("expected the zero Reply to fail the test, got %q", tb.errors)
+	}
+}
+
+func TestCountTokensAndModels(t *testing.T) {
+	server := anthropictest.NewServer(t)
+	client := anthropic.NewClient(server.Options()...)
+	server.CountTokensWith(func(anthropictest.Request) int64 { return 42 })
+
+	count, err := client.Messages.CountTokens(context.Background(), anthropic.MessageCountTokensParams{
+		Model:    anthropic.ModelClaudeSonnet4_5,
+		Messages: []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hi"))},
+	})
+	if err != nil || count.InputTokens != 42 {
+		t.Errorf("expected 42 input tokens, got %v, %v", count, err)
+	}
+
+	server.SetModels(anthropictest.Model{ID: "claude-test-1", DisplayName: "Claude Test"})
+	var ids []string
+	iter := client.Models.ListAutoPaging(context.Background(), anthropic.ModelListParams{})
+	for iter.Next() {
+		ids = append(ids, iter.Current().ID)
+	}
+	if err := iter.Err(); err != nil || !slices.Equal(ids, []string{"claude-test-1"}) {
+		t.Errorf("unexpected models %v: %v", ids, err)
+	}
+	model, err := client.Models.Get(context.Background(), "claude-test-1", anthropic.ModelGetParams{})
+	if err != nil || model.DisplayName != "Claude Test" {
+		t.Errorf("unexpected model %v: %v", model, err)
+	}
+	if _, err := client.Models.Get(context.Background(), "claude-missing", anthropic.ModelGetParams{}); err == nil {
+		t.Error("expected an unknown model to be not found")
+	}
+}
+
+func TestBatches(t *testing.T) {
+	server := anthropictest.NewServer(t)
+	client := anthropic.NewClient(server.Options()...)
+	server.Reply(anthropictest.Text("one"), anthropictest.Overloaded(), anthropictest.Text("three"))
+
+	requests := []anthropic.MessageBatchJobRequest{
+		{CustomID: "a", Params: newParams("1")},
+		{CustomID: "b", Params: newParams("2")},
+		{CustomID: "c", Params: newParams("3")},
+	}
+	job, err := client.Messages.Batches.Submit(context.Background(), slices.Values(requests), anthropic.MessageBatchJobParams{PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := map[string]string{}
+	for res, err := range job.Results(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		switch r := res.Result.AsAny().(type) {
+		case anthropic.MessageBatchSucceededResult:
+			got[res.CustomID] = r.Message.Content[0].Text
+		case anthropic.MessageBatchErroredResult:
+			got[res.CustomID] = "error: " + r.Error.Error.Message
+		}
+	}
+	want := map[string]string{"a": "one", "b": "error: Overloaded", "c": "three"}
+	for id, text := range want {
+		if got[id] != text {
+			t.Errorf("%s: expected %q, got %q", id, text, got[id])
+		}
+	}
+
+	batches, err := job.Wait(context.Background())
+	if err != nil || batches[0].RequestCounts.Succeeded != 2 || batches[0].RequestCounts.Errored != 1 {
+		t.Errorf("unexpected batch counts %+v: %v", batches[0].RequestCounts, err)
+	}
+}
+
+func TestFiles(t *testing.T) {
+	server := anthropictest.NewServer(t)
+	client := anthropic.NewClient(server.Options()...)
+
+	meta, err := client.Beta.Files.Upload(context.Background(), anthropic.BetaFileUploadParams{
+		File: anthropic.File(bytes.NewReader([]byte("hello, world")), "hello.txt", "text/plain"),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.Filename != "hello.txt" || meta.SizeBytes != 12 || meta.MimeType != "text/plain" {
+		t.Errorf("unexpected file metadata: %s", meta.RawJSON())
+	}
+
+	resp, err := client.Beta.Files.Download(context.Background(), meta.ID, anthropic.BetaFileDownloadParams{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(data) != "hello, world" {
+		t.Errorf("unexpected file content %q", data)
+	}
+
+	page, err := client.Beta.Files.List(context.Background(), anthropic.BetaFileListParams{})
+	if err != nil || len(page.Data) != 1 || page.Data[0].ID != meta.ID {
+		t.Errorf("unexpected file list: %v", err)
+	}
+	if _, err := client.Beta.Files.Delete(context.Background(), meta.ID, anthropic.BetaFileDeleteParams{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Beta.Files.GetMetadata(context.Background(), meta.ID, anthropic.BetaFileGetMetadataParams{}); err == nil {
+		t.Error("expected the deleted file to be gone")
+	}
+}
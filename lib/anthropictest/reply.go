@@ -0,0 +1,263 @@
+package anthropictest
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+	"unicode/utf8"
+)
+
+// Reply is a scripted answer to a Messages request: either a message, built
+// from content blocks, or an API error. A message reply is streamed as
+// server-sent events when the request asks for a stream. The zero Reply
+// scripts nothing: a request answered with it fails the test.
+type Reply struct {
+	blocks     []Block
+	stopReason string
+	usage      [2]int64
+	header     http.Header
+
+	status       int
+	errorType    string
+	errorMessage string
+
+	// disconnect drops the connection after disconnectAfter stream events,
+	// or before a non-streaming response.
+	disconnect      bool
+	disconnectAfter int
+	delay           time.Duration
+}
+
+// Block is a content block of a message [Reply].
+type Block map[string]any
+
+// TextBlock returns a text content block.
+func TextBlock(text string) Block {
+	return Block{"type": "text", "text": text}
+}
+
+// ToolUseBlock returns a tool_use content block calling the tool name with
+// input, which is encoded as JSON. Its ID is assigned when it is sent.
+func ToolUseBlock(name string, input any) Block {
+	return Block{"type": "tool_use", "name": name, "input": input}
+}
+
+// ThinkingBlock returns a thinking content block.
+func ThinkingBlock(thinking, signature string) Block {
+	return Block{"type": "thinking", "thinking": thinking, "signature": signature}
+}
+
+// Message returns a reply with the given content. Its stop reason is tool_use
+// if a block is a tool_use block, and end_turn otherwise.
+func Message(blocks ...Block) Reply {
+	stop := "end_turn"
+	for _, b := range blocks {
+		if b["type"] == "tool_use" {
+			stop = "tool_use"
+		}
+	}
+	return Reply{blocks: blocks, stopReason: stop, usage: [2]int64{10, 10}}
+}
+
+// Text returns a reply with one text block.
+func Text(text string) Reply {
+	return Message(TextBlock(text))
+}
+
+// ToolUse returns a reply calling the tool name with input.
+func ToolUse(name string, input any) Reply {
+	return Message(ToolUseBlock(name, input))
+}
+
+// Error returns a reply that fails with the given HTTP status and API error.
+func Error(status int, errorType, message string) Reply {
+	return Reply{status: status, errorType: errorType, errorMessage: message}
+}
+
+// Overloaded returns a reply that fails with a 529 overloaded_error.
+func Overloaded() Reply {
+	return Error(529, "overloaded_error", "Overloaded")
+}
+
+// RateLimited returns a reply that fails with a 429 rate_limit_error and a
+// retry-after header.
+func RateLimited(retryAfter time.Duration) Reply {
+	return Error(http.StatusTooManyRequests, "rate_limit_error", "Rate limited").
+		WithHeader("retry-after", strconv.Itoa(int(retryAfter.Seconds())))
+}
+
+// Disconnect returns a reply that drops the connection before responding.
+func Disconnect() Reply {
+	return Reply{disconnect: true}
+}
+
+// WithStopReason returns r with its stop reason set.
+func (r Reply) WithStopReason(stopReason string) Reply {
+	r.stopReason = stopReason
+	return r
+}
+
+// WithUsage returns r reporting the given token usage. Message replies
+// default to 10 input and 10 output tokens.
+func (r Reply) WithUsage(inputTokens, outputTokens int64) Reply {
+	r.usage = [2]int64{inputTokens, outputTokens}
+	return r
+}
+
+// WithHeader returns r with a response header added.
+func (r Reply) WithHeader(key, value string) Reply {
+	h := r.header.Clone()
+	if h == nil {
+		h = http.Header{}
+	}
+	h.Add(key, value)
+	r.header = h
+	return r
+}
+
+// WithDelay returns r sent after a delay, or, for a stream, with the delay
+// between events.
+func (r Reply) WithDelay(d time.Duration) Reply {
+	r.delay = d
+	return r
+}
+
+// DisconnectAfter returns r with the connection dropped after n events of its
+// stream, before the message is complete. A non-streaming request gets no
+// response at all.
+func (r Reply) DisconnectAfter(n int) Reply {
+	r.disconnect = true
+	r.disconnectAfter = n
+	return r
+}
+
+func (r Reply) isError() bool { return r.status != 0 }
+
+// isZero reports whether r is the zero Reply, which scripts nothing.
+func (r Reply) isZero() bool {
+	return r.blocks == nil && r.stopReason == "" && !r.isError() && !r.disconnect
+}
+
+// errorBody returns the API error body of an error reply.
+func (r Reply) errorBody() []byte {
+	return errorJSON(r.errorType, r.errorMessage)
+}
+
+func errorJSON(errorType, message string) []byte {
+	b, _ := json.Marshal(map[string]any{
+		"type":  "error",
+		"error": map[string]string{"type": errorType, "message": message},
+	})
+	return b
+}
+
+// message renders r as a message for a request with the given model. newID
+// returns IDs with the given prefix.
+func (r Reply) message(model string, newID func(prefix string) string) map[string]any {
+	content := make([]Block, len(r.blocks))
+	for i, b := range r.blocks {
+		c := Block{}
+		for k, v := range b {
+			c[k] = v
+		}
+		if c["type"] == "tool_use" && c["id"] == nil {
+			c["id"] = newID("toolu_")
+		}
+		content[i] = c
+	}
+	return map[string]any{
+		"id":            newID("msg_"),
+		"type":          "message",
+		"role":          "assistant",
+		"model":         model,
+		"content":       content,
+		"stop_reason":   r.stopReason,
+		"stop_sequence": nil,
+		"usage":         map[string]int64{"input_tokens": r.usage[0], "output_tokens": r.usage[1]},
+	}
+}
+
+// sseEvent is one server-sent event of a streamed message.
+type sseEvent struct {
+	name string
+	data any
+}
+
+// streamEvents renders message, as returned by [Reply.message], as the events
+// of a stream.
+func streamEvents(message map[string]any) []sseEvent {
+	start := map[string]any{}
+	for k, v := range message {
+		start[k] = v
+	}
+	usage := message["usage"].(map[string]int64)
+	start["content"] = []Block{}
+	start["stop_reason"] = nil
+	start["usage"] = map[string]int64{"input_tokens": usage["input_tokens"], "output_tokens": 1}
+	events := []sseEvent{{"message_start", map[string]any{"type": "message_start", "message": start}}}
+
+	for i, block := range message["content"].([]Block) {
+		var startBlock Block
+		var deltas []map[string]any
+		switch block["type"] {
+		case "text":
+			startBlock = Block{"type": "text", "text": ""}
+			for _, chunk := range chunks(block["text"].(string)) {
+				deltas = append(deltas, map[string]any{"type": "text_delta", "text": chunk})
+			}
+		case "tool_use":
+			startBlock = Block{"type": "tool_use", "id": block["id"], "name": block["name"], "input": map[string]any{}}
+			input, _ := json.Marshal(block["input"])
+			for _, chunk := range chunks(string(input)) {
+				deltas = append(deltas, map[string]any{"type": "input_json_delta", "partial_json": chunk})
+			}
+		case "thinking":
+			startBlock = Block{"type": "thinking", "thinking": "", "signature": ""}
+			for _, chunk := range chunks(block["thinking"].(string)) {
+				deltas = append(deltas, map[string]any{"type": "thinking_delta", "thinking": chunk})
+			}
+			deltas = append(deltas, map[string]any{"type": "signature_delta", "signature": block["signature"]})
+		default:
+			startBlock = block
+		}
+		events = append(events, sseEvent{"content_block_start", map[string]any{"type": "content_block_start", "index": i, "content_block": startBlock}})
+		for _, delta := range deltas {
+			events = append(events, sseEvent{"content_block_delta", map[string]any{"type": "content_block_delta", "index": i, "delta": delta}})
+		}
+		events = append(events, sseEvent{"content_block_stop", map[string]any{"type": "content_block_stop", "index": i}})
+	}
+
+	events = append(events,
+		sseEvent{"message_delta", map[string]any{
+			"type":  "message_delta",
+			"delta": map[string]any{"stop_reason": message["stop_reason"], "stop_sequence": nil},
+			"usage": map[string]int64{"output_tokens": usage["output_tokens"]},
+		}},
+		sseEvent{"message_stop", map[string]any{"type": "message_stop"}},
+	)
+	return events
+}
+
+// chunks splits s into pieces of a few runes, as a stream delivers them.
+func chunks(s string) []string {
+	const size = 8
+	var out []string
+	for len(s) > 0 {
+		n, i := 0, 0
+		for i < len(s) && n < size {
+			_, w := utf8.DecodeRuneInString(s[i:])
+			i += w
+			n++
+		}
+		out = append(out, s[:i])
+		s = s[i:]
+	}
+	return out
+}
+
+func (e sseEvent) encode() []byte {
+	data, _ := json.Marshal(e.data)
+	return []byte(fmt.Sprintf("event: %s\ndata: %s\n\n", e.name, data))
+}
@@ -0,0 +1,323 @@
+package anthropictest
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/tidwall/gjson"
+)
+
+// batch is a Message Batch held by a [Server]. Its results are decided when
+// it is created; it ends the first time it is fetched.
+type batch struct {
+	id        string
+	createdAt time.Time
+	status    string
+	cancel    bool
+	customIDs []string
+	results   []map[string]any
+}
+
+func (b *batch) json() map[string]any {
+	counts := map[string]int{"processing": 0, "succeeded": 0, "errored": 0, "canceled": 0, "expired": 0}
+	if b.status == "ended" {
+		for _, res := range b.results {
+			counts[res["type"].(string)]++
+		}
+	} else {
+		counts["processing"] = len(b.results)
+	}
+	res := map[string]any{
+		"id":                  b.id,
+		"type":                "message_batch",
+		"processing_status":   b.status,
+		"request_counts":      counts,
+		"created_at":          b.createdAt,
+		"expires_at":          b.createdAt.Add(24 * time.Hour),
+		"archived_at":         nil,
+		"cancel_initiated_at": nil,
+		"ended_at":            nil,
+		"results_url":         nil,
+	}
+	if b.cancel {
+		res["cancel_initiated_at"] = b.createdAt
+	}
+	if b.status == "ended" {
+		res["ended_at"] = b.createdAt
+		res["results_url"] = fmt.Sprintf("/v1/messages/batches/%s/results", b.id)
+	}
+	return res
+}
+
+func (s *Server) handleCreateBatch(w http.ResponseWriter, r *http.Request) {
+	req := requestOf(r)
+	requests := gjson.GetBytes(req.Body, "requests").Array()
+	if len(requests) == 0 {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "requests: must not be empty")
+		return
+	}
+	b := &batch{id: s.newID("msgbatch_"), createdAt: time.Now().UTC(), status: "in_progress"}
+	for _, item := range requests {
+		params := []byte(item.Get("params").Raw)
+		reply, ok := s.nextReply(Request{Method: http.MethodPost, Path: "/v1/messages", Header: req.Header, Body: params})
+		if !ok {
+			s.tb.Errorf("anthropictest: no reply scripted for batch request %s", item.Get("custom_id"))
+			reply = Error(http.StatusInternalServerError, "api_error", "anthropictest: no reply scripted")
+		}
+		if reply.isZero() {
+			s.tb.Errorf("anthropictest: zero Reply for batch request %s; build replies with Message, Text, Error or Disconnect", item.Get("custom_id"))
+			reply = Error(http.StatusInternalServerError, "api_error", "anthropictest: zero Reply")
+		}
+		var result map[string]any
+		switch {
+		case reply.isError():
+			result = map[string]any{"type": "errored", "error": json.RawMessage(reply.errorBody())}
+		case reply.disconnect:
+			result = map[string]any{"type": "errored", "error": json.RawMessage(errorJSON("api_error", "Internal server error"))}
+		default:
+			result = map[string]any{"type": "succeeded", "message": reply.message(gjson.GetBytes(params, "model").String(), s.newID)}
+		}
+		b.customIDs = append(b.customIDs, item.Get("custom_id").String())
+		b.results = append(b.results, result)
+	}
+	s.mu.Lock()
+	s.batches[b.id] = b
+	s.batchIDs = append(s.batchIDs, b.id)
+	res := b.json()
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, res)
+}
+
+// getBatch returns the batch with the request's id, writing a 404 if there is
+// none. s.mu must be held.
+func (s *Server) getBatch(w http.ResponseWriter, r *http.Request) *batch {
+	b, ok := s.batches[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found_error", "batch not found")
+	}
+	return b
+}
+
+func (s *Server) handleGetBatch(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if b := s.getBatch(w, r); b != nil {
+		b.status = "ended"
+		writeJSON(w, http.StatusOK, b.json())
+	}
+}
+
+func (s *Server) handleListBatches(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []map[string]any
+	for i := len(s.batchIDs) - 1; i >= 0; i-- {
+		items = append(items, s.batches[s.batchIDs[i]].json())
+	}
+	writeJSON(w, http.StatusOK, page(items, func(b map[string]any) string { return b["id"].(string) }))
+}
+
+func (s *Server) handleCancelBatch(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.getBatch(w, r)
+	if b == nil {
+		return
+	}
+	if b.status != "ended" {
+		b.cancel = true
+		b.status = "canceling"
+		for i := range b.results {
+			b.results[i] = map[string]any{"type": "canceled"}
+		}
+	}
+	writeJSON(w, http.StatusOK, b.json())
+}
+
+func (s *Server) handleDeleteBatch(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.getBatch(w, r)
+	if b == nil {
+		return
+	}
+	if b.status != "ended" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "batch has not ended")
+		return
+	}
+	delete(s.batches, b.id)
+	writeJSON(w, http.StatusOK, map[string]string{"id": b.id, "type": "message_batch_deleted"})
+}
+
+func (s *Server) handleBatchResults(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	b := s.getBatch(w, r)
+	if b == nil {
+		return
+	}
+	if b.status != "ended" {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "batch has not ended")
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-jsonl")
+	enc := json.NewEncoder(w)
+	for i, result := range b.results {
+		enc.Encode(map[string]any{"custom_id": b.customIDs[i], "result": result})
+	}
+}
+
+// file is a file uploaded to a [Server].
+type file struct {
+	id        string
+	filename  string
+	mimeType  string
+	createdAt time.Time
+	data      []byte
+}
+
+func (f *file) json() map[string]any {
+	return map[string]any{
+		"id":           f.id,
+		"type":         "file",
+		"filename":     f.filename,
+		"mime_type":    f.mimeType,
+		"size_bytes":   len(f.data),
+		"created_at":   f.createdAt,
+		"downloadable": true,
+	}
+}
+
+func (s *Server) handleUploadFile(w http.ResponseWriter, r *http.Request) {
+	part, header, err := r.FormFile("file")
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "file: "+err.Error())
+		return
+	}
+	defer part.Close()
+	data, err := io.ReadAll(part)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "file: "+err.Error())
+		return
+	}
+	mimeType := header.Header.Get("Content-Type")
+	if mimeType == "" || mimeType == "application/octet-stream" {
+		mimeType = http.DetectContentType(data)
+	}
+	f := &file{id: s.newID("file_"), filename: header.Filename, mimeType: mimeType, createdAt: time.Now().UTC(), data: data}
+	s.mu.Lock()
+	s.files[f.id] = f
+	s.fileIDs = append(s.fileIDs, f.id)
+	s.mu.Unlock()
+	writeJSON(w, http.StatusOK, f.json())
+}
+
+// getFile returns the file with the request's id, writing a 404 if there is
+// none. s.mu must be held.
+func (s *Server) getFile(w http.ResponseWriter, r *http.Request) *file {
+	f, ok := s.files[r.PathValue("id")]
+	if !ok {
+		writeError(w, http.StatusNotFound, "not_found_error", "file not found")
+	}
+	return f
+}
+
+func (s *Server) handleGetFile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f := s.getFile(w, r); f != nil {
+		writeJSON(w, http.StatusOK, f.json())
+	}
+}
+
+func (s *Server) handleFileContent(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f := s.getFile(w, r); f != nil {
+		w.Header().Set("Content-Type", f.mimeType)
+		w.Write(f.data)
+	}
+}
+
+func (s *Server) handleListFiles(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []map[string]any
+	for i := len(s.fileIDs) - 1; i >= 0; i-- {
+		if f, ok := s.files[s.fileIDs[i]]; ok {
+			items = append(items, f.json())
+		}
+	}
+	writeJSON(w, http.StatusOK, page(items, func(f map[string]any) string { return f["id"].(string) }))
+}
+
+func (s *Server) handleDeleteFile(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if f := s.getFile(w, r); f != nil {
+		delete(s.files, f.id)
+		writeJSON(w, http.StatusOK, map[string]string{"id": f.id, "type": "file_deleted"})
+	}
+}
+
+// Model is a model served by a [Server]'s Models endpoints.
+type Model struct {
+	ID             string
+	DisplayName    string
+	CreatedAt      time.Time
+	MaxInputTokens int64
+	MaxTokens      int64
+}
+
+func (m Model) json() map[string]any {
+	return map[string]any{
+		"id":               m.ID,
+		"type":             "model",
+		"display_name":     m.DisplayName,
+		"created_at":       m.CreatedAt,
+		"max_input_tokens": m.MaxInputTokens,
+		"max_tokens":       m.MaxTokens,
+	}
+}
+
+// DefaultModels returns the models a [Server] lists unless SetModels is
+// called.
+func DefaultModels() []Model {
+	return []Model{
+		{ID: "claude-opus-4-5-20251101", DisplayName: "Claude Opus 4.5", CreatedAt: time.Date(2025, 11, 24, 0, 0, 0, 0, time.UTC), MaxInputTokens: 200_000, MaxTokens: 64_000},
+		{ID: "claude-sonnet-4-5-20250929", DisplayName: "Claude Sonnet 4.5", CreatedAt: time.Date(2025, 9, 29, 0, 0, 0, 0, time.UTC), MaxInputTokens: 200_000, MaxTokens: 64_000},
+		{ID: "claude-haiku-4-5-20251001", DisplayName: "Claude Haiku 4.5", CreatedAt: time.Date(2025, 10, 15, 0, 0, 0, 0, time.UTC), MaxInputTokens: 200_000, MaxTokens: 64_000},
+	}
+}
+
+// SetModels replaces the models listed by the Models endpoints.
+func (s *Server) SetModels(models ...Model) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.models = append([]Model(nil), models...)
+}
+
+func (s *Server) handleListModels(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var items []map[string]any
+	for _, m := range s.models {
+		items = append(items, m.json())
+	}
+	writeJSON(w, http.StatusOK, page(items, func(m map[string]any) string { return m["id"].(string) }))
+}
+
+func (s *Server) handleGetModel(w http.ResponseWriter, r *http.Request) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, m := range s.models {
+		if m.ID == r.PathValue("id") {
+			writeJSON(w, http.StatusOK, m.json())
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "not_found_error", "model not found")
+}
@@ -0,0 +1,411 @@
+// Package router sends Messages requests to an ordered list of backends —
+// the first-party API, Amazon Bedrock, Google Vertex AI, or a gateway, each
+// an [anthropic.Client] built with that provider's options — and fails over
+// to the next backend when one is overloaded, rate limited or unreachable.
+//
+//	client := router.NewClient([]router.Backend{
+//		{Name: "anthropic", Client: anthropic.NewClient()},
+//		{Name: "bedrock", Client: anthropic.NewClient(bedrock.WithLoadDefaultConfig(ctx))},
+//		{Name: "gateway", Client: anthropic.NewClient(option.WithBaseURL(gatewayURL)),
+//			Models: map[string]string{"claude-sonnet-4-5": "sonnet-prod"}},
+//	}, router.ClientOptions{})
+//	var res *http.Response
+//	message, err := client.Messages.New(ctx, params, option.WithResponseInto(&res))
+//	log.Printf("served by %s", router.ServedBy(res))
+//
+// Each backend has a circuit breaker: after [ClientOptions.FailureThreshold]
+// consecutive failures it is skipped for [ClientOptions.Cooldown], then
+// probed by the next request while others keep skipping it. A 429 that asks for a wait skips the
+// backend for that long straight away.
+package router
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+)
+
+// BackendHeader is the response header the router sets to the name of the
+// backend that served a request. Read it with [ServedBy].
+const BackendHeader = "X-Router-Backend"
+
+// ServedBy returns the name of the backend that served res, a response
+// captured with option.WithResponseInto or from an [anthropic.Error]. It
+// returns "" for a response that did not come through a [Client].
+func ServedBy(res *http.Response) string {
+	if res == nil {
+		return ""
+	}
+	return res.Header.Get(BackendHeader)
+}
+
+// Backend is one destination of a [Client].
+type Backend struct {
+	// Name identifies the backend in [BackendHeader], [Client.Health] and
+	// errors. Required and unique.
+	Name string
+
+	// Client is configured for the backend's provider, e.g. with
+	// bedrock.WithLoadDefaultConfig or vertex.WithGoogleAuth.
+	Client anthropic.Client
+
+	// Models maps the model of a request to the ID this backend knows it
+	// by. When nil, models are sent unchanged. Otherwise the backend only
+	// serves the models it lists. The bedrock and vertex clients already
+	// translate first-party model IDs with the modelregistry package.
+	Models map[string]string
+}
+
+func (b *Backend) model(model string) (string, bool) {
+	if b.Models == nil {
+		return model, true
+	}
+	mapped, ok := b.Models[model]
+	return mapped, ok
+}
+
+// ClientOptions configures a [Client].
+type ClientOptions struct {
+	// FailureThreshold is the number of consecutive failures after which a
+	// backend's circuit opens. Defaults to 3.
+	FailureThreshold int
+
+	// Cooldown is how long an open circuit skips its backend before one
+	// request is let through to probe it. Defaults to 30s.
+	Cooldown time.Duration
+}
+
+// Client sends Messages requests to the first healthy backend that serves
+// the requested model. Create one with [NewClient]; it is safe for
+// concurrent use.
+type Client struct {
+	Messages MessageService
+
+	backends []*backendState
+	opts     ClientOptions
+}
+
+// NewClient returns a Client over backends, tried in order. It panics if a
+// backend has no name or two share one.
+func NewClient(backends []Backend, opts ClientOptions) *Client {
+	if opts.FailureThreshold <= 0 {
+		opts.FailureThreshold = 3
+	}
+	if opts.Cooldown <= 0 {
+		opts.Cooldown = 30 * time.Second
+	}
+	c := &Client{opts: opts}
+	seen := map[string]bool{}
+	for _, b := range backends {
+		if b.Name == "" {
+			panic("router: backend name is required")
+		}
+		if seen[b.Name] {
+			panic(fmt.Sprintf("router: duplicate backend name %q", b.Name))
+		}
+		seen[b.Name] = true
+		c.backends = append(c.backends, &backendState{Backend: b})
+	}
+	c.Messages = MessageService{client: c}
+	return c
+}
+
+// CircuitState is the state of a backend's circuit breaker.
+type CircuitState string
+
+const (
+	// CircuitClosed means the backend takes requests.
+	CircuitClosed CircuitState = "closed"
+	// CircuitOpen means the backend is skipped until its cooldown ends.
+	CircuitOpen CircuitState = "open"
+	// CircuitHalfOpen means the cooldown has ended and the next request
+	// probes the backend: success closes the circuit, failure reopens it.
+	// Other requests skip the backend while the probe is in flight.
+	CircuitHalfOpen CircuitState = "half_open"
+)
+
+// BackendHealth is a snapshot of a backend's health, as reported by
+// [Client.Health].
+type BackendHealth struct {
+	Name                string
+	State               CircuitState
+	ConsecutiveFailures int
+	// OpenUntil is when an open circuit lets a probe through.
+	OpenUntil time.Time
+	// LastError is the most recent failure, kept until the next success.
+	LastError error
+	// LastServed is when the backend last served a request.
+	LastServed time.Time
+}
+
+// Health returns the health of each backend, in order.
+func (c *Client) Health() []BackendHealth {
+	now := time.Now()
+	health := make([]BackendHealth, len(c.backends))
+	for i, b := range c.backends {
+		b.mu.Lock()
+		health[i] = BackendHealth{
+			Name:                b.Name,
+			State:               b.state(now),
+			ConsecutiveFailures: b.failures,
+			OpenUntil:           b.openUntil,
+			LastError:           b.lastErr,
+			LastServed:          b.lastServed,
+		}
+		b.mu.Unlock()
+	}
+	return health
+}
+
+type backendState struct {
+	Backend
+
+	mu         sync.Mutex
+	failures   int
+	openUntil  time.Time
+	lastErr    error
+	lastServed time.Time
+	// probing is set while a half-open circuit's probe is in flight.
+	probing bool
+}
+
+func (b *backendState) state(now time.Time) CircuitState {
+	switch {
+	case b.openUntil.IsZero():
+		return CircuitClosed
+	case now.Before(b.openUntil):
+		return CircuitOpen
+	default:
+		return CircuitHalfOpen
+	}
+}
+
+// admit reports whether b takes a request now. A half-open circuit takes
+// one, the probe, until [Client.recordSuccess] or [Client.recordFailure]
+// records its outcome or [backendState.endProbe] abandons it.
+func (b *backendState) admit(now time.Time) (ok, probe bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch b.state(now) {
+	case CircuitClosed:
+		return true, false
+	case CircuitHalfOpen:
+		if b.probing {
+			return false, false
+		}
+		b.probing = true
+		return true, true
+	default:
+		return false, false
+	}
+}
+
+func (b *backendState) endProbe() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+}
+
+func (c *Client) recordSuccess(b *backendState) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.failures = 0
+	b.openUntil = time.Time{}
+	b.lastErr = nil
+	b.lastServed = time.Now()
+	b.probing = false
+}
+
+// recordFailure counts err against b. probe is whether the request was b's
+// half-open probe, which the failure ends.
+func (c *Client) recordFailure(b *backendState, err error, probe bool) {
+	now := time.Now()
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if probe {
+		b.probing = false
+	}
+	b.failures++
+	b.lastErr = err
+	if b.failures >= c.opts.FailureThreshold {
+		b.openUntil = now.Add(c.opts.Cooldown)
+	}
+	if wait := retryAfter(err); wait > 0 && now.Add(wait).After(b.openUntil) {
+		b.openUntil = now.Add(wait)
+	}
+}
+
+// candidates returns the backends that serve model, in order, with the
+// model each knows it by.
+func (c *Client) candidates(model string) (backends []*backendState, models []string) {
+	for _, b := range c.backends {
+		if mapped, ok := b.model(model); ok {
+			backends = append(backends, b)
+			models = append(models, mapped)
+		}
+	}
+	return backends, models
+}
+
+// Attempt is a failed request to one backend.
+type Attempt struct {
+	Backend string
+	Err     error
+}
+
+// FailoverError is returned when every backend that serves the requested
+// model failed. It unwraps to the last attempt's error, usually an
+// [*anthropic.Error].
+type FailoverError struct {
+	Model    string
+	Attempts []Attempt
+}
+
+func (e *FailoverError) Error() string {
+	if len(e.Attempts) == 0 {
+		return fmt.Sprintf("router: no backend serves model %q", e.Model)
+	}
+	parts := make([]string, len(e.Attempts))
+	for i, a := range e.Attempts {
+		parts[i] = fmt.Sprintf("%s: %v", a.Backend, a.Err)
+	}
+	return fmt.Sprintf("router: all backends failed for model %q: %s", e.Model, strings.Join(parts, "; "))
+}
+
+func (e *FailoverError) Unwrap() error {
+	if len(e.Attempts) == 0 {
+		return nil
+	}
+	return e.Attempts[len(e.Attempts)-1].Err
+}
+
+// shouldFailover reports whether err means the backend, rather than the
+// request, is at fault: a 429, 5xx or 529 response, or a transport error.
+func shouldFailover(ctx context.Context, err error) bool {
+	if ctx.Err() != nil {
+		return false
+	}
+	var apiErr *anthropic.Error
+	if errors.As(err, &apiErr) {
+		return apiErr.StatusCode == http.StatusTooManyRequests || apiErr.StatusCode >= 500
+	}
+	return true
+}
+
+func retryAfter(err error) time.Duration {
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests {
+		return 0
+	}
+	snapshot, ok := apiErr.RateLimit()
+	if !ok {
+		return 0
+	}
+	return snapshot.RetryAfter
+}
+
+// requestOptions returns the options for a request to b. Backend retries
+// are off so a struggling backend fails over at once; callers can turn
+// them back on with option.WithMaxRetries in opts.
+func requestOptions(b *backendState, opts []option.RequestOption) []option.RequestOption {
+	tagged := make([]option.RequestOption, 0, len(opts)+2)
+	tagged = append(tagged, option.WithMaxRetries(0))
+	tagged = append(tagged, opts...)
+	return append(tagged, option.WithMiddleware(func(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+		res, err := next(req)
+		if res != nil {
+			res.Header.Set(BackendHeader, b.Name)
+		}
+		return res, err
+	}))
+}
+
+// do sends a request with call to each candidate backend in turn until one
+// succeeds or fails in a way another backend would not fix. Backends whose
+// circuit does not admit the request are skipped unless none does: trying
+// them then beats failing without a request.
+func do[T any](ctx context.Context, c *Client, model string, call func(b *backendState, model string) (T, error)) (T, error) {
+	var zero T
+	backends, models := c.candidates(model)
+	failover := &FailoverError{Model: model}
+	for _, admitted := range []bool{true, false} {
+		if len(failover.Attempts) > 0 {
+			break
+		}
+		for i, b := range backends {
+			var probe bool
+			if admitted {
+				var ok bool
+				if ok, probe = b.admit(time.Now()); !ok {
+					continue
+				}
+			}
+			result, err := call(b, models[i])
+			if err == nil {
+				c.recordSuccess(b)
+				return result, nil
+			}
+			if !shouldFailover(ctx, err) {
+				if probe {
+					b.endProbe()
+				}
+				return zero, err
+			}
+			c.recordFailure(b, err, probe)
+			failover.Attempts = append(failover.Attempts, Attempt{Backend: b.Name, Err: err})
+		}
+	}
+	return zero, failover
+}
+
+// MessageService is the Messages surface of a [Client].
+type MessageService struct {
+	client *Client
+}
+
+// New sends params to the first healthy backend that serves params.Model,
+// with the model mapped for that backend, and fails over on a 429, 5xx or
+// 529 response or a transport error. Other errors are returned as is;
+// when every backend fails the error is a [*FailoverError].
+func (r MessageService) New(ctx context.Context, params anthropic.MessageNewParams, opts ...option.RequestOption) (*anthropic.Message, error) {
+	return do(ctx, r.client, string(params.Model), func(b *backendState, model string) (*anthropic.Message, error) {
+		params.Model = anthropic.Model(model)
+		return b.Client.Messages.New(ctx, params, requestOptions(b, opts)...)
+	})
+}
+
+// NewStreaming is [MessageService.New] for a streaming request. A backend
+// is failed over only when the stream fails before its first event; an
+// error in the middle of a stream is returned by the stream.
+func (r MessageService) NewStreaming(ctx context.Context, params anthropic.MessageNewParams, opts ...option.RequestOption) *ssestream.Stream[anthropic.MessageStreamEventUnion] {
+	stream, err := do(ctx, r.client, string(params.Model), func(b *backendState, model string) (*ssestream.Stream[anthropic.MessageStreamEventUnion], error) {
+		params.Model = anthropic.Model(model)
+		stream := b.Client.Messages.NewStreaming(ctx, params, requestOptions(b, opts)...)
+		if err := stream.Err(); err != nil {
+			stream.Close()
+			return nil, err
+		}
+		return stream, nil
+	})
+	if err != nil {
+		return ssestream.NewStream[anthropic.MessageStreamEventUnion](nil, err)
+	}
+	return stream
+}
+
+// CountTokens counts the tokens of params on the first healthy backend
+// that serves params.Model, failing over like [MessageService.New].
+func (r MessageService) CountTokens(ctx context.Context, params anthropic.MessageCountTokensParams, opts ...option.RequestOption) (*anthropic.MessageTokensCount, error) {
+	return do(ctx, r.client, string(params.Model), func(b *backendState, model string) (*anthropic.MessageTokensCount, error) {
+		params.Model = anthropic.Model(model)
+		return b.Client.Messages.CountTokens(ctx, params, requestOptions(b, opts)...)
+	})
+}
@@ -0,0 +1,252 @@
+package router_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/lib/anthropictest"
+	"github.com/anthropics/anthropic-sdk-go/lib/router"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/tidwall/gjson"
+)
+
+func newBackend(t *testing.T, name string, models map[string]string) (router.Backend, *anthropictest.Server) {
+	server := anthropictest.NewServer(t)
+	return router.Backend{
+		Name:   name,
+		Client: anthropic.NewClient(append(server.Options(), option.WithoutEnvironmentDefaults())...),
+		Models: models,
+	}, server
+}
+
+func params() anthropic.MessageNewParams {
+	return anthropic.MessageNewParams{
+		Model:     "claude-sonnet-4-5",
+		MaxTokens: 100,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hi"))},
+	}
+}
+
+func requestModels(server *anthropictest.Server) []string {
+	var models []string
+	for _, req := range server.Requests() {
+		models = append(models, gjson.GetBytes(req.Body, "model").String())
+	}
+	return models
+}
+
+func TestFailover(t *testing.T) {
+	primary, primaryServer := newBackend(t, "primary", nil)
+	secondary, secondaryServer := newBackend(t, "secondary", map[string]string{"claude-sonnet-4-5": "claude-sonnet-4-5@20250929"})
+	client := router.NewClient([]router.Backend{primary, secondary}, router.ClientOptions{FailureThreshold: 2, Cooldown: time.Hour})
+
+	primaryServer.Fail(anthropictest.Overloaded())
+	secondaryServer.Reply(anthropictest.Text("from secondary"))
+	var res *http.Response
+	message, err := client.Messages.New(context.Background(), params(), option.WithResponseInto(&res))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message.Content[0].Text != "from secondary" || router.ServedBy(res) != "secondary" {
+		t.Fatalf("got %q from %q", message.Content[0].Text, router.ServedBy(res))
+	}
+	if got := requestModels(secondaryServer); len(got) != 1 || got[0] != "claude-sonnet-4-5@20250929" {
+		t.Errorf("expected the model to be mapped for the secondary, got %v", got)
+	}
+	if len(primaryServer.Requests()) != 1 {
+		t.Errorf("expected the backend's own retries to be off, got %d requests", len(primaryServer.Requests()))
+	}
+
+	health := client.Health()
+	if health[0].State != router.CircuitClosed || health[0].ConsecutiveFailures != 1 || health[0].LastError == nil {
+		t.Errorf("unexpected primary health %+v", health[0])
+	}
+	if health[1].LastServed.IsZero() {
+		t.Errorf("expected the secondary to have served, got %+v", health[1])
+	}
+
+	// A second failure opens the primary's circuit, after which it is
+	// skipped without a request.
+	primaryServer.Fail(anthropictest.Error(http.StatusInternalServerError, "api_error", "boom"))
+	secondaryServer.Reply(anthropictest.Text("again"), anthropictest.Text("skipped primary"))
+	if _, err := client.Messages.New(context.Background(), params()); err != nil {
+		t.Fatal(err)
+	}
+	if state := client.Health()[0].State; state != router.CircuitOpen {
+		t.Fatalf("expected the primary's circuit to be open, got %s", state)
+	}
+	message, err = client.Messages.New(context.Background(), params(), option.WithResponseInto(&res))
+	if err != nil || message.Content[0].Text != "skipped primary" || router.ServedBy(res) != "secondary" {
+		t.Fatalf("got %v, %v", message, err)
+	}
+	if len(primaryServer.Requests()) != 2 {
+		t.Errorf("expected the open primary to be skipped, got %d requests", len(primaryServer.Requests()))
+	}
+}
+
+func TestFailover_HalfOpen(t *testing.T) {
+	primary, primaryServer := newBackend(t, "primary", nil)
+	secondary, secondaryServer := newBackend(t, "secondary", nil)
+	const cooldown = 50 * time.Millisecond
+	client := router.NewClient([]router.Backend{primary, secondary}, router.ClientOptions{FailureThreshold: 1, Cooldown: cooldown})
+	secondaryServer.RespondWith(func(anthropictest.Request) anthropictest.Reply { return anthropictest.Text("from secondary") })
+
+	primaryServer.Fail(anthropictest.Overloaded())
+	if _, err := client.Messages.New(context.Background(), params()); err != nil {
+		t.Fatal(err)
+	}
+	if state := client.Health()[0].State; state != router.CircuitOpen {
+		t.Fatalf("expected the primary's circuit to be open, got %s", state)
+	}
+
+	// Once the cooldown ends, one request probes the primary while the
+	// others keep going to the secondary until it succeeds.
+	time.Sleep(cooldown)
+	if state := client.Health()[0].State; state != router.CircuitHalfOpen {
+		t.Fatalf("expected the primary's circuit to be half-open, got %s", state)
+	}
+	primaryServer.Reply(anthropictest.Text("probe").WithDelay(200 * time.Millisecond))
+	probed := make(chan error, 1)
+	go func() {
+		_, err := client.Messages.New(context.Background(), params())
+		probed <- err
+	}()
+	for len(primaryServer.Requests()) < 2 {
+		time.Sleep(time.Millisecond)
+	}
+	var res *http.Response
+	if _, err := client.Messages.New(context.Background(), params(), option.WithResponseInto(&res)); err != nil || router.ServedBy(res) != "secondary" {
+		t.Fatalf("expected the secondary to serve during the probe, got %q, %v", router.ServedBy(res), err)
+	}
+	if err := <-probed; err != nil {
+		t.Fatal(err)
+	}
+	if h := client.Health()[0]; h.State != router.CircuitClosed || h.ConsecutiveFailures != 0 {
+		t.Fatalf("expected the probe to close the primary's circuit, got %+v", h)
+	}
+
+	// A failed probe opens the circuit for another cooldown.
+	primaryServer.Fail(anthropictest.Overloaded())
+	if _, err := client.Messages.New(context.Background(), params()); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(cooldown)
+	primaryServer.Fail(anthropictest.Overloaded())
+	if _, err := client.Messages.New(context.Background(), params(), option.WithResponseInto(&res)); err != nil || router.ServedBy(res) != "secondary" {
+		t.Fatalf("got %q, %v", router.ServedBy(res), err)
+	}
+	if h := client.Health()[0]; h.State != router.CircuitOpen || h.ConsecutiveFailures != 2 {
+		t.Fatalf("expected the failed probe to reopen the primary's circuit, got %+v", h)
+	}
+	if _, err := client.Messages.New(context.Background(), params()); err != nil {
+		t.Fatal(err)
+	}
+	if len(primaryServer.Requests()) != 4 {
+		t.Errorf("expected the reopened primary to be skipped, got %d requests", len(primaryServer.Requests()))
+	}
+}
+
+func TestFailover_RequestErrorsAreReturned(t *testing.T) {
+	primary, primaryServer := newBackend(t, "primary", nil)
+	secondary, secondaryServer := newBackend(t, "secondary", nil)
+	client := router.NewClient([]router.Backend{primary, secondary}, router.ClientOptions{})
+
+	primaryServer.Fail(anthropictest.Error(http.StatusBadRequest, "invalid_request_error", "bad"))
+	_, err := client.Messages.New(context.Background(), params())
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected the 400 to be returned, got %v", err)
+	}
+	if router.ServedBy(apiErr.Response) != "primary" {
+		t.Errorf("expected the error response to name its backend, got %q", router.ServedBy(apiErr.Response))
+	}
+	if len(secondaryServer.Requests()) != 0 {
+		t.Error("expected no failover for a request error")
+	}
+	if client.Health()[0].ConsecutiveFailures != 0 {
+		t.Error("expected a request error not to count against the backend")
+	}
+}
+
+func TestFailover_AllBackendsFail(t *testing.T) {
+	primary, primaryServer := newBackend(t, "primary", nil)
+	secondary, secondaryServer := newBackend(t, "secondary", nil)
+	client := router.NewClient([]router.Backend{primary, secondary}, router.ClientOptions{})
+
+	primaryServer.Fail(anthropictest.RateLimited(time.Minute))
+	secondaryServer.Fail(anthropictest.Overloaded())
+	_, err := client.Messages.New(context.Background(), params())
+	var failover *router.FailoverError
+	if !errors.As(err, &failover) || len(failover.Attempts) != 2 || failover.Attempts[0].Backend != "primary" {
+		t.Fatalf("expected a failover error over both backends, got %v", err)
+	}
+	var apiErr *anthropic.Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != 529 {
+		t.Errorf("expected the last attempt's error, got %v", err)
+	}
+
+	// The 429 asked for a minute, which opens the circuit below the
+	// failure threshold.
+	if h := client.Health()[0]; h.State != router.CircuitOpen || time.Until(h.OpenUntil) < 50*time.Second {
+		t.Errorf("expected the rate-limited backend to be open for the retry-after, got %+v", h)
+	}
+}
+
+func TestFailover_Models(t *testing.T) {
+	bedrock, bedrockServer := newBackend(t, "bedrock", map[string]string{"claude-sonnet-4-5": "us.anthropic.claude-sonnet-4-5-20250929-v1:0"})
+	client := router.NewClient([]router.Backend{bedrock}, router.ClientOptions{})
+
+	p := params()
+	p.Model = "claude-opus-4-1"
+	_, err := client.Messages.New(context.Background(), p)
+	var failover *router.FailoverError
+	if !errors.As(err, &failover) || len(failover.Attempts) != 0 {
+		t.Fatalf("expected no backend to serve the model, got %v", err)
+	}
+	if len(bedrockServer.Requests()) != 0 {
+		t.Error("expected no request for an unmapped model")
+	}
+}
+
+func TestFailover_Streaming(t *testing.T) {
+	primary, primaryServer := newBackend(t, "primary", nil)
+	secondary, secondaryServer := newBackend(t, "secondary", nil)
+	client := router.NewClient([]router.Backend{primary, secondary}, router.ClientOptions{})
+
+	primaryServer.Fail(anthropictest.Overloaded())
+	secondaryServer.Reply(anthropictest.Text("streamed"))
+	var res *http.Response
+	stream := client.Messages.NewStreaming(context.Background(), params(), option.WithResponseInto(&res))
+	defer stream.Close()
+	var message anthropic.Message
+	for stream.Next() {
+		if err := message.Accumulate(stream.Current()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if message.Content[0].Text != "streamed" || router.ServedBy(res) != "secondary" {
+		t.Errorf("got %q from %q", message.Content[0].Text, router.ServedBy(res))
+	}
+}
+
+func TestFailover_CountTokens(t *testing.T) {
+	primary, primaryServer := newBackend(t, "primary", nil)
+	secondary, _ := newBackend(t, "secondary", nil)
+	client := router.NewClient([]router.Backend{primary, secondary}, router.ClientOptions{})
+
+	primaryServer.Fail(anthropictest.Disconnect())
+	count, err := client.Messages.CountTokens(context.Background(), anthropic.MessageCountTokensParams{
+		Model:    "claude-sonnet-4-5",
+		Messages: params().Messages,
+	})
+	if err != nil || count.InputTokens == 0 {
+		t.Fatalf("got %+v, %v", count, err)
+	}
+}
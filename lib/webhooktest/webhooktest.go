@@ -0,0 +1,304 @@
+// Package webhooktest produces signed webhook deliveries, for testing webhook
+// consumers without the live service. [Sign] builds a body and headers that
+// pass [anthropic.BetaWebhookService.Unwrap] verification, and an [Emitter]
+// delivers a sequence of events to a handler the way the service does: at
+// least once, retrying failed deliveries, optionally with injected faults.
+package webhooktest
+
+import (
+	"bytes"
+	"context"
+	crand "crypto/rand"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"time"
+
+	"github.com/tidwall/gjson"
+
+	standardwebhooks "github.com/standard-webhooks/standard-webhooks/libraries/go"
+)
+
+// NewKey returns a random webhook signing key, in the whsec_ form accepted by
+// [option.WithWebhookKey].
+//
+// [option.WithWebhookKey]: https://pkg.go.dev/github.com/anthropics/anthropic-sdk-go/option#WithWebhookKey
+func NewKey() string {
+	secret := make([]byte, 24)
+	crand.Read(secret)
+	return "whsec_" + base64.StdEncoding.EncodeToString(secret)
+}
+
+// Event is a webhook event to sign or emit.
+type Event struct {
+	// ID is the event's id. Defaults to a random whe_ ID.
+	ID string
+	// CreatedAt defaults to the current time.
+	CreatedAt time.Time
+	// Data is the event's payload: one of the variants of
+	// [anthropic.BetaWebhookEventDataUnion], such as
+	// [anthropic.BetaWebhookDeploymentRunFailedEventData], whose type field is
+	// set when it is encoded.
+	Data any
+}
+
+// Payload returns the JSON body of e, filling in a missing ID and CreatedAt.
+func (e Event) Payload() ([]byte, error) {
+	if e.ID == "" {
+		e.ID = randomID("whe_")
+	}
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = time.Now()
+	}
+	data, err := json.Marshal(e.Data)
+	if err != nil {
+		return nil, fmt.Errorf("webhooktest: encoding event data: %w", err)
+	}
+	if gjson.GetBytes(data, "type").String() == "" {
+		return nil, fmt.Errorf("webhooktest: event data %T has no type; use a BetaWebhook*EventData variant", e.Data)
+	}
+	return json.Marshal(struct {
+		ID        string          `json:"id"`
+		CreatedAt time.Time       `json:"created_at"`
+		Data      json.RawMessage `json:"data"`
+		Type      string          `json:"type"`
+	}{e.ID, e.CreatedAt.UTC(), data, "event"})
+}
+
+// Sign encodes event and signs it with key, returning the body and the
+// webhook-id, webhook-timestamp and webhook-signature headers of a delivery
+// that passes verification. The webhook-id is random.
+func Sign(key string, event Event) (body []byte, header http.Header, err error) {
+	body, err = event.Payload()
+	if err != nil {
+		return nil, nil, err
+	}
+	header, err = SignPayload(key, randomID("msg_"), time.Now(), body)
+	return body, header, err
+}
+
+// SignPayload returns the headers that sign payload as the delivery webhookID,
+// sent at timestamp.
+func SignPayload(key, webhookID string, timestamp time.Time, payload []byte) (http.Header, error) {
+	wh, err := standardwebhooks.NewWebhook(key)
+	if err != nil {
+		return nil, fmt.Errorf("webhooktest: %w", err)
+	}
+	sig, err := wh.Sign(webhookID, timestamp, payload)
+	if err != nil {
+		return nil, fmt.Errorf("webhooktest: %w", err)
+	}
+	header := http.Header{}
+	header.Set("Content-Type", "application/json")
+	header.Set("webhook-id", webhookID)
+	header.Set("webhook-timestamp", strconv.FormatInt(timestamp.Unix(), 10))
+	header.Set("webhook-signature", sig)
+	return header, nil
+}
+
+// Fault is a failure an [Emitter] injects into a delivery attempt.
+type Fault int
+
+const (
+	// NoFault delivers the attempt as is.
+	NoFault Fault = iota
+	// FaultDrop does not send the attempt, as if the connection failed.
+	FaultDrop
+	// FaultLoseResponse sends the attempt but treats it as failed whatever the
+	// response, as if the response was lost, so the event is redelivered.
+	FaultLoseResponse
+	// FaultDuplicate sends the attempt twice.
+	FaultDuplicate
+	// FaultBadSignature sends the attempt with a signature that does not
+	// verify.
+	FaultBadSignature
+	// FaultStaleTimestamp sends the attempt signed with a timestamp an hour in
+	// the past.
+	FaultStaleTimestamp
+)
+
+func (f Fault) String() string {
+	switch f {
+	case NoFault:
+		return "none"
+	case FaultDrop:
+		return "drop"
+	case FaultLoseResponse:
+		return "lose-response"
+	case FaultDuplicate:
+		return "duplicate"
+	case FaultBadSignature:
+		return "bad-signature"
+	case FaultStaleTimestamp:
+		return "stale-timestamp"
+	}
+	return "Fault(" + strconv.Itoa(int(f)) + ")"
+}
+
+// Attempt identifies one delivery attempt of an [Emitter].
+type Attempt struct {
+	// Index is the position of the event in the emitted sequence.
+	Index int
+	// Number counts the attempts at delivering the event, from 1.
+	Number    int
+	WebhookID string
+	Event     Event
+}
+
+// AttemptResult is the outcome of one delivery attempt.
+type AttemptResult struct {
+	Fault Fault
+	// StatusCode is the consumer's response status, or 0 if it was not sent or
+	// the request failed.
+	StatusCode int
+	// Err is the error of a request that failed.
+	Err error
+}
+
+// Result is the outcome of delivering one event.
+type Result struct {
+	WebhookID string
+	Event     Event
+	Attempts  []AttemptResult
+	// Delivered reports whether an attempt was acknowledged with a 2xx status.
+	Delivered bool
+}
+
+// Emitter delivers signed webhook events to a consumer in process, retrying
+// each until it is acknowledged with a 2xx status or MaxAttempts is reached.
+// Every attempt at an event carries the same webhook-id and a fresh
+// timestamp and signature, as the service's retries do.
+type Emitter struct {
+	// Key signs the deliveries.
+	Key string
+	// Handler receives the deliveries. If it is nil, they are POSTed to URL.
+	Handler http.Handler
+	URL     string
+	// Client sends the deliveries to URL. Defaults to [http.DefaultClient].
+	Client *http.Client
+	// MaxAttempts caps the attempts at delivering each event. Defaults to 3.
+	MaxAttempts int
+	// RetryDelay is the wait between attempts. It defaults to 0, retrying
+	// immediately.
+	RetryDelay time.Duration
+	// Inject, if set, chooses the fault to inject into each attempt.
+	Inject func(Attempt) Fault
+}
+
+// Emit delivers each event in turn and returns their results. It returns an
+// error, joined with any others, for each event that was not delivered.
+func (e *Emitter) Emit(ctx context.Context, events ...Event) ([]Result, error) {
+	maxAttempts := e.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = 3
+	}
+	results := make([]Result, len(events))
+	var errs []error
+	for i, event := range events {
+		if event.ID == "" {
+			event.ID = randomID("whe_")
+		}
+		if event.CreatedAt.IsZero() {
+			event.CreatedAt = time.Now()
+		}
+		payload, err := event.Payload()
+		if err != nil {
+			return results[:i], err
+		}
+		res := Result{WebhookID: randomID("msg_"), Event: event}
+		for n := 1; n <= maxAttempts && !res.Delivered; n++ {
+			if n > 1 && e.RetryDelay > 0 {
+				timer := time.NewTimer(e.RetryDelay)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return results[:i], ctx.Err()
+				case <-timer.C:
+				}
+			}
+			fault := NoFault
+			if e.Inject != nil {
+				fault = e.Inject(Attempt{Index: i, Number: n, WebhookID: res.WebhookID, Event: event})
+			}
+			attempt, err := e.attempt(ctx, res.WebhookID, payload, fault)
+			if err != nil {
+				return results[:i], err
+			}
+			res.Attempts = append(res.Attempts, attempt)
+			res.Delivered = fault != FaultLoseResponse && attempt.StatusCode >= 200 && attempt.StatusCode < 300
+		}
+		if !res.Delivered {
+			last := res.Attempts[len(res.Attempts)-1]
+			errs = append(errs, fmt.Errorf("webhooktest: event %s (%s) not delivered after %d attempts: last status %d, error %v",
+				event.ID, res.WebhookID, len(res.Attempts), last.StatusCode, last.Err))
+		}
+		results[i] = res
+	}
+	return results, errors.Join(errs...)
+}
+
+// attempt sends one attempt at a delivery with fault injected. It returns an
+// error only if the attempt could not be built.
+func (e *Emitter) attempt(ctx context.Context, webhookID string, payload []byte, fault Fault) (AttemptResult, error) {
+	res := AttemptResult{Fault: fault}
+	if fault == FaultDrop {
+		return res, nil
+	}
+	timestamp := time.Now()
+	if fault == FaultStaleTimestamp {
+		timestamp = timestamp.Add(-time.Hour)
+	}
+	header, err := SignPayload(e.Key, webhookID, timestamp, payload)
+	if err != nil {
+		return res, err
+	}
+	if fault == FaultBadSignature {
+		header.Set("webhook-signature", "v1,"+base64.StdEncoding.EncodeToString(make([]byte, 32)))
+	}
+	sends := 1
+	if fault == FaultDuplicate {
+		sends = 2
+	}
+	for range sends {
+		res.StatusCode, res.Err = e.send(ctx, header, payload)
+	}
+	return res, nil
+}
+
+func (e *Emitter) send(ctx context.Context, header http.Header, payload []byte) (int, error) {
+	if e.Handler != nil {
+		req := httptest.NewRequestWithContext(ctx, http.MethodPost, "/", bytes.NewReader(payload))
+		req.Header = header.Clone()
+		rec := httptest.NewRecorder()
+		e.Handler.ServeHTTP(rec, req)
+		return rec.Code, nil
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, e.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header = header.Clone()
+	client := e.Client
+	if client == nil {
+		client = http.DefaultClient
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func randomID(prefix string) string {
+	b := make([]byte, 12)
+	crand.Read(b)
+	return prefix + hex.EncodeToString(b)
+}
@@ -0,0 +1,143 @@
+package webhooktest_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/lib/webhooktest"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+func TestSignPassesUnwrap(t *testing.T) {
+	key := webhooktest.NewKey()
+	client := anthropic.NewClient(option.WithWebhookKey(key), option.WithAPIKey("my-anthropic-api-key"))
+
+	created := time.Date(2026, 3, 15, 10, 0, 0, 0, time.UTC)
+	body, header, err := webhooktest.Sign(key, webhooktest.Event{
+		ID:        "whe_01",
+		CreatedAt: created,
+		Data:      anthropic.BetaWebhookDeploymentRunFailedEventData{ID: "drun_01", OrganizationID: "org_01", WorkspaceID: "wrkspc_01"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	event, err := client.Beta.Webhooks.Unwrap(body, header)
+	if err != nil {
+		t.Fatalf("Unwrap: %v", err)
+	}
+	if event.ID != "whe_01" || !event.CreatedAt.Equal(created) {
+		t.Errorf("unexpected event envelope: %s %s", event.ID, event.CreatedAt)
+	}
+	data, ok := event.Data.AsAny().(anthropic.BetaWebhookDeploymentRunFailedEventData)
+	if !ok || data.ID != "drun_01" || data.WorkspaceID != "wrkspc_01" {
+		t.Errorf("unexpected event data: %+v", event.Data)
+	}
+
+	other := anthropic.NewClient(option.WithWebhookKey(webhooktest.NewKey()), option.WithAPIKey("my-anthropic-api-key"))
+	if _, err := other.Beta.Webhooks.Unwrap(body, header); err == nil {
+		t.Error("expected a delivery signed with another key to fail verification")
+	}
+
+	if _, _, err := webhooktest.Sign(key, webhooktest.Event{Data: map[string]string{"id": "x"}}); err == nil {
+		t.Error("expected data without a type to be rejected")
+	}
+}
+
+// consumer is a webhook consumer built on BetaWebhookHandler that records the
+// IDs of the events it handled.
+func consumer(t *testing.T, key string) (http.Handler, func() []string) {
+	client := anthropic.NewClient(option.WithWebhookKey(key), option.WithAPIKey("my-anthropic-api-key"))
+	var (
+		mu      sync.Mutex
+		handled []string
+	)
+	handler, err := client.Beta.Webhooks.NewHandler(anthropic.BetaWebhookHandlerParams{
+		DedupeStore: anthropic.NewMemoryWebhookDedupeStore(time.Hour),
+		OnEvent: func(ctx context.Context, event *anthropic.UnwrapWebhookEvent) error {
+			mu.Lock()
+			defer mu.Unlock()
+			handled = append(handled, event.ID)
+			return nil
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return handler, func() []string {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), handled...)
+	}
+}
+
+func TestEmitterRetriesAndFaults(t *testing.T) {
+	key := webhooktest.NewKey()
+	handler, handled := consumer(t, key)
+
+	faults := map[int]webhooktest.Fault{
+		0: webhooktest.FaultLoseResponse,
+		1: webhooktest.FaultBadSignature,
+		2: webhooktest.FaultStaleTimestamp,
+		3: webhooktest.FaultDuplicate,
+		4: webhooktest.FaultDrop,
+	}
+	emitter := &webhooktest.Emitter{
+		Key:     key,
+		Handler: handler,
+		Inject: func(a webhooktest.Attempt) webhooktest.Fault {
+			if a.Number == 1 {
+				return faults[a.Index]
+			}
+			return webhooktest.NoFault
+		},
+	}
+	var events []webhooktest.Event
+	for _, id := range []string{"whe_0", "whe_1", "whe_2", "whe_3", "whe_4", "whe_5"} {
+		events = append(events, webhooktest.Event{ID: id, Data: anthropic.BetaWebhookSessionStatusIdledEventData{ID: "sesn_01"}})
+	}
+	results, err := emitter.Emit(context.Background(), events...)
+	if err != nil {
+		t.Fatalf("Emit: %v", err)
+	}
+
+	wantAttempts := []int{2, 2, 2, 1, 2, 1}
+	wantFirstStatus := []int{http.StatusNoContent, http.StatusUnauthorized, http.StatusUnauthorized, http.StatusNoContent, 0, http.StatusNoContent}
+	for i, res := range results {
+		if !res.Delivered || len(res.Attempts) != wantAttempts[i] {
+			t.Errorf("event %d: expected delivery after %d attempts, got delivered=%v attempts=%+v", i, wantAttempts[i], res.Delivered, res.Attempts)
+			continue
+		}
+		if got := res.Attempts[0].StatusCode; got != wantFirstStatus[i] {
+			t.Errorf("event %d: expected first attempt status %d, got %d", i, wantFirstStatus[i], got)
+		}
+	}
+	// The redeliveries of the lost response and the duplicate are deduplicated.
+	if got := handled(); len(got) != len(events) {
+		t.Errorf("expected each event to be handled once, got %v", got)
+	}
+}
+
+func TestEmitterReportsUndelivered(t *testing.T) {
+	key := webhooktest.NewKey()
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	emitter := &webhooktest.Emitter{Key: key, URL: server.URL, MaxAttempts: 4, RetryDelay: time.Millisecond}
+	results, err := emitter.Emit(context.Background(), webhooktest.Event{Data: anthropic.BetaWebhookVaultCreatedEventData{ID: "vlt_01"}})
+	if err == nil {
+		t.Fatal("expected an error for the undelivered event")
+	}
+	if len(results) != 1 || results[0].Delivered || len(results[0].Attempts) != 4 {
+		t.Fatalf("expected 4 failed attempts, got %+v", results)
+	}
+	if results[0].Attempts[3].StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("expected the consumer's status to be recorded, got %d", results[0].Attempts[3].StatusCode)
+	}
+}
@@ -0,0 +1,286 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"iter"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+)
+
+// Limits the API places on a single Message Batch.
+const (
+	MessageBatchMaxRequests = 100_000
+	MessageBatchMaxBytes    = 256 << 20
+)
+
+// Default polling intervals of a batch job.
+const (
+	DefaultBatchPollInterval    = 5 * time.Second
+	DefaultBatchMaxPollInterval = time.Minute
+)
+
+// batchRequestOverhead approximates the JSON that wraps a request's params in
+// the batch body: its custom_id key, the params key, braces and a comma.
+const batchRequestOverhead = 32
+
+// MessageBatchJobParams configures how a batch job splits its requests into
+// batches and polls them.
+type MessageBatchJobParams struct {
+	// MaxRequestsPerBatch caps the number of requests in each batch. Defaults to
+	// [MessageBatchMaxRequests].
+	MaxRequestsPerBatch int
+	// MaxBytesPerBatch caps the encoded size of each batch. Defaults to
+	// [MessageBatchMaxBytes].
+	MaxBytesPerBatch int
+	// PollInterval is the wait before a batch that is still processing is
+	// checked again. It doubles after each check, up to MaxPollInterval.
+	// Defaults to [DefaultBatchPollInterval] and [DefaultBatchMaxPollInterval].
+	PollInterval    time.Duration
+	MaxPollInterval time.Duration
+}
+
+func (p MessageBatchJobParams) withDefaults() MessageBatchJobParams {
+	if p.MaxRequestsPerBatch <= 0 {
+		p.MaxRequestsPerBatch = MessageBatchMaxRequests
+	}
+	if p.MaxBytesPerBatch <= 0 {
+		p.MaxBytesPerBatch = MessageBatchMaxBytes
+	}
+	if p.PollInterval <= 0 {
+		p.PollInterval = DefaultBatchPollInterval
+	}
+	if p.MaxPollInterval <= 0 {
+		p.MaxPollInterval = DefaultBatchMaxPollInterval
+	}
+	return p
+}
+
+// MessageBatchJobRequest is one request of a [MessageBatchJob].
+type MessageBatchJobRequest struct {
+	// CustomID identifies the request's result. It must be unique within the
+	// job.
+	CustomID string
+	Params   MessageNewParams
+}
+
+// MessageBatchJobResult is the outcome of one request of a [MessageBatchJob].
+type MessageBatchJobResult struct {
+	CustomID string
+	// BatchID is the ID of the batch the request was submitted in.
+	BatchID string
+	// Request is the request as submitted. For a job resumed without its
+	// requests, it holds only the CustomID.
+	Request MessageBatchJobRequest
+	// Result is the outcome of the request. Switch on Result.AsAny() for the
+	// [MessageBatchSucceededResult], [MessageBatchErroredResult],
+	// [MessageBatchCanceledResult] or [MessageBatchExpiredResult].
+	Result MessageBatchResultUnion
+}
+
+// MessageBatchJob is a set of requests submitted as one or more Message
+// Batches. Create one with [MessageBatchService.Submit], or re-attach to the
+// batches of an earlier job with [MessageBatchService.ResumeJob].
+type MessageBatchJob struct {
+	service  *MessageBatchService
+	params   MessageBatchJobParams
+	opts     []option.RequestOption
+	batchIDs []string
+	requests map[string]MessageBatchJobRequest
+}
+
+// Submit creates batches for requests, splitting them across as many batches
+// as the request count and size limits in params require, and returns the job
+// that tracks them. Pass a slice of requests with [slices.Values].
+//
+//	job, err := client.Messages.Batches.Submit(ctx, slices.Values(requests), anthropic.MessageBatchJobParams{})
+//	if err != nil { ... }
+//	for result, err := range job.Results(ctx) {
+//		if err != nil { ... }
+//		switch r := result.Result.AsAny().(type) {
+//		case anthropic.MessageBatchSucceededResult:
+//			fmt.Println(result.CustomID, r.Message.Content[0].Text)
+//		case anthropic.MessageBatchErroredResult:
+//			fmt.Println(result.CustomID, r.Error.Error.Message)
+//		}
+//	}
+//
+// If creating a batch fails after others were created, the job is returned
+// alongside the error; its BatchIDs can be used to resume or cancel it.
+func (r *MessageBatchService) Submit(ctx context.Context, requests iter.Seq[MessageBatchJobRequest], params MessageBatchJobParams, opts ...option.RequestOption) (*MessageBatchJob, error) {
+	job := r.newJob(nil, params, opts)
+
+	var (
+		pending []MessageBatchNewParamsRequest
+		size    int
+	)
+	flush := func() error {
+		if len(pending) == 0 {
+			return nil
+		}
+		batch, err := r.New(ctx, MessageBatchNewParams{Requests: pending}, opts...)
+		if err != nil {
+			return err
+		}
+		job.batchIDs = append(job.batchIDs, batch.ID)
+		pending, size = nil, 0
+		return nil
+	}
+	for req := range requests {
+		if _, dup := job.requests[req.CustomID]; dup {
+			return job.orNil(), fmt.Errorf("anthropic: duplicate batch request custom_id %q", req.CustomID)
+		}
+		raw, err := json.Marshal(req.Params)
+		if err != nil {
+			return job.orNil(), fmt.Errorf("anthropic: encoding batch request %q: %w", req.CustomID, err)
+		}
+		n := len(raw) + len(req.CustomID) + batchRequestOverhead
+		if n > job.params.MaxBytesPerBatch {
+			return job.orNil(), fmt.Errorf("anthropic: batch request %q is %d bytes, more than the %d allowed per batch", req.CustomID, n, job.params.MaxBytesPerBatch)
+		}
+		if len(pending) == job.params.MaxRequestsPerBatch || size+n > job.params.MaxBytesPerBatch {
+			if err := flush(); err != nil {
+				return job.orNil(), err
+			}
+		}
+		job.requests[req.CustomID] = req
+		pending = append(pending, MessageBatchNewParamsRequest{
+			CustomID: req.CustomID,
+			Params:   param.Override[MessageBatchNewParamsRequestParams](json.RawMessage(raw)),
+		})
+		size += n
+	}
+	if err := flush(); err != nil {
+		return job.orNil(), err
+	}
+	return job, nil
+}
+
+// ResumeJob returns the job made up of existing batches, such as those of a
+// job submitted by an earlier process. requests, if not nil, are the requests
+// the batches were submitted with, and are joined to the results by custom ID.
+func (r *MessageBatchService) ResumeJob(batchIDs []string, requests iter.Seq[MessageBatchJobRequest], params MessageBatchJobParams, opts ...option.RequestOption) *MessageBatchJob {
+	job := r.newJob(batchIDs, params, opts)
+	if requests != nil {
+		for req := range requests {
+			job.requests[req.CustomID] = req
+		}
+	}
+	return job
+}
+
+func (r *MessageBatchService) newJob(batchIDs []string, params MessageBatchJobParams, opts []option.RequestOption) *MessageBatchJob {
+	return &MessageBatchJob{
+		service:  r,
+		params:   params.withDefaults(),
+		opts:     opts,
+		batchIDs: append([]string(nil), batchIDs...),
+		requests: map[string]MessageBatchJobRequest{},
+	}
+}
+
+// orNil returns j if it has created any batches.
+func (j *MessageBatchJob) orNil() *MessageBatchJob {
+	if len(j.batchIDs) == 0 {
+		return nil
+	}
+	return j
+}
+
+// BatchIDs returns the IDs of the job's batches. Persist them to re-attach to
+// the job with [MessageBatchService.ResumeJob].
+func (j *MessageBatchJob) BatchIDs() []string {
+	return append([]string(nil), j.batchIDs...)
+}
+
+// Wait polls the job's batches until every one has ended and returns them.
+func (j *MessageBatchJob) Wait(ctx context.Context) ([]*MessageBatch, error) {
+	batches := make([]*MessageBatch, 0, len(j.batchIDs))
+	for _, id := range j.batchIDs {
+		batch, err := j.wait(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		batches = append(batches, batch)
+	}
+	return batches, nil
+}
+
+func (j *MessageBatchJob) wait(ctx context.Context, batchID string) (batch *MessageBatch, err error) {
+	err = pollBatch(ctx, j.params.PollInterval, j.params.MaxPollInterval, func() (bool, error) {
+		batch, err = j.service.Get(ctx, batchID, j.opts...)
+		if err != nil {
+			return false, err
+		}
+		return batch.ProcessingStatus == MessageBatchProcessingStatusEnded, nil
+	})
+	return batch, err
+}
+
+// Results waits for each of the job's batches to end in turn and yields the
+// results of its requests, joined to the requests they answer. Results within
+// a batch are not in request order. Iteration stops at the first error, which
+// is yielded.
+func (j *MessageBatchJob) Results(ctx context.Context) iter.Seq2[MessageBatchJobResult, error] {
+	return func(yield func(MessageBatchJobResult, error) bool) {
+		for _, id := range j.batchIDs {
+			if _, err := j.wait(ctx, id); err != nil {
+				yield(MessageBatchJobResult{BatchID: id}, err)
+				return
+			}
+			stream := j.service.ResultsStreaming(ctx, id, j.opts...)
+			for stream.Next() {
+				res := stream.Current()
+				req, ok := j.requests[res.CustomID]
+				if !ok {
+					req = MessageBatchJobRequest{CustomID: res.CustomID}
+				}
+				if !yield(MessageBatchJobResult{CustomID: res.CustomID, BatchID: id, Request: req, Result: res.Result}, nil) {
+					stream.Close()
+					return
+				}
+			}
+			err := stream.Err()
+			stream.Close()
+			if err != nil {
+				yield(MessageBatchJobResult{BatchID: id}, err)
+				return
+			}
+		}
+	}
+}
+
+// Cancel cancels every batch of the job. Requests that have not yet been
+// processed end with a canceled result.
+func (j *MessageBatchJob) Cancel(ctx context.Context) error {
+	var errs []error
+	for _, id := range j.batchIDs {
+		if _, err := j.service.Cancel(ctx, id, j.opts...); err != nil {
+			errs = append(errs, fmt.Errorf("cancel batch %s: %w", id, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// pollBatch calls done until it reports true, waiting interval after the
+// first call and doubling the wait after each later one, up to maxInterval.
+func pollBatch(ctx context.Context, interval, maxInterval time.Duration, done func() (bool, error)) error {
+	for {
+		ok, err := done()
+		if err != nil || ok {
+			return err
+		}
+		timer := time.NewTimer(interval)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+		interval = min(interval*2, maxInterval)
+	}
+}
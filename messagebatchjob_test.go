@@ -0,0 +1,322 @@
+package anthropic_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/tidwall/gjson"
+)
+
+// fakeBatches serves the Message Batches endpoints. Each batch reports
+// in_progress for its first polls, then ended, and answers each request with a
+// message whose text is the request's first user message; requests whose text
+// is "fail" error instead.
+type fakeBatches struct {
+	*httptest.Server
+	t          *testing.T
+	pollsUntil int
+
+	mu       sync.Mutex
+	batches  map[string][]gjson.Result
+	polls    map[string]int
+	order    []string
+	canceled []string
+	betas    []string
+}
+
+func newFakeBatches(t *testing.T, pollsUntil int) *fakeBatches {
+	f := &fakeBatches{t: t, pollsUntil: pollsUntil, batches: map[string][]gjson.Result{}, polls: map[string]int{}}
+	f.Server = httptest.NewServer(http.HandlerFunc(f.serve))
+	t.Cleanup(f.Close)
+	return f
+}
+
+func (f *fakeBatches) serve(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.betas = append(f.betas, strings.Join(r.Header.Values("anthropic-beta"), ","))
+	path := strings.TrimPrefix(r.URL.Path, "/v1/messages/batches")
+	switch {
+	case r.Method == http.MethodPost && path == "":
+		body, _ := io.ReadAll(r.Body)
+		id := fmt.Sprintf("msgbatch_%d", len(f.order)+1)
+		f.order = append(f.order, id)
+		f.batches[id] = gjson.GetBytes(body, "requests").Array()
+		f.writeBatch(w, id, "in_progress")
+	case r.Method == http.MethodPost && strings.HasSuffix(path, "/cancel"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/cancel")
+		f.canceled = append(f.canceled, id)
+		f.writeBatch(w, id, "canceling")
+	case r.Method == http.MethodGet && strings.HasSuffix(path, "/results"):
+		id := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/results")
+		w.Header().Set("Content-Type", "application/x-jsonl")
+		for _, req := range f.batches[id] {
+			customID := req.Get("custom_id").String()
+			text := req.Get("params.messages.0.content.0.text").String()
+			if text == "fail" {
+				fmt.Fprintf(w, `{"custom_id":%q,"result":{"type":"errored","error":{"type":"error","error":{"type":"invalid_request_error","message":"bad"}}}}`+"\n", customID)
+				continue
+			}
+			fmt.Fprintf(w, `{"custom_id":%q,"result":{"type":"succeeded","message":{"id":"msg_%s","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"end_turn","content":[{"type":"text","text":%q}],"usage":{"input_tokens":1,"output_tokens":1}}}}`+"\n", customID, customID, text)
+		}
+	case r.Method == http.MethodGet:
+		id := strings.TrimPrefix(path, "/")
+		f.polls[id]++
+		status := "in_progress"
+		if f.polls[id] > f.pollsUntil {
+			status = "ended"
+		}
+		f.writeBatch(w, id, status)
+	default:
+		f.t.Errorf("unexpected request %s %s", r.Method, r.URL)
+		w.WriteHeader(http.StatusNotFound)
+	}
+}
+
+func (f *fakeBatches) writeBatch(w http.ResponseWriter, id, status string) {
+	if _, ok := f.batches[id]; !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	fmt.Fprintf(w, `{"id":%q,"type":"message_batch","processing_status":%q,"request_counts":{"processing":%d}}`, id, status, len(f.batches[id]))
+}
+
+func batchJobRequests(texts ...string) []anthropic.MessageBatchJobRequest {
+	var reqs []anthropic.MessageBatchJobRequest
+	for i, text := range texts {
+		reqs = append(reqs, anthropic.MessageBatchJobRequest{
+			CustomID: fmt.Sprintf("req-%d", i),
+			Params: anthropic.MessageNewParams{
+				Model:     anthropic.ModelClaudeSonnet4_5,
+				MaxTokens: 64,
+				Messages:  []anthropic.MessageParam{{Role: anthropic.MessageParamRoleUser, Content: []anthropic.ContentBlockParamUnion{anthropic.NewTextBlock(text)}}},
+			},
+		})
+	}
+	return reqs
+}
+
+func newBatchClient(f *fakeBatches) anthropic.Client {
+	return anthropic.NewClient(
+		option.WithBaseURL(f.URL),
+		option.WithAPIKey("test-key"),
+		option.WithMaxRetries(0),
+	)
+}
+
+var fastPolling = anthropic.MessageBatchJobParams{PollInterval: time.Millisecond, MaxPollInterval: 2 * time.Millisecond}
+
+func TestMessageBatchJob(t *testing.T) {
+	f := newFakeBatches(t, 2)
+	client := newBatchClient(f)
+
+	params := fastPolling
+	params.MaxRequestsPerBatch = 2
+	job, err := client.Messages.Batches.Submit(context.Background(), slices.Values(batchJobRequests("a", "b", "fail", "d", "e")), params)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if ids := job.BatchIDs(); !slices.Equal(ids, []string{"msgbatch_1", "msgbatch_2", "msgbatch_3"}) {
+		t.Fatalf("expected the requests to be split into 3 batches, got %v", ids)
+	}
+	if got := len(f.batches["msgbatch_3"]); got != 1 {
+		t.Errorf("expected the last batch to hold 1 request, got %d", got)
+	}
+	if model := f.batches["msgbatch_1"][0].Get("params.model").String(); model != "claude-sonnet-4-5" {
+		t.Errorf("expected the request params to be sent, got model %q", model)
+	}
+
+	results := map[string]anthropic.MessageBatchJobResult{}
+	for result, err := range job.Results(context.Background()) {
+		if err != nil {
+			t.Fatalf("Results: %v", err)
+		}
+		results[result.CustomID] = result
+	}
+	if len(results) != 5 {
+		t.Fatalf("expected 5 results, got %d", len(results))
+	}
+	for id, result := range results {
+		switch r := result.Result.AsAny().(type) {
+		case anthropic.MessageBatchSucceededResult:
+			if want := result.Request.Params.Messages[0].Content[0].OfText.Text; r.Message.Content[0].Text != want {
+				t.Errorf("%s: expected the result to be joined to its request %q, got %q", id, want, r.Message.Content[0].Text)
+			}
+		case anthropic.MessageBatchErroredResult:
+			if id != "req-2" || r.Error.Error.Message != "bad" {
+				t.Errorf("%s: unexpected errored result %+v", id, r)
+			}
+		default:
+			t.Errorf("%s: unexpected result type %q", id, result.Result.Type)
+		}
+	}
+	for _, id := range job.BatchIDs() {
+		if f.polls[id] != 3 {
+			t.Errorf("expected %s to be polled until ended, got %d polls", id, f.polls[id])
+		}
+	}
+}
+
+func TestMessageBatchJobSplitsBySize(t *testing.T) {
+	f := newFakeBatches(t, 0)
+	client := newBatchClient(f)
+
+	params := fastPolling
+	params.MaxBytesPerBatch = 400
+	job, err := client.Messages.Batches.Submit(context.Background(), slices.Values(batchJobRequests("a", "b", "c")), params)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if n := len(job.BatchIDs()); n < 2 {
+		t.Errorf("expected the size limit to split the requests, got %d batches", n)
+	}
+
+	params.MaxBytesPerBatch = 10
+	if _, err := client.Messages.Batches.Submit(context.Background(), slices.Values(batchJobRequests("a")), params); err == nil {
+		t.Error("expected an error for a request larger than the batch size limit")
+	}
+}
+
+func TestMessageBatchJobDuplicateCustomID(t *testing.T) {
+	f := newFakeBatches(t, 0)
+	client := newBatchClient(f)
+
+	reqs := append(batchJobRequests("a"), batchJobRequests("b")...)
+	job, err := client.Messages.Batches.Submit(context.Background(), slices.Values(reqs), fastPolling)
+	if err == nil || job != nil {
+		t.Fatalf("expected a duplicate custom_id error before any batch is created, got %v, %v", job, err)
+	}
+	if len(f.order) != 0 {
+		t.Errorf("expected no batch to be created, got %v", f.order)
+	}
+}
+
+func TestMessageBatchJobResume(t *testing.T) {
+	f := newFakeBatches(t, 1)
+	client := newBatchClient(f)
+
+	reqs := batchJobRequests("a", "b", "c")
+	params := fastPolling
+	params.MaxRequestsPerBatch = 2
+	job, err := client.Messages.Batches.Submit(context.Background(), slices.Values(reqs), params)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+
+	resumed := client.Messages.Batches.ResumeJob(job.BatchIDs(), slices.Values(reqs), params)
+	batches, err := resumed.Wait(context.Background())
+	if err != nil {
+		t.Fatalf("Wait: %v", err)
+	}
+	for _, batch := range batches {
+		if batch.ProcessingStatus != anthropic.MessageBatchProcessingStatusEnded {
+			t.Errorf("expected %s to have ended, got %s", batch.ID, batch.ProcessingStatus)
+		}
+	}
+	var ids []string
+	for result, err := range resumed.Results(context.Background()) {
+		if err != nil {
+			t.Fatalf("Results: %v", err)
+		}
+		if result.Request.Params.Model != anthropic.ModelClaudeSonnet4_5 {
+			t.Errorf("expected the resumed job to join results to the given requests, got %+v", result.Request)
+		}
+		ids = append(ids, result.CustomID)
+	}
+	slices.Sort(ids)
+	if !slices.Equal(ids, []string{"req-0", "req-1", "req-2"}) {
+		t.Errorf("unexpected results %v", ids)
+	}
+
+	// Without the requests, results still carry their custom IDs.
+	for result, err := range client.Messages.Batches.ResumeJob([]string{"msgbatch_2"}, nil, params).Results(context.Background()) {
+		if err != nil {
+			t.Fatalf("Results: %v", err)
+		}
+		if result.CustomID != "req-2" || result.Request.CustomID != "req-2" || len(result.Request.Params.Messages) != 0 {
+			t.Errorf("unexpected result %+v", result)
+		}
+	}
+
+	if err := resumed.Cancel(context.Background()); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if !slices.Equal(f.canceled, job.BatchIDs()) {
+		t.Errorf("expected every batch to be canceled, got %v", f.canceled)
+	}
+}
+
+func TestBetaMessageBatchJob(t *testing.T) {
+	f := newFakeBatches(t, 1)
+	client := newBatchClient(f)
+
+	var reqs []anthropic.BetaMessageBatchJobRequest
+	for i, text := range []string{"x", "y", "z"} {
+		reqs = append(reqs, anthropic.BetaMessageBatchJobRequest{
+			CustomID: fmt.Sprintf("beta-%d", i),
+			Params: anthropic.BetaMessageNewParams{
+				Model:     anthropic.ModelClaudeSonnet4_5,
+				MaxTokens: 64,
+				Messages:  []anthropic.BetaMessageParam{{Role: anthropic.BetaMessageParamRoleUser, Content: []anthropic.BetaContentBlockParamUnion{anthropic.NewBetaTextBlock(text)}}},
+			},
+		})
+	}
+	params := anthropic.BetaMessageBatchJobParams{MessageBatchJobParams: fastPolling, Betas: []anthropic.AnthropicBeta{"test-beta"}}
+	params.MaxRequestsPerBatch = 2
+	job, err := client.Beta.Messages.Batches.Submit(context.Background(), slices.Values(reqs), params)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	if n := len(job.BatchIDs()); n != 2 {
+		t.Fatalf("expected 2 batches, got %d", n)
+	}
+	texts := map[string]string{}
+	for result, err := range job.Results(context.Background()) {
+		if err != nil {
+			t.Fatalf("Results: %v", err)
+		}
+		texts[result.CustomID] = result.Result.AsSucceeded().Message.Content[0].Text
+		if result.Request.Params.MaxTokens != 64 {
+			t.Errorf("expected the result to be joined to its request, got %+v", result.Request)
+		}
+	}
+	if want := map[string]string{"beta-0": "x", "beta-1": "y", "beta-2": "z"}; fmt.Sprint(texts) != fmt.Sprint(want) {
+		t.Errorf("expected results %v, got %v", want, texts)
+	}
+	for _, betas := range f.betas {
+		if !strings.Contains(betas, "test-beta") {
+			t.Errorf("expected every request to carry the job's betas, got %q", betas)
+		}
+	}
+}
+
+func TestMessageBatchJobContextCanceled(t *testing.T) {
+	f := newFakeBatches(t, 1000)
+	client := newBatchClient(f)
+
+	job, err := client.Messages.Batches.Submit(context.Background(), slices.Values(batchJobRequests("a")), fastPolling)
+	if err != nil {
+		t.Fatalf("Submit: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if _, err := job.Wait(ctx); err == nil {
+		t.Fatal("expected Wait to stop when the context is done")
+	}
+	raw, _ := json.Marshal(job.BatchIDs())
+	if string(raw) != `["msgbatch_1"]` {
+		t.Errorf("unexpected batch IDs %s", raw)
+	}
+}
@@ -0,0 +1,186 @@
+package anthropic
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/anthropics/anthropic-sdk-go/packages/partialjson"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+)
+
+// MessageStreamHandlers are the callbacks of a [MessageStream]. Every field is
+// optional. Each callback is called once the event has been accumulated, so
+// snapshots include it. If a callback returns an error, the stream stops and
+// reports that error.
+type MessageStreamHandlers struct {
+	// OnEvent is called with every event, before the more specific callbacks.
+	OnEvent func(event MessageStreamEventUnion, snapshot *Message) error
+	// OnText is called with each piece of text, and the text of its content
+	// block so far.
+	OnText func(delta, snapshot string) error
+	// OnThinking is called with each piece of extended thinking, and the
+	// thinking of its content block so far.
+	OnThinking func(delta, snapshot string) error
+	// OnToolUseInput is called as the input of a tool use block streams in.
+	// partial is the input received so far, completed into a valid JSON
+	// document; see [ContentBlockUnion.ParsePartialInput].
+	OnToolUseInput func(partial json.RawMessage, block ContentBlockUnion) error
+	// OnCitation is called with each citation of a text block, and the
+	// citations of that block so far.
+	OnCitation func(citation TextCitationUnion, citations []TextCitationUnion) error
+	// OnContentBlockDone is called with each content block once it is
+	// complete.
+	OnContentBlockDone func(block ContentBlockUnion) error
+	// OnMessageDone is called with the complete message at the end of the
+	// stream.
+	OnMessageDone func(final *Message) error
+}
+
+// MessageStream wraps a stream from [MessageService.NewStreaming],
+// accumulating its events into a [Message] and calling the
+// [MessageStreamHandlers] as they arrive.
+//
+//	stream := anthropic.NewMessageStream(client.Messages.NewStreaming(ctx, params), anthropic.MessageStreamHandlers{
+//		OnText: func(delta, _ string) error {
+//			fmt.Print(delta)
+//			return nil
+//		},
+//	})
+//	message, err := stream.FinalMessage()
+//
+// A MessageStream can also be iterated like the stream it wraps, with Next,
+// Current and Err.
+type MessageStream struct {
+	stream   *ssestream.Stream[MessageStreamEventUnion]
+	handlers MessageStreamHandlers
+	snapshot Message
+	done     bool
+	err      error
+}
+
+// NewMessageStream returns a [MessageStream] that reads the events of stream.
+func NewMessageStream(stream *ssestream.Stream[MessageStreamEventUnion], handlers MessageStreamHandlers) *MessageStream {
+	return &MessageStream{stream: stream, handlers: handlers}
+}
+
+// Next reads and accumulates the next event, calling the handlers for it. It
+// returns false when the stream has ended or failed.
+func (s *MessageStream) Next() bool {
+	if s.err != nil || !s.stream.Next() {
+		return false
+	}
+	if err := s.handle(s.stream.Current()); err != nil {
+		s.err = err
+		return false
+	}
+	return true
+}
+
+// Current returns the most recent event.
+func (s *MessageStream) Current() MessageStreamEventUnion {
+	return s.stream.Current()
+}
+
+// Err returns the error that ended the stream: that of the underlying stream,
+// an event that could not be accumulated, or a handler's.
+func (s *MessageStream) Err() error {
+	if s.err != nil {
+		return s.err
+	}
+	return s.stream.Err()
+}
+
+// Close closes the underlying stream.
+func (s *MessageStream) Close() error {
+	return s.stream.Close()
+}
+
+// Snapshot returns the message accumulated so far. It is updated in place as
+// the stream is read.
+func (s *MessageStream) Snapshot() *Message {
+	return &s.snapshot
+}
+
+// FinalMessage reads the rest of the stream and returns the complete message.
+// It fails if the stream ends before the message does.
+func (s *MessageStream) FinalMessage() (*Message, error) {
+	for s.Next() {
+	}
+	if err := s.Err(); err != nil {
+		return nil, err
+	}
+	if !s.done {
+		return nil, errors.New("message stream ended before the message was complete")
+	}
+	return &s.snapshot, nil
+}
+
+// FinalText reads the rest of the stream and returns the text of the
+// message's text blocks, concatenated.
+func (s *MessageStream) FinalText() (string, error) {
+	message, err := s.FinalMessage()
+	if err != nil {
+		return "", err
+	}
+	var text strings.Builder
+	for _, block := range message.Content {
+		if block.Type == "text" {
+			text.WriteString(block.Text)
+		}
+	}
+	return text.String(), nil
+}
+
+func (s *MessageStream) handle(event MessageStreamEventUnion) error {
+	if err := s.snapshot.Accumulate(event); err != nil {
+		return err
+	}
+	h := s.handlers
+	if h.OnEvent != nil {
+		if err := h.OnEvent(event, &s.snapshot); err != nil {
+			return err
+		}
+	}
+
+	switch event := event.AsAny().(type) {
+	case ContentBlockDeltaEvent:
+		block := s.snapshot.Content[event.Index]
+		switch delta := event.Delta.AsAny().(type) {
+		case TextDelta:
+			if h.OnText != nil {
+				return h.OnText(delta.Text, block.Text)
+			}
+		case ThinkingDelta:
+			if h.OnThinking != nil {
+				return h.OnThinking(delta.Thinking, block.Thinking)
+			}
+		case InputJSONDelta:
+			if h.OnToolUseInput != nil {
+				partial, err := partialjson.Complete(block.Input)
+				if err != nil {
+					return fmt.Errorf("parsing partial input of content block %d: %w", event.Index, err)
+				}
+				if partial == nil {
+					partial = []byte("{}")
+				}
+				return h.OnToolUseInput(partial, block)
+			}
+		case CitationsDelta:
+			if h.OnCitation != nil {
+				return h.OnCitation(block.Citations[len(block.Citations)-1], block.Citations)
+			}
+		}
+	case ContentBlockStopEvent:
+		if h.OnContentBlockDone != nil {
+			return h.OnContentBlockDone(s.snapshot.Content[event.Index])
+		}
+	case MessageStopEvent:
+		s.done = true
+		if h.OnMessageDone != nil {
+			return h.OnMessageDone(&s.snapshot)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,205 @@
+package anthropic_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/lib/anthropictest"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+)
+
+func streamParams() anthropic.MessageNewParams {
+	return anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaudeSonnet4_5,
+		MaxTokens: 1024,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("What's the weather?"))},
+	}
+}
+
+func TestMessageStreamHandlers(t *testing.T) {
+	server := anthropictest.NewServer(t)
+	server.Reply(anthropictest.Message(
+		anthropictest.ThinkingBlock("The user wants the weather in Paris.", "sig"),
+		anthropictest.TextBlock("Let me check the forecast for you."),
+		anthropictest.ToolUseBlock("get_weather", map[string]any{"city": "Paris", "units": "celsius"}),
+	))
+	client := anthropic.NewClient(server.Options()...)
+
+	var text, thinking strings.Builder
+	var lastText string
+	var partials []string
+	var done []string
+	var final *anthropic.Message
+	stream := anthropic.NewMessageStream(client.Messages.NewStreaming(context.Background(), streamParams()), anthropic.MessageStreamHandlers{
+		OnText: func(delta, snapshot string) error {
+			text.WriteString(delta)
+			if snapshot != text.String() {
+				t.Errorf("expected the snapshot %q to hold the text so far %q", snapshot, text.String())
+			}
+			lastText = snapshot
+			return nil
+		},
+		OnThinking: func(delta, snapshot string) error {
+			thinking.WriteString(delta)
+			return nil
+		},
+		OnToolUseInput: func(partial json.RawMessage, block anthropic.ContentBlockUnion) error {
+			if !json.Valid(partial) {
+				t.Errorf("expected valid partial input, got %s", partial)
+			}
+			partials = append(partials, string(partial))
+			return nil
+		},
+		OnContentBlockDone: func(block anthropic.ContentBlockUnion) error {
+			done = append(done, block.Type)
+			return nil
+		},
+		OnMessageDone: func(message *anthropic.Message) error {
+			final = message
+			return nil
+		},
+	})
+
+	message, err := stream.FinalMessage()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if message != final || message.StopReason != anthropic.StopReasonToolUse {
+		t.Errorf("expected OnMessageDone to receive the final message, got %s", final.RawJSON())
+	}
+	if thinking.String() != "The user wants the weather in Paris." || lastText != "Let me check the forecast for you." {
+		t.Errorf("unexpected thinking %q and text %q", thinking.String(), lastText)
+	}
+	if strings.Join(done, ",") != "thinking,text,tool_use" {
+		t.Errorf("unexpected completed blocks %v", done)
+	}
+	if len(partials) < 2 || partials[len(partials)-1] != `{"city":"Paris","units":"celsius"}` {
+		t.Errorf("expected the tool input to stream in, got %q", partials)
+	}
+	if got, err := stream.FinalText(); err != nil || got != "Let me check the forecast for you." {
+		t.Errorf("unexpected final text %q (%v)", got, err)
+	}
+}
+
+func TestMessageStreamCitations(t *testing.T) {
+	events := []string{
+		`{"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"stop_reason":null,"usage":{"input_tokens":10,"output_tokens":1}}}`,
+		`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":"","citations":[]}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"citations_delta","citation":{"type":"char_location","cited_text":"The sky is blue.","document_index":0,"document_title":"Facts","start_char_index":0,"end_char_index":16}}}`,
+		`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"The sky is blue."}}`,
+		`{"type":"content_block_stop","index":0}`,
+		`{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":5}}`,
+		`{"type":"message_stop"}`,
+	}
+	var body strings.Builder
+	for _, event := range events {
+		var e struct{ Type string }
+		json.Unmarshal([]byte(event), &e)
+		body.WriteString("event: " + e.Type + "\ndata: " + event + "\n\n")
+	}
+	res := &http.Response{Header: http.Header{"Content-Type": {"text/event-stream"}}, Body: io.NopCloser(strings.NewReader(body.String()))}
+
+	var cited []string
+	stream := anthropic.NewMessageStream(ssestream.NewStream[anthropic.MessageStreamEventUnion](ssestream.NewDecoder(res), nil), anthropic.MessageStreamHandlers{
+		OnCitation: func(citation anthropic.TextCitationUnion, citations []anthropic.TextCitationUnion) error {
+			cited = append(cited, citation.CitedText)
+			if len(citations) != len(cited) {
+				t.Errorf("expected %d citations so far, got %d", len(cited), len(citations))
+			}
+			return nil
+		},
+	})
+	text, err := stream.FinalText()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if text != "The sky is blue." || len(cited) != 1 || cited[0] != "The sky is blue." {
+		t.Errorf("unexpected text %q and citations %q", text, cited)
+	}
+}
+
+func TestMessageStreamHandlerErrorStopsStream(t *testing.T) {
+	server := anthropictest.NewServer(t)
+	server.Reply(anthropictest.Text("A long answer that arrives in many pieces."))
+	client := anthropic.NewClient(server.Options()...)
+
+	stop := errors.New("stop")
+	calls := 0
+	stream := anthropic.NewMessageStream(client.Messages.NewStreaming(context.Background(), streamParams()), anthropic.MessageStreamHandlers{
+		OnText: func(delta, snapshot string) error {
+			calls++
+			return stop
+		},
+	})
+	defer stream.Close()
+	if _, err := stream.FinalMessage(); !errors.Is(err, stop) {
+		t.Errorf("expected the handler's error, got %v", err)
+	}
+	if calls != 1 {
+		t.Errorf("expected the stream to stop after the first error, got %d calls", calls)
+	}
+	if stream.Snapshot().ID == "" {
+		t.Error("expected the snapshot to hold the message so far")
+	}
+}
+
+func TestMessageStreamEndsEarly(t *testing.T) {
+	server := anthropictest.NewServer(t)
+	server.Reply(anthropictest.Text("Cut off").DisconnectAfter(3))
+	client := anthropic.NewClient(server.Options()...)
+
+	stream := anthropic.NewMessageStream(client.Messages.NewStreaming(context.Background(), streamParams()), anthropic.MessageStreamHandlers{})
+	if _, err := stream.FinalMessage(); err == nil {
+		t.Error("expected an incomplete stream to fail")
+	}
+}
+
+func TestBetaMessageStream(t *testing.T) {
+	server := anthropictest.NewServer(t)
+	server.Reply(anthropictest.Message(
+		anthropictest.TextBlock("Checking. "),
+		anthropictest.ToolUseBlock("get_weather", map[string]any{"city": "Paris"}),
+		anthropictest.TextBlock("Done."),
+	))
+	client := anthropic.NewClient(server.Options()...)
+
+	var events int
+	var input json.RawMessage
+	stream := anthropic.NewBetaMessageStream(client.Beta.Messages.NewStreaming(context.Background(), anthropic.BetaMessageNewParams{
+		Model:     anthropic.ModelClaudeSonnet4_5,
+		MaxTokens: 1024,
+		Messages:  []anthropic.BetaMessageParam{anthropic.NewBetaUserMessage(anthropic.NewBetaTextBlock("What's the weather?"))},
+	}), anthropic.BetaMessageStreamHandlers{
+		OnEvent: func(event anthropic.BetaRawMessageStreamEventUnion, snapshot *anthropic.BetaMessage) error {
+			events++
+			return nil
+		},
+		OnToolUseInput: func(partial json.RawMessage, block anthropic.BetaContentBlockUnion) error {
+			input = partial
+			return nil
+		},
+	})
+
+	n := 0
+	for stream.Next() {
+		n++
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if n != events {
+		t.Errorf("expected OnEvent for each of the %d events, got %d", n, events)
+	}
+	if string(input) != `{"city":"Paris"}` {
+		t.Errorf("unexpected tool input %s", input)
+	}
+	if text, err := stream.FinalText(); err != nil || text != "Checking. Done." {
+		t.Errorf("unexpected final text %q (%v)", text, err)
+	}
+}
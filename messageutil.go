@@ -6,6 +6,7 @@ import (
 
 	"github.com/anthropics/anthropic-sdk-go/internal/paramutil"
 	"github.com/anthropics/anthropic-sdk-go/packages/param"
+	"github.com/anthropics/anthropic-sdk-go/packages/partialjson"
 )
 
 // Accumulate builds up the Message incrementally from a MessageStreamEvent. The Message then can be used as
@@ -109,6 +110,51 @@ func (acc *Message) Accumulate(event MessageStreamEventUnion) error {
 	return nil
 }
 
+// ParseOutput finds the first text content block in the message and unmarshals it
+// into dest. This is useful for streaming workflows where you accumulate the message
+// first and then parse the structured output.
+//
+//	var msg anthropic.Message
+//	for stream.Next() { msg.Accumulate(stream.Current()) }
+//	msg.ParseOutput(&myStruct)
+func (r *Message) ParseOutput(dest any) error {
+	return parseMessageOutputContent(r, dest)
+}
+
+// ParsePartialOutput is [Message.ParseOutput] for a message that is still
+// streaming. The structured output received so far is parsed as a truncated
+// JSON document, with unterminated strings, arrays and objects closed, so
+// dest holds a best-effort partial value after each event:
+//
+//	var msg anthropic.Message
+//	for stream.Next() {
+//		msg.Accumulate(stream.Current())
+//		var partial MyStruct
+//		if err := msg.ParsePartialOutput(&partial); err == nil {
+//			render(partial)
+//		}
+//	}
+//
+// dest may also be a map[string]any. If no text has arrived yet, dest is left
+// unchanged.
+func (r *Message) ParsePartialOutput(dest any) error {
+	for _, block := range r.Content {
+		if block.Type == "text" {
+			return unmarshalPartialOutput(block.Text, dest)
+		}
+	}
+	return nil
+}
+
+// ParsePartialInput unmarshals the input of a tool use block into dest. While
+// the block is still streaming, the input received so far is parsed as a
+// truncated JSON document, so dest holds a best-effort partial value; see
+// [Message.ParsePartialOutput]. If no input has arrived yet, dest is left
+// unchanged.
+func (r ContentBlockUnion) ParsePartialInput(dest any) error {
+	return partialjson.Unmarshal(r.Input, dest)
+}
+
 // checkContentBlockIndex reports an error if a stream event's index does not
 // address one of the numBlocks content blocks accumulated so far. Delta and
 // stop events may interleave across open content blocks, so they address
@@ -17,6 +17,9 @@ import (
 	"github.com/anthropics/anthropic-sdk-go/config"
 	"github.com/anthropics/anthropic-sdk-go/internal/auth"
 	"github.com/anthropics/anthropic-sdk-go/internal/requestconfig"
+	"github.com/anthropics/anthropic-sdk-go/packages/cassette"
+	"github.com/anthropics/anthropic-sdk-go/packages/ratelimit"
+	"github.com/anthropics/anthropic-sdk-go/packages/responsecache"
 	"github.com/tidwall/sjson"
 )
 
@@ -103,6 +106,62 @@ func IdentityTokenFile(path string) IdentityTokenFunc {
 	return (&auth.IdentityTokenFile{Path: path}).GetIdentityToken
 }
 
+// IdentityTokenEnv returns an [IdentityTokenFunc] that reads a JWT from the
+// environment variable name on each invocation, for platforms that inject
+// the token into the process environment.
+func IdentityTokenEnv(name string) IdentityTokenFunc {
+	return (&auth.IdentityTokenEnv{Name: name}).GetIdentityToken
+}
+
+// IdentityTokenExec returns an [IdentityTokenFunc] that runs a credential
+// helper, command with args, and uses the JWT it prints on stdout. The
+// helper may print the bare token or a JSON object
+// {"token": "...", "expires_at": <unix seconds>}. The token is reused until
+// shortly before it expires; a token whose expiry is unknown (no
+// expires_at and no exp claim) is fetched again on every exchange.
+//
+// The command is run directly, not through a shell, and is killed after
+// 30 seconds. The returned function is safe for concurrent use.
+func IdentityTokenExec(command string, args ...string) IdentityTokenFunc {
+	return (&auth.IdentityTokenExec{Command: append([]string{command}, args...)}).GetIdentityToken
+}
+
+// IdentityTokenGitHubActions returns an [IdentityTokenFunc] that requests a
+// JWT from the GitHub Actions OIDC provider with the given audience. The
+// workflow job needs the id-token: write permission, which makes GitHub set
+// the ACTIONS_ID_TOKEN_REQUEST_URL and ACTIONS_ID_TOKEN_REQUEST_TOKEN
+// variables the request uses. An empty audience requests GitHub's default,
+// the URL of the repository owner.
+//
+// The token is reused until shortly before it expires. The returned
+// function is safe for concurrent use.
+func IdentityTokenGitHubActions(audience string) IdentityTokenFunc {
+	return (&auth.IdentityTokenGitHubActions{Audience: audience}).GetIdentityToken
+}
+
+// IdentityTokenGCPMetadata returns an [IdentityTokenFunc] that requests an
+// identity token with the given audience for the default service account
+// of the GCE instance, GKE workload or Cloud Run service it runs on, from
+// the GCP metadata server. Set GCE_METADATA_HOST to reach a metadata server
+// at another address.
+//
+// The token is reused until shortly before it expires. The returned
+// function is safe for concurrent use.
+func IdentityTokenGCPMetadata(audience string) IdentityTokenFunc {
+	return (&auth.IdentityTokenGCPMetadata{Audience: audience}).GetIdentityToken
+}
+
+// IdentityTokenKubernetes returns an [IdentityTokenFunc] that reads a
+// Kubernetes projected service account token from path, or from
+// config.DefaultKubernetesTokenPath when path is empty. Unlike
+// [IdentityTokenFile], the token is kept in memory and the file is only
+// re-read when the kubelet rotates it or the token nears expiry.
+//
+// The returned function is safe for concurrent use.
+func IdentityTokenKubernetes(path string) IdentityTokenFunc {
+	return (&auth.IdentityTokenKubernetes{Path: path}).GetIdentityToken
+}
+
 // WithFederationTokenProvider returns a [RequestOption] that authenticates
 // requests using workload identity federation, exchanging a caller-supplied
 // identity token for a short-lived Anthropic access token. Use this to
@@ -226,6 +285,106 @@ func WithMaxRetries(retries int) RequestOption {
 	})
 }
 
+// WithStreamReconnect returns a RequestOption that lets streaming responses
+// resume after their connection drops. When a stream fails mid-way, or its
+// connection closes between a message_start and its message_stop, and the
+// server has sent an event ID, the request is re-issued with a Last-Event-ID
+// header (waiting for any server `retry` hint first) and the
+// [ssestream.Stream] continues yielding events from where it left off.
+// A replay of the last received event is skipped.
+//
+// maxReconnects bounds the number of reconnections over the lifetime of one
+// stream. Each reconnection is itself retried according to [WithMaxRetries]
+// or [WithRetryPolicy].
+//
+// WithStreamReconnect panics when maxReconnects is negative.
+//
+// [ssestream.Stream]: https://pkg.go.dev/github.com/anthropics/anthropic-sdk-go/packages/ssestream#Stream
+func WithStreamReconnect(maxReconnects int) RequestOption {
+	if maxReconnects < 0 {
+		panic("option: cannot have fewer than 0 stream reconnects")
+	}
+	return requestconfig.RequestOptionFunc(func(r *requestconfig.RequestConfig) error {
+		r.StreamReconnects = maxReconnects
+		return nil
+	})
+}
+
+// WithRateLimiter returns a RequestOption that paces requests with the given
+// [ratelimit.Limiter], based on the anthropic-ratelimit-* headers of earlier
+// responses. Share one Limiter between every client that uses the same API
+// key so that they back off together. Retries are paced as well.
+//
+// WithRateLimiter panics when limiter is nil.
+//
+// [ratelimit.Limiter]: https://pkg.go.dev/github.com/anthropics/anthropic-sdk-go/packages/ratelimit#Limiter
+func WithRateLimiter(limiter *ratelimit.Limiter) RequestOption {
+	if limiter == nil {
+		panic("option: rate limiter cannot be nil")
+	}
+	return WithMiddleware(limiter.Do)
+}
+
+// WithRecorder returns a RequestOption that records the client's API
+// interactions to the cassette file at path, or replays them from it,
+// according to mode. Credential headers are not recorded. Use it as a client
+// option, so that every request shares the cassette:
+//
+//	mode := cassette.ModeReplay
+//	if os.Getenv("RECORD") != "" {
+//		mode = cassette.ModeRecord
+//	}
+//	client := anthropic.NewClient(option.WithRecorder("testdata/agent.json", mode))
+//
+// Middleware runs in registration order, so with the bedrock or vertex
+// options pass WithRecorder before them. It then records Anthropic-shaped
+// requests, and replays without provider credentials. Passed after them, it
+// records the rewritten requests after they are signed; the signature headers
+// are scrubbed, but replaying still signs each request first.
+//
+// Open a [cassette.Cassette] and install it with [WithCassette] to customize
+// how requests are matched.
+//
+// [cassette.Cassette]: https://pkg.go.dev/github.com/anthropics/anthropic-sdk-go/packages/cassette#Cassette
+func WithRecorder(path string, mode cassette.Mode) RequestOption {
+	c, err := cassette.Open(path, mode, cassette.Options{})
+	if err != nil {
+		return errOption(fmt.Errorf("option: WithRecorder: %w", err))
+	}
+	return WithCassette(c)
+}
+
+// WithCassette returns a RequestOption that records or replays API
+// interactions with the given [cassette.Cassette]. Order it relative to
+// provider options as for [WithRecorder].
+//
+// WithCassette panics when c is nil.
+//
+// [cassette.Cassette]: https://pkg.go.dev/github.com/anthropics/anthropic-sdk-go/packages/cassette#Cassette
+func WithCassette(c *cassette.Cassette) RequestOption {
+	if c == nil {
+		panic("option: cassette cannot be nil")
+	}
+	return WithMiddleware(c.Do)
+}
+
+// WithResponseCache returns a RequestOption that serves repeated Messages
+// requests from the given [responsecache.Cache], and caches the responses of
+// new ones, including streams. By default only requests with a temperature of 0 are cached:
+//
+//	cache := responsecache.New(responsecache.NewMemoryStore(1000), responsecache.Options{TTL: time.Hour})
+//	client := anthropic.NewClient(option.WithResponseCache(cache))
+//
+// WithResponseCache panics when cache is nil.
+//
+// [responsecache.Cache]: https://pkg.go.dev/github.com/anthropics/anthropic-sdk-go/packages/responsecache#Cache
+func WithResponseCache(cache *responsecache.Cache) RequestOption {
+	if cache == nil {
+		panic("option: response cache cannot be nil")
+	}
+	return WithMiddleware(cache.Do)
+}
+
 // WithHeader returns a RequestOption that sets the header value to the associated key. It overwrites
 // any value if there was one already present.
 func WithHeader(key, value string) RequestOption {
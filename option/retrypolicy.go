@@ -0,0 +1,69 @@
+package option
+
+import (
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/internal/requestconfig"
+)
+
+// RetryPolicy decides, for each failed attempt, whether the request is
+// retried and how long to wait first. Install one with [WithRetryPolicy].
+type RetryPolicy = requestconfig.RetryPolicy
+
+// RetryAttempt describes a failed attempt for a [RetryPolicy] to judge: the
+// request, the response or error it produced, the number of retries so far and
+// the time since the first attempt.
+type RetryAttempt = requestconfig.RetryAttempt
+
+// RetryPolicyFunc adapts a function to a [RetryPolicy].
+type RetryPolicyFunc = requestconfig.RetryPolicyFunc
+
+// WithRetryPolicy returns a RequestOption that lets policy decide whether and
+// when failed requests are retried, in place of [WithMaxRetries] and the
+// default backoff. The policy also decides whether a streaming response whose
+// connection drops mid-way is resumed with a Last-Event-ID request; such
+// attempts have [RetryAttempt.LastEventID] set. [WithStreamReconnect], if
+// also given, takes precedence for streams.
+//
+// Passing nil restores the default behavior.
+func WithRetryPolicy(policy RetryPolicy) RequestOption {
+	return requestconfig.RequestOptionFunc(func(r *requestconfig.RequestConfig) error {
+		r.RetryPolicy = policy
+		return nil
+	})
+}
+
+// DefaultRetryPolicy returns the policy used when none is set: connection
+// errors, 408, 409, 429 and 5xx responses are retried up to maxRetries times
+// with exponential backoff from 0.5s capped at 8s, unless the response asks
+// for a specific delay.
+func DefaultRetryPolicy(maxRetries int) RetryPolicy {
+	return requestconfig.DefaultRetryPolicy{MaxRetries: maxRetries}
+}
+
+// RetryBudget returns a policy that retries as policy does, but only while
+// the retry would start within budget of the first attempt. If policy is nil,
+// the conditions and backoff of [DefaultRetryPolicy] are used with no limit on
+// the number of retries, so the budget alone bounds them.
+//
+//	// Latency-sensitive: give up after 2s.
+//	option.WithRetryPolicy(option.RetryBudget(2*time.Second, nil))
+//	// Batch: keep retrying for up to 10 minutes.
+//	option.WithRetryPolicy(option.RetryBudget(10*time.Minute, nil))
+func RetryBudget(budget time.Duration, policy RetryPolicy) RetryPolicy {
+	return requestconfig.RetryBudgetPolicy{Budget: budget, Policy: policy}
+}
+
+// RetryOverloaded returns a policy that retries 529 overloaded responses up
+// to maxRetries times, waiting initialDelay before the first retry and
+// doubling the wait for each later one up to maxDelay. A Retry-After header
+// takes precedence. Every other failure is left to policy, or not retried if
+// policy is nil.
+func RetryOverloaded(maxRetries int, initialDelay, maxDelay time.Duration, policy RetryPolicy) RetryPolicy {
+	return requestconfig.OverloadedRetryPolicy{
+		MaxRetries:   maxRetries,
+		InitialDelay: initialDelay,
+		MaxDelay:     maxDelay,
+		Policy:       policy,
+	}
+}
@@ -0,0 +1,105 @@
+package option_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+type statusTransport struct {
+	statuses []int
+	header   http.Header
+	calls    int
+}
+
+func (t *statusTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	status := t.statuses[min(t.calls, len(t.statuses)-1)]
+	t.calls++
+	header := t.header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{StatusCode: status, Header: header, Body: http.NoBody}, nil
+}
+
+func sendMessage(transport *statusTransport, opts ...option.RequestOption) error {
+	client := anthropic.NewClient(append([]option.RequestOption{
+		option.WithAPIKey("my-anthropic-api-key"),
+		option.WithHTTPClient(&http.Client{Transport: transport}),
+	}, opts...)...)
+	_, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
+		MaxTokens: 1024,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("x"))},
+		Model:     anthropic.ModelClaudeSonnet4_5,
+	})
+	return err
+}
+
+func TestWithRetryPolicy(t *testing.T) {
+	transport := &statusTransport{statuses: []int{http.StatusBadRequest}}
+	var attempts []option.RetryAttempt
+	err := sendMessage(transport, option.WithMaxRetries(0), option.WithRetryPolicy(option.RetryPolicyFunc(func(a option.RetryAttempt) (time.Duration, bool) {
+		attempts = append(attempts, a)
+		return 0, a.Attempt < 3
+	})))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if transport.calls != 4 {
+		t.Errorf("expected the policy to override MaxRetries and allow 4 attempts, got %d", transport.calls)
+	}
+	for i, a := range attempts {
+		if a.Attempt != i || a.Response == nil || a.Response.StatusCode != http.StatusBadRequest || a.Request == nil {
+			t.Errorf("unexpected attempt %d: %+v", i, a)
+		}
+	}
+}
+
+func TestRetryBudget(t *testing.T) {
+	transport := &statusTransport{
+		statuses: []int{http.StatusServiceUnavailable},
+		header:   http.Header{"Retry-After-Ms": []string{"40"}},
+	}
+	start := time.Now()
+	err := sendMessage(transport, option.WithRetryPolicy(option.RetryBudget(150*time.Millisecond, nil)))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected the budget to bound retrying, took %s", elapsed)
+	}
+	// Retries at ~40ms, ~80ms and ~120ms fit in the budget; the next does not.
+	if transport.calls < 3 || transport.calls > 4 {
+		t.Errorf("expected 3-4 attempts within the budget, got %d", transport.calls)
+	}
+}
+
+func TestRetryOverloaded(t *testing.T) {
+	transport := &statusTransport{statuses: []int{529, 529, 529, http.StatusOK}}
+	policy := option.RetryOverloaded(5, time.Millisecond, 5*time.Millisecond, nil)
+	_ = sendMessage(transport, option.WithRetryPolicy(policy))
+	if transport.calls != 4 {
+		t.Errorf("expected overloaded responses to be retried until success, got %d attempts", transport.calls)
+	}
+
+	transport = &statusTransport{statuses: []int{http.StatusInternalServerError}}
+	if err := sendMessage(transport, option.WithRetryPolicy(policy)); err == nil {
+		t.Fatal("expected an error")
+	}
+	if transport.calls != 1 {
+		t.Errorf("expected other failures not to be retried without a fallback policy, got %d attempts", transport.calls)
+	}
+
+	transport = &statusTransport{statuses: []int{http.StatusInternalServerError}}
+	policy = option.RetryOverloaded(5, time.Millisecond, 5*time.Millisecond, option.RetryPolicyFunc(func(a option.RetryAttempt) (time.Duration, bool) {
+		return 0, a.Attempt < 1
+	}))
+	_ = sendMessage(transport, option.WithRetryPolicy(policy))
+	if transport.calls != 2 {
+		t.Errorf("expected other failures to be left to the fallback policy, got %d attempts", transport.calls)
+	}
+}
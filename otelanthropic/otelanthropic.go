@@ -0,0 +1,399 @@
+// Package otelanthropic instruments the Anthropic Go SDK with OpenTelemetry.
+//
+// It lives in its own module so that the SDK itself does not depend on
+// OpenTelemetry. Install it on a client with [WithOpenTelemetry]:
+//
+//	client := anthropic.NewClient(otelanthropic.WithOpenTelemetry())
+//
+// Every HTTP attempt made by the client then gets a client span, named after
+// the GenAI semantic conventions (for example "chat claude-sonnet-4-5"), that
+// records the requested and served model, token usage, stop reason, retry
+// count and, for streams, the time to the first content chunk. The same
+// values feed the gen_ai.client.* metrics. Spans are children of the span in
+// the request's context, so they nest under whatever the caller is tracing;
+// [Instrumentation.WrapTools] and [Instrumentation.RunToCompletion] add spans
+// for tool executions and tool runner iterations.
+package otelanthropic
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"mime"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/tidwall/gjson"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// ScopeName is the instrumentation scope of the tracer and meter.
+const ScopeName = "github.com/anthropics/anthropic-sdk-go/otelanthropic"
+
+// Attribute keys. The gen_ai.* keys follow the OpenTelemetry semantic
+// conventions for generative AI; the anthropic.* keys are specific to this
+// SDK.
+const (
+	AttrSystem              = attribute.Key("gen_ai.system")
+	AttrOperationName       = attribute.Key("gen_ai.operation.name")
+	AttrRequestModel        = attribute.Key("gen_ai.request.model")
+	AttrRequestMaxTokens    = attribute.Key("gen_ai.request.max_tokens")
+	AttrRequestTemperature  = attribute.Key("gen_ai.request.temperature")
+	AttrResponseID          = attribute.Key("gen_ai.response.id")
+	AttrResponseModel       = attribute.Key("gen_ai.response.model")
+	AttrResponseFinish      = attribute.Key("gen_ai.response.finish_reasons")
+	AttrUsageInputTokens    = attribute.Key("gen_ai.usage.input_tokens")
+	AttrUsageOutputTokens   = attribute.Key("gen_ai.usage.output_tokens")
+	AttrTokenType           = attribute.Key("gen_ai.token.type")
+	AttrToolName            = attribute.Key("gen_ai.tool.name")
+	AttrToolType            = attribute.Key("gen_ai.tool.type")
+	AttrTimeToFirstChunk    = attribute.Key("gen_ai.response.time_to_first_chunk")
+	AttrCacheReadTokens     = attribute.Key("anthropic.usage.cache_read_input_tokens")
+	AttrCacheCreationTokens = attribute.Key("anthropic.usage.cache_creation_input_tokens")
+	AttrRetryCount          = attribute.Key("anthropic.retry_count")
+	AttrRequestID           = attribute.Key("anthropic.request_id")
+	AttrIteration           = attribute.Key("anthropic.tool_runner.iteration")
+	AttrServerAddress       = attribute.Key("server.address")
+	AttrServerPort          = attribute.Key("server.port")
+	AttrHTTPStatusCode      = attribute.Key("http.response.status_code")
+	AttrErrorType           = attribute.Key("error.type")
+)
+
+// Option configures an [Instrumentation].
+type Option func(*config)
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	meterProvider  metric.MeterProvider
+}
+
+// WithTracerProvider sets the TracerProvider spans are created with. Defaults
+// to the global provider.
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) { c.tracerProvider = provider }
+}
+
+// WithMeterProvider sets the MeterProvider metrics are recorded with. Defaults
+// to the global provider.
+func WithMeterProvider(provider metric.MeterProvider) Option {
+	return func(c *config) { c.meterProvider = provider }
+}
+
+// Instrumentation creates the spans and metrics for one configuration.
+type Instrumentation struct {
+	tracer   trace.Tracer
+	duration metric.Float64Histogram
+	tokens   metric.Int64Histogram
+	ttfc     metric.Float64Histogram
+}
+
+// New returns an Instrumentation configured by opts.
+func New(opts ...Option) *Instrumentation {
+	cfg := config{
+		tracerProvider: otel.GetTracerProvider(),
+		meterProvider:  otel.GetMeterProvider(),
+	}
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	meter := cfg.meterProvider.Meter(ScopeName)
+	in := &Instrumentation{tracer: cfg.tracerProvider.Tracer(ScopeName)}
+	// Instrument creation only fails for invalid names; the no-op instruments
+	// returned alongside the error are safe to use.
+	in.duration, _ = meter.Float64Histogram("gen_ai.client.operation.duration",
+		metric.WithDescription("Duration of GenAI client operations."),
+		metric.WithUnit("s"))
+	in.tokens, _ = meter.Int64Histogram("gen_ai.client.token.usage",
+		metric.WithDescription("Number of input and output tokens used."),
+		metric.WithUnit("{token}"))
+	in.ttfc, _ = meter.Float64Histogram("gen_ai.client.operation.time_to_first_chunk",
+		metric.WithDescription("Time from sending a streaming request to receiving its first content chunk."),
+		metric.WithUnit("s"))
+	return in
+}
+
+// WithOpenTelemetry returns a request option that traces and measures every
+// API call. It is shorthand for New(opts...).RequestOption().
+func WithOpenTelemetry(opts ...Option) option.RequestOption {
+	return New(opts...).RequestOption()
+}
+
+// RequestOption returns a request option that traces and measures every API
+// call made with it.
+func (in *Instrumentation) RequestOption() option.RequestOption {
+	return option.WithMiddleware(in.middleware)
+}
+
+func (in *Instrumentation) middleware(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	call := in.describe(req)
+	ctx, span := in.tracer.Start(req.Context(), call.spanName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(call.spanAttrs...))
+	call.span = span
+	call.start = time.Now()
+
+	res, err := next(req.WithContext(ctx))
+	if err != nil {
+		call.finish(ctx, in, "", err)
+		return res, err
+	}
+
+	span.SetAttributes(AttrHTTPStatusCode.Int(res.StatusCode))
+	if id := res.Header.Get("request-id"); id != "" {
+		span.SetAttributes(AttrRequestID.String(id))
+	}
+	if res.StatusCode >= http.StatusBadRequest {
+		call.finish(ctx, in, strconv.Itoa(res.StatusCode), nil)
+		return res, nil
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("content-type"))
+	switch {
+	case mediaType == "text/event-stream":
+		res.Body = &streamRecorder{ReadCloser: res.Body, ctx: ctx, in: in, call: call}
+	case mediaType == "application/json" && call.genAI:
+		body, readErr := io.ReadAll(res.Body)
+		res.Body.Close()
+		if readErr != nil {
+			call.finish(ctx, in, "", readErr)
+			return nil, readErr
+		}
+		res.Body = io.NopCloser(bytes.NewReader(body))
+		call.recordMessage(gjson.ParseBytes(body))
+		call.finish(ctx, in, "", nil)
+	default:
+		call.finish(ctx, in, "", nil)
+	}
+	return res, nil
+}
+
+// apiCall accumulates what is known about one HTTP attempt.
+type apiCall struct {
+	span      trace.Span
+	start     time.Time
+	spanName  string
+	spanAttrs []attribute.KeyValue
+	// metricAttrs are shared by every metric recorded for the call.
+	metricAttrs []attribute.KeyValue
+	genAI       bool
+
+	responseModel string
+	inputTokens   int64
+	outputTokens  int64
+	hasUsage      bool
+	once          sync.Once
+}
+
+// describe derives the span name and attributes of a request.
+func (in *Instrumentation) describe(req *http.Request) *apiCall {
+	call := &apiCall{spanName: req.Method + " " + req.URL.Path}
+	attrs := []attribute.KeyValue{AttrSystem.String("anthropic")}
+	if host, port, err := net.SplitHostPort(req.URL.Host); err == nil {
+		attrs = append(attrs, AttrServerAddress.String(host))
+		if p, err := strconv.Atoi(port); err == nil {
+			attrs = append(attrs, AttrServerPort.Int(p))
+		}
+	} else {
+		attrs = append(attrs, AttrServerAddress.String(req.URL.Host))
+	}
+
+	if req.Method == http.MethodPost && strings.HasSuffix(req.URL.Path, "/v1/messages") {
+		call.genAI = true
+		attrs = append(attrs, AttrOperationName.String("chat"))
+		if body := requestBody(req); body.Exists() {
+			model := body.Get("model").String()
+			call.spanName = "chat " + model
+			attrs = append(attrs, AttrRequestModel.String(model))
+			if v := body.Get("max_tokens"); v.Exists() {
+				attrs = append(attrs, AttrRequestMaxTokens.Int64(v.Int()))
+			}
+			if v := body.Get("temperature"); v.Exists() {
+				attrs = append(attrs, AttrRequestTemperature.Float64(v.Float()))
+			}
+		}
+	}
+	call.metricAttrs = attrs
+	call.spanAttrs = append([]attribute.KeyValue{}, attrs...)
+	if n, err := strconv.Atoi(req.Header.Get("X-Stainless-Retry-Count")); err == nil {
+		call.spanAttrs = append(call.spanAttrs, AttrRetryCount.Int(n))
+	}
+	return call
+}
+
+// requestBody parses a copy of the request body as JSON.
+func requestBody(req *http.Request) gjson.Result {
+	if req.GetBody == nil {
+		return gjson.Result{}
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return gjson.Result{}
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil || !gjson.ValidBytes(data) {
+		return gjson.Result{}
+	}
+	return gjson.ParseBytes(data)
+}
+
+// recordMessage records the fields of a complete message response.
+func (c *apiCall) recordMessage(msg gjson.Result) {
+	c.recordStart(msg)
+	if reason := msg.Get("stop_reason").String(); reason != "" {
+		c.span.SetAttributes(AttrResponseFinish.StringSlice([]string{reason}))
+	}
+	if v := msg.Get("usage.output_tokens"); v.Exists() {
+		c.outputTokens = v.Int()
+	}
+}
+
+// recordStart records the message identity and input usage, which streams
+// report in message_start.
+func (c *apiCall) recordStart(msg gjson.Result) {
+	if id := msg.Get("id").String(); id != "" {
+		c.span.SetAttributes(AttrResponseID.String(id))
+	}
+	if model := msg.Get("model").String(); model != "" {
+		c.responseModel = model
+		c.span.SetAttributes(AttrResponseModel.String(model))
+	}
+	usage := msg.Get("usage")
+	if !usage.Exists() {
+		return
+	}
+	c.hasUsage = true
+	c.inputTokens = usage.Get("input_tokens").Int()
+	c.outputTokens = usage.Get("output_tokens").Int()
+	if v := usage.Get("cache_read_input_tokens"); v.Exists() {
+		c.span.SetAttributes(AttrCacheReadTokens.Int64(v.Int()))
+	}
+	if v := usage.Get("cache_creation_input_tokens"); v.Exists() {
+		c.span.SetAttributes(AttrCacheCreationTokens.Int64(v.Int()))
+	}
+}
+
+// finish ends the span and records the call's metrics. errorType, or err if
+// errorType is empty, marks the call as failed.
+func (c *apiCall) finish(ctx context.Context, in *Instrumentation, errorType string, err error) {
+	c.once.Do(func() {
+		attrs := c.metricAttrs
+		if c.responseModel != "" {
+			attrs = append(attrs[:len(attrs):len(attrs)], AttrResponseModel.String(c.responseModel))
+		}
+		if err != nil && errorType == "" {
+			errorType = errorTypeOf(err)
+			c.span.RecordError(err)
+		}
+		if errorType != "" {
+			attrs = append(attrs[:len(attrs):len(attrs)], AttrErrorType.String(errorType))
+			c.span.SetAttributes(AttrErrorType.String(errorType))
+			c.span.SetStatus(codes.Error, errorType)
+		}
+		if c.hasUsage {
+			c.span.SetAttributes(AttrUsageInputTokens.Int64(c.inputTokens), AttrUsageOutputTokens.Int64(c.outputTokens))
+			in.tokens.Record(ctx, c.inputTokens, metric.WithAttributes(append(attrs[:len(attrs):len(attrs)], AttrTokenType.String("input"))...))
+			in.tokens.Record(ctx, c.outputTokens, metric.WithAttributes(append(attrs[:len(attrs):len(attrs)], AttrTokenType.String("output"))...))
+		}
+		in.duration.Record(ctx, time.Since(c.start).Seconds(), metric.WithAttributes(attrs...))
+		c.span.End()
+	})
+}
+
+func errorTypeOf(err error) string {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "timeout"
+	default:
+		return "_OTHER"
+	}
+}
+
+// streamRecorder observes a text/event-stream body as it is read, and ends the
+// call's span when the stream ends or is closed.
+type streamRecorder struct {
+	io.ReadCloser
+	ctx     context.Context
+	in      *Instrumentation
+	call    *apiCall
+	pending []byte
+	first   bool
+}
+
+func (s *streamRecorder) Read(p []byte) (int, error) {
+	n, err := s.ReadCloser.Read(p)
+	s.observe(p[:n])
+	switch {
+	case err == io.EOF:
+		s.call.finish(s.ctx, s.in, "", nil)
+	case err != nil:
+		s.call.finish(s.ctx, s.in, "", err)
+	}
+	return n, err
+}
+
+func (s *streamRecorder) Close() error {
+	err := s.ReadCloser.Close()
+	s.call.finish(s.ctx, s.in, "", nil)
+	return err
+}
+
+// observe processes the complete lines in data.
+func (s *streamRecorder) observe(data []byte) {
+	s.pending = append(s.pending, data...)
+	for {
+		i := bytes.IndexByte(s.pending, '\n')
+		if i < 0 {
+			return
+		}
+		line := bytes.TrimRight(s.pending[:i], "\r")
+		s.pending = s.pending[i+1:]
+		if payload, ok := bytes.CutPrefix(line, []byte("data:")); ok {
+			s.event(gjson.ParseBytes(bytes.TrimSpace(payload)))
+		}
+	}
+}
+
+func (s *streamRecorder) event(event gjson.Result) {
+	switch event.Get("type").String() {
+	case "message_start":
+		s.call.recordStart(event.Get("message"))
+	case "content_block_delta":
+		if !s.first {
+			s.first = true
+			ttfc := time.Since(s.call.start).Seconds()
+			s.call.span.SetAttributes(AttrTimeToFirstChunk.Float64(ttfc))
+			s.in.ttfc.Record(s.ctx, ttfc, metric.WithAttributes(s.call.metricAttrs...))
+		}
+	case "message_delta":
+		if reason := event.Get("delta.stop_reason").String(); reason != "" {
+			s.call.span.SetAttributes(AttrResponseFinish.StringSlice([]string{reason}))
+		}
+		if v := event.Get("usage.output_tokens"); v.Exists() {
+			s.call.hasUsage = true
+			s.call.outputTokens = v.Int()
+		}
+		if v := event.Get("usage.input_tokens"); v.Exists() {
+			s.call.inputTokens = v.Int()
+		}
+	case "error":
+		errorType := event.Get("error.type").String()
+		if errorType == "" {
+			errorType = "_OTHER"
+		}
+		s.call.finish(s.ctx, s.in, errorType, nil)
+	}
+}
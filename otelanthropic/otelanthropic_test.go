@@ -0,0 +1,341 @@
+package otelanthropic_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/otelanthropic"
+	"github.com/anthropics/anthropic-sdk-go/toolrunner"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+type telemetry struct {
+	spans  *tracetest.InMemoryExporter
+	reader *sdkmetric.ManualReader
+	in     *otelanthropic.Instrumentation
+}
+
+func newTelemetry() *telemetry {
+	spans := tracetest.NewInMemoryExporter()
+	reader := sdkmetric.NewManualReader()
+	return &telemetry{
+		spans:  spans,
+		reader: reader,
+		in: otelanthropic.New(
+			otelanthropic.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(spans))),
+			otelanthropic.WithMeterProvider(sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))),
+		),
+	}
+}
+
+func (tel *telemetry) span(t *testing.T, name string) tracetest.SpanStub {
+	t.Helper()
+	for _, span := range tel.spans.GetSpans() {
+		if span.Name == name {
+			return span
+		}
+	}
+	t.Fatalf("no span named %q among %d spans", name, len(tel.spans.GetSpans()))
+	return tracetest.SpanStub{}
+}
+
+func (tel *telemetry) metric(t *testing.T, name string) metricdata.Metrics {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	if err := tel.reader.Collect(context.Background(), &rm); err != nil {
+		t.Fatalf("collect metrics: %v", err)
+	}
+	for _, scope := range rm.ScopeMetrics {
+		for _, m := range scope.Metrics {
+			if m.Name == name {
+				return m
+			}
+		}
+	}
+	t.Fatalf("no metric named %q", name)
+	return metricdata.Metrics{}
+}
+
+func attr(span tracetest.SpanStub, key attribute.Key) attribute.Value {
+	for _, kv := range span.Attributes {
+		if kv.Key == key {
+			return kv.Value
+		}
+	}
+	return attribute.Value{}
+}
+
+// server replies to successive requests with the given handlers.
+func server(t *testing.T, handlers ...http.HandlerFunc) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	n := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		i := n
+		n++
+		mu.Unlock()
+		if i >= len(handlers) {
+			t.Errorf("unexpected request %d", i+1)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		handlers[i](w, r)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func jsonReply(body string) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Header().Set("Request-Id", "req_123")
+		fmt.Fprint(w, body)
+	}
+}
+
+const textReply = `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5-20250929","stop_reason":"end_turn",
+	"content":[{"type":"text","text":"Hello"}],"usage":{"input_tokens":12,"output_tokens":4}}`
+
+func newClient(srv *httptest.Server, opts ...option.RequestOption) anthropic.Client {
+	return anthropic.NewClient(append([]option.RequestOption{
+		option.WithBaseURL(srv.URL),
+		option.WithAPIKey("test-key"),
+		option.WithMaxRetries(0),
+	}, opts...)...)
+}
+
+var messageParams = anthropic.MessageNewParams{
+	Model:     anthropic.ModelClaudeSonnet4_5,
+	MaxTokens: 256,
+	Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("Hi"))},
+}
+
+func TestMessageSpan(t *testing.T) {
+	tel := newTelemetry()
+	client := newClient(server(t, jsonReply(textReply)), tel.in.RequestOption())
+
+	message, err := client.Messages.New(context.Background(), messageParams)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if message.Content[0].Text != "Hello" {
+		t.Errorf("expected the response body to be passed through, got %+v", message.Content)
+	}
+
+	span := tel.span(t, "chat claude-sonnet-4-5")
+	for key, want := range map[attribute.Key]any{
+		otelanthropic.AttrSystem:            "anthropic",
+		otelanthropic.AttrOperationName:     "chat",
+		otelanthropic.AttrRequestModel:      "claude-sonnet-4-5",
+		otelanthropic.AttrRequestMaxTokens:  int64(256),
+		otelanthropic.AttrResponseID:        "msg_1",
+		otelanthropic.AttrResponseModel:     "claude-sonnet-4-5-20250929",
+		otelanthropic.AttrUsageInputTokens:  int64(12),
+		otelanthropic.AttrUsageOutputTokens: int64(4),
+		otelanthropic.AttrHTTPStatusCode:    int64(200),
+		otelanthropic.AttrRequestID:         "req_123",
+	} {
+		if got := attr(span, key).AsInterface(); got != want {
+			t.Errorf("%s: expected %v, got %v", key, want, got)
+		}
+	}
+	if got := attr(span, otelanthropic.AttrResponseFinish).AsStringSlice(); len(got) != 1 || got[0] != "end_turn" {
+		t.Errorf("expected finish reasons [end_turn], got %v", got)
+	}
+
+	tokens := tel.metric(t, "gen_ai.client.token.usage").Data.(metricdata.Histogram[int64])
+	var sum int64
+	for _, point := range tokens.DataPoints {
+		sum += point.Sum
+	}
+	if len(tokens.DataPoints) != 2 || sum != 16 {
+		t.Errorf("expected input and output token points summing to 16, got %+v", tokens.DataPoints)
+	}
+	duration := tel.metric(t, "gen_ai.client.operation.duration").Data.(metricdata.Histogram[float64])
+	if len(duration.DataPoints) != 1 || duration.DataPoints[0].Count != 1 {
+		t.Errorf("expected one duration measurement, got %+v", duration.DataPoints)
+	}
+}
+
+func TestErrorResponse(t *testing.T) {
+	tel := newTelemetry()
+	client := newClient(server(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+	}), otelanthropic.WithOpenTelemetry(
+		otelanthropic.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(tel.spans))),
+	))
+
+	if _, err := client.Messages.New(context.Background(), messageParams); err == nil {
+		t.Fatal("expected an error")
+	}
+	span := tel.span(t, "chat claude-sonnet-4-5")
+	if span.Status.Code != codes.Error {
+		t.Errorf("expected an error status, got %+v", span.Status)
+	}
+	if got := attr(span, otelanthropic.AttrErrorType).AsString(); got != "429" {
+		t.Errorf("expected error.type 429, got %q", got)
+	}
+}
+
+func TestTimeoutError(t *testing.T) {
+	tel := newTelemetry()
+	release := make(chan struct{})
+	client := newClient(server(t, func(w http.ResponseWriter, r *http.Request) {
+		<-release
+	}), otelanthropic.WithOpenTelemetry(
+		otelanthropic.WithTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSyncer(tel.spans))),
+	))
+
+	t.Cleanup(func() { close(release) })
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if _, err := client.Messages.New(ctx, messageParams); err == nil {
+		t.Fatal("expected an error")
+	}
+	// The transport reports the deadline wrapped in a *url.Error.
+	if got := attr(tel.span(t, "chat claude-sonnet-4-5"), otelanthropic.AttrErrorType).AsString(); got != "timeout" {
+		t.Errorf("expected error.type timeout, got %q", got)
+	}
+}
+
+func TestStreamingSpan(t *testing.T) {
+	tel := newTelemetry()
+	client := newClient(server(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range []string{
+			`{"type":"message_start","message":{"id":"msg_s","type":"message","role":"assistant","model":"claude-sonnet-4-5-20250929","content":[],"usage":{"input_tokens":9,"output_tokens":1}}}`,
+			`{"type":"content_block_start","index":0,"content_block":{"type":"text","text":""}}`,
+			`{"type":"content_block_delta","index":0,"delta":{"type":"text_delta","text":"Hi"}}`,
+			`{"type":"content_block_stop","index":0}`,
+			`{"type":"message_delta","delta":{"stop_reason":"end_turn","stop_sequence":null},"usage":{"output_tokens":7}}`,
+			`{"type":"message_stop"}`,
+		} {
+			fmt.Fprintf(w, "data: %s\n\n", event)
+		}
+	}), tel.in.RequestOption())
+
+	stream := client.Messages.NewStreaming(context.Background(), messageParams)
+	for stream.Next() {
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatalf("stream: %v", err)
+	}
+	stream.Close()
+
+	span := tel.span(t, "chat claude-sonnet-4-5")
+	if got := attr(span, otelanthropic.AttrResponseID).AsString(); got != "msg_s" {
+		t.Errorf("expected the response ID from message_start, got %q", got)
+	}
+	if got := attr(span, otelanthropic.AttrUsageOutputTokens).AsInt64(); got != 7 {
+		t.Errorf("expected output tokens from message_delta, got %d", got)
+	}
+	if got := attr(span, otelanthropic.AttrResponseFinish).AsStringSlice(); len(got) != 1 || got[0] != "end_turn" {
+		t.Errorf("expected finish reasons [end_turn], got %v", got)
+	}
+	if got := attr(span, otelanthropic.AttrTimeToFirstChunk).AsFloat64(); got <= 0 {
+		t.Errorf("expected a time to first chunk, got %v", got)
+	}
+	ttfc := tel.metric(t, "gen_ai.client.operation.time_to_first_chunk").Data.(metricdata.Histogram[float64])
+	if len(ttfc.DataPoints) != 1 || ttfc.DataPoints[0].Count != 1 {
+		t.Errorf("expected one time-to-first-chunk measurement, got %+v", ttfc.DataPoints)
+	}
+}
+
+type weatherInput struct {
+	City string `json:"city"`
+}
+
+func TestToolRunnerSpans(t *testing.T) {
+	tel := newTelemetry()
+	srv := server(t,
+		jsonReply(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"tool_use",
+			"content":[{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris"}}],
+			"usage":{"input_tokens":10,"output_tokens":5}}`),
+		jsonReply(`{"id":"msg_2","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"end_turn",
+			"content":[{"type":"text","text":"Sunny."}],"usage":{"input_tokens":20,"output_tokens":3}}`),
+	)
+	client := newClient(srv, tel.in.RequestOption())
+
+	tool, err := toolrunner.NewBetaToolFromJSONSchema("get_weather", "Get weather",
+		func(ctx context.Context, in weatherInput) (anthropic.BetaToolResultBlockParamContentUnion, error) {
+			return anthropic.BetaToolResultBlockParamContentUnion{
+				OfText: &anthropic.BetaTextBlockParam{Text: "Sunny in " + in.City},
+			}, nil
+		})
+	if err != nil {
+		t.Fatalf("create tool: %v", err)
+	}
+	runner := client.Beta.Messages.NewToolRunner(tel.in.WrapTools([]anthropic.BetaTool{tool}), anthropic.BetaToolRunnerParams{
+		BetaMessageNewParams: anthropic.BetaMessageNewParams{
+			Model:     anthropic.ModelClaudeSonnet4_5,
+			MaxTokens: 256,
+			Messages:  []anthropic.BetaMessageParam{anthropic.NewBetaUserMessage(anthropic.NewBetaTextBlock("Weather?"))},
+		},
+		MaxIterations: 5,
+	})
+
+	message, err := tel.in.RunToCompletion(context.Background(), runner)
+	if err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+	if message == nil || message.ID != "msg_2" {
+		t.Fatalf("expected the final message, got %+v", message)
+	}
+
+	agent := tel.span(t, "invoke_agent")
+	if got := attr(agent, otelanthropic.AttrUsageInputTokens).AsInt64(); got != 30 {
+		t.Errorf("expected cumulative input tokens 30, got %d", got)
+	}
+	var iterations, chats []tracetest.SpanStub
+	for _, span := range tel.spans.GetSpans() {
+		switch span.Name {
+		case "tool_runner.iteration":
+			iterations = append(iterations, span)
+		case "chat claude-sonnet-4-5":
+			chats = append(chats, span)
+		}
+	}
+	if len(iterations) != 2 || len(chats) != 2 {
+		t.Fatalf("expected an iteration span per chat span, got %d iterations and %d chat spans", len(iterations), len(chats))
+	}
+	for _, span := range iterations {
+		if span.Parent.SpanID() != agent.SpanContext.SpanID() {
+			t.Errorf("expected iteration span to be a child of invoke_agent")
+		}
+	}
+	isIteration := func(span tracetest.SpanStub) bool {
+		for _, it := range iterations {
+			if span.Parent.SpanID() == it.SpanContext.SpanID() {
+				return true
+			}
+		}
+		return false
+	}
+	for _, span := range chats {
+		if !isIteration(span) {
+			t.Errorf("expected chat span to be a child of an iteration span")
+		}
+	}
+	toolSpan := tel.span(t, "execute_tool get_weather")
+	if !isIteration(toolSpan) {
+		t.Errorf("expected tool span to be a child of an iteration span")
+	}
+	if got := attr(toolSpan, otelanthropic.AttrToolName).AsString(); got != "get_weather" {
+		t.Errorf("expected gen_ai.tool.name get_weather, got %q", got)
+	}
+}
@@ -0,0 +1,96 @@
+package otelanthropic
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// WrapTools returns tools wrapped so that each execution runs in an
+// "execute_tool <name>" span. The runner executes tools with the context of
+// the iteration that requested them, so the spans nest under it.
+func (in *Instrumentation) WrapTools(tools []anthropic.BetaTool) []anthropic.BetaTool {
+	wrapped := make([]anthropic.BetaTool, len(tools))
+	for i, tool := range tools {
+		wrapped[i] = tracedTool{BetaTool: tool, in: in}
+	}
+	return wrapped
+}
+
+type tracedTool struct {
+	anthropic.BetaTool
+	in *Instrumentation
+}
+
+func (t tracedTool) Execute(ctx context.Context, input json.RawMessage) ([]anthropic.BetaToolResultBlockParamContentUnion, error) {
+	ctx, span := t.in.tracer.Start(ctx, "execute_tool "+t.Name(),
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			AttrOperationName.String("execute_tool"),
+			AttrToolName.String(t.Name()),
+			AttrToolType.String("function"),
+		))
+	defer span.End()
+
+	content, err := t.BetaTool.Execute(ctx, input)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+		span.SetAttributes(AttrErrorType.String("_OTHER"))
+	}
+	return content, err
+}
+
+// NextMessage runs one iteration of runner in a "tool_runner.iteration" span.
+// The iteration's tool executions (with [Instrumentation.WrapTools]) and API
+// call nest under it. A turn that makes no API call, because it only
+// completes the conversation, gets no span.
+func (in *Instrumentation) NextMessage(ctx context.Context, runner *anthropic.BetaToolRunner) (*anthropic.BetaMessage, error) {
+	if !runner.HasNextTurn() {
+		return runner.NextMessage(ctx)
+	}
+	ctx, span := in.tracer.Start(ctx, "tool_runner.iteration",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(AttrIteration.Int(runner.IterationCount()+1)))
+	defer span.End()
+
+	message, err := runner.NextMessage(ctx)
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return message, err
+}
+
+// RunToCompletion is [anthropic.BetaToolRunner.RunToCompletion] in an
+// "invoke_agent" span, with each iteration run by
+// [Instrumentation.NextMessage]. The span records the runner's cumulative
+// token usage.
+func (in *Instrumentation) RunToCompletion(ctx context.Context, runner *anthropic.BetaToolRunner) (*anthropic.BetaMessage, error) {
+	ctx, span := in.tracer.Start(ctx, "invoke_agent",
+		trace.WithSpanKind(trace.SpanKindInternal),
+		trace.WithAttributes(
+			AttrSystem.String("anthropic"),
+			AttrOperationName.String("invoke_agent"),
+			AttrRequestModel.String(string(runner.Params.Model)),
+		))
+	defer span.End()
+
+	for !runner.IsCompleted() {
+		if _, err := in.NextMessage(ctx, runner); err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+			return nil, err
+		}
+	}
+	usage := runner.Usage()
+	span.SetAttributes(
+		AttrIteration.Int(runner.IterationCount()),
+		AttrUsageInputTokens.Int64(usage.Total.InputTokens),
+		AttrUsageOutputTokens.Int64(usage.Total.OutputTokens),
+	)
+	return runner.LastMessage(), nil
+}
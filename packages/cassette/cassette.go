@@ -0,0 +1,396 @@
+// Package cassette records HTTP interactions with the API to a file and
+// replays them, so that tests can pin real model behavior without making live
+// calls.
+//
+// A [Cassette], installed with option.WithRecorder, sits in front of the
+// client's HTTP transport. When recording, it forwards each request and saves
+// the request and response, with credentials scrubbed. When replaying, it
+// answers each request with the first unused recorded response whose request
+// matches it: same method, path and query, and same body once normalized.
+// Streamed responses are stored verbatim and replay event for event.
+package cassette
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"unicode/utf8"
+
+	"github.com/tidwall/sjson"
+)
+
+// Mode is how a [Cassette] handles requests.
+type Mode int
+
+const (
+	// ModeReplay answers requests from the cassette only. A request with no
+	// matching interaction fails.
+	ModeReplay Mode = iota
+	// ModeRecord sends every request to the API and records the cassette
+	// afresh, replacing any existing file.
+	ModeRecord
+	// ModeReplayOrRecord answers requests from the cassette when it can, and
+	// otherwise sends them to the API and adds them to the cassette.
+	ModeReplayOrRecord
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeReplay:
+		return "replay"
+	case ModeRecord:
+		return "record"
+	case ModeReplayOrRecord:
+		return "replay-or-record"
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// ErrNoInteraction is returned, wrapped, for a request that a replaying
+// cassette has no unused interaction for.
+var ErrNoInteraction = errors.New("cassette: no matching interaction")
+
+// scrubbedHeaders are removed from every recorded request and response.
+var scrubbedHeaders = []string{
+	"Authorization", "X-Api-Key", "Proxy-Authorization", "Cookie", "Set-Cookie",
+	// Provider credentials: the AWS session token and the Google API key.
+	"X-Amz-Security-Token", "X-Goog-Api-Key",
+}
+
+// Options customizes a [Cassette].
+type Options struct {
+	// ScrubHeaders are headers removed from the recorded requests and
+	// responses, in addition to the credential headers that always are.
+	ScrubHeaders []string
+	// IgnoreBodyFields are paths, in gjson/sjson syntax such as
+	// "metadata.user_id", of request body fields that vary between runs and
+	// are left out when matching requests.
+	IgnoreBodyFields []string
+}
+
+// Interaction is one recorded request and its response.
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded request. URL holds the path and query only, so a
+// cassette replays against any base URL.
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   Body        `json:"body,omitempty"`
+}
+
+// Response is a recorded response.
+type Response struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header,omitempty"`
+	Body       Body        `json:"body,omitempty"`
+}
+
+// Body is a recorded body. It is stored as a string when it is valid UTF-8 and
+// base64-encoded otherwise.
+type Body []byte
+
+func (b Body) MarshalJSON() ([]byte, error) {
+	if utf8.Valid(b) {
+		return json.Marshal(string(b))
+	}
+	return json.Marshal(map[string]string{"base64": base64.StdEncoding.EncodeToString(b)})
+}
+
+func (b *Body) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err == nil {
+		*b = Body(s)
+		return nil
+	}
+	var encoded struct {
+		Base64 string `json:"base64"`
+	}
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return err
+	}
+	raw, err := base64.StdEncoding.DecodeString(encoded.Base64)
+	*b = raw
+	return err
+}
+
+type file struct {
+	Version      int            `json:"version"`
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// Cassette is a file of recorded interactions. Its methods are safe for
+// concurrent use.
+type Cassette struct {
+	path string
+	mode Mode
+	opts Options
+
+	mu           sync.Mutex
+	interactions []*Interaction
+	used         []bool
+}
+
+// Open returns the cassette stored at path. In ModeReplay the file must exist;
+// in ModeRecord it is replaced as interactions are recorded.
+func Open(path string, mode Mode, opts Options) (*Cassette, error) {
+	c := &Cassette{path: path, mode: mode, opts: opts}
+	if mode == ModeRecord {
+		return c, nil
+	}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) && mode == ModeReplayOrRecord {
+		return c, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("cassette: %w", err)
+	}
+	var f file
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("cassette: decoding %s: %w", path, err)
+	}
+	c.interactions = f.Interactions
+	c.used = make([]bool, len(f.Interactions))
+	return c, nil
+}
+
+// Interactions returns the cassette's recorded interactions.
+func (c *Cassette) Interactions() []Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	out := make([]Interaction, len(c.interactions))
+	for i, in := range c.interactions {
+		out[i] = *in
+	}
+	return out
+}
+
+// Do answers req from the cassette or sends it with next and records the
+// exchange, depending on the cassette's mode. It has the signature of an
+// option.Middleware.
+func (c *Cassette) Do(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	body, err := readRequestBody(req)
+	if err != nil {
+		return nil, err
+	}
+	key := c.matchKey(req.Method, requestURL(req), req.Header.Get("Content-Type"), body)
+
+	if c.mode != ModeRecord {
+		if in := c.take(key); in != nil {
+			return replay(req, in), nil
+		}
+		if c.mode == ModeReplay {
+			return nil, fmt.Errorf("%w in %s for %s %s", ErrNoInteraction, c.path, req.Method, requestURL(req))
+		}
+	}
+
+	res, err := next(req)
+	if err != nil {
+		return res, err
+	}
+	in := &Interaction{
+		Request: Request{
+			Method: req.Method,
+			URL:    requestURL(req),
+			Header: c.scrub(req.Header),
+			Body:   body,
+		},
+		Response: Response{
+			StatusCode: res.StatusCode,
+			Header:     c.scrub(res.Header),
+		},
+	}
+	res.Body = &recordingBody{rc: res.Body, done: func(data []byte) error {
+		in.Response.Body = data
+		return c.add(in)
+	}}
+	return res, nil
+}
+
+// take returns the first unused interaction matching key, marking it used.
+func (c *Cassette) take(key string) *Interaction {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for i, in := range c.interactions {
+		if c.used[i] {
+			continue
+		}
+		if c.matchKey(in.Request.Method, in.Request.URL, in.Request.Header.Get("Content-Type"), in.Request.Body) == key {
+			c.used[i] = true
+			return in
+		}
+	}
+	return nil
+}
+
+// add appends in to the cassette and saves it.
+func (c *Cassette) add(in *Interaction) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.interactions = append(c.interactions, in)
+	c.used = append(c.used, true)
+	return c.save()
+}
+
+// save writes the cassette to its file, atomically. c.mu must be held.
+func (c *Cassette) save() error {
+	data, err := json.MarshalIndent(file{Version: 1, Interactions: c.interactions}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0o755); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(c.path), filepath.Base(c.path)+".*")
+	if err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), c.path); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
+
+func (c *Cassette) scrub(h http.Header) http.Header {
+	h = h.Clone()
+	for _, name := range slices.Concat(scrubbedHeaders, c.opts.ScrubHeaders) {
+		h.Del(name)
+	}
+	return h
+}
+
+// matchKey returns the string two requests share if they match.
+func (c *Cassette) matchKey(method, url, contentType string, body []byte) string {
+	return method + " " + url + "\n" + string(c.normalizeBody(contentType, body))
+}
+
+// normalizeBody returns body in a canonical form: JSON with the ignored
+// fields removed and its keys sorted, or multipart data with its random
+// boundary replaced.
+func (c *Cassette) normalizeBody(contentType string, body []byte) []byte {
+	mediaType, params, _ := mime.ParseMediaType(contentType)
+	if boundary := params["boundary"]; strings.HasPrefix(mediaType, "multipart/") && boundary != "" {
+		return bytes.ReplaceAll(body, []byte(boundary), []byte("BOUNDARY"))
+	}
+	if !json.Valid(body) {
+		return body
+	}
+	for _, path := range c.opts.IgnoreBodyFields {
+		if b, err := sjson.DeleteBytes(body, path); err == nil {
+			body = b
+		}
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err != nil {
+		return body
+	}
+	canonical, err := json.Marshal(v)
+	if err != nil {
+		return body
+	}
+	return canonical
+}
+
+func requestURL(req *http.Request) string {
+	return req.URL.RequestURI()
+}
+
+// readRequestBody returns req's body, leaving req able to send it.
+func readRequestBody(req *http.Request) ([]byte, error) {
+	if req.Body == nil || req.Body == http.NoBody {
+		return nil, nil
+	}
+	if req.GetBody != nil {
+		rc, err := req.GetBody()
+		if err != nil {
+			return nil, err
+		}
+		defer rc.Close()
+		return io.ReadAll(rc)
+	}
+	body, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	req.Body = io.NopCloser(bytes.NewReader(body))
+	return body, nil
+}
+
+// replay returns the recorded response of in as the response to req.
+func replay(req *http.Request, in *Interaction) *http.Response {
+	header := in.Response.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", in.Response.StatusCode, http.StatusText(in.Response.StatusCode)),
+		StatusCode:    in.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(in.Response.Body)),
+		ContentLength: int64(len(in.Response.Body)),
+		Request:       req,
+	}
+}
+
+// recordingBody passes a response body through, and records it once it has
+// been read to the end or closed. Closing it early reads the rest, so that a
+// stream the caller stopped reading is recorded whole.
+type recordingBody struct {
+	rc   io.ReadCloser
+	buf  bytes.Buffer
+	done func([]byte) error
+	once sync.Once
+	err  error
+}
+
+func (b *recordingBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.finish()
+		if b.err != nil {
+			return n, b.err
+		}
+	}
+	return n, err
+}
+
+func (b *recordingBody) Close() error {
+	io.Copy(&b.buf, b.rc)
+	err := b.rc.Close()
+	b.finish()
+	return errors.Join(err, b.err)
+}
+
+func (b *recordingBody) finish() {
+	b.once.Do(func() { b.err = b.done(bytes.Clone(b.buf.Bytes())) })
+}
@@ -0,0 +1,163 @@
+package cassette_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/lib/anthropictest"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/cassette"
+)
+
+func params(text string) anthropic.MessageNewParams {
+	return anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaudeSonnet4_5,
+		MaxTokens: 1024,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(text))},
+	}
+}
+
+func streamText(t *testing.T, client anthropic.Client, text string) string {
+	t.Helper()
+	stream := client.Messages.NewStreaming(context.Background(), params(text))
+	var msg anthropic.Message
+	for stream.Next() {
+		if err := msg.Accumulate(stream.Current()); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := stream.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return msg.Content[0].Text
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassettes", "agent.json")
+
+	server := anthropictest.NewServer(t)
+	server.Reply(anthropictest.Text("Recorded reply"), anthropictest.Text("Recorded stream"))
+	recording := anthropic.NewClient(
+		option.WithBaseURL(server.URL),
+		option.WithAPIKey("sk-ant-secret"),
+		option.WithHeader("X-Amz-Security-Token", "aws-session-secret"),
+		option.WithRecorder(path, cassette.ModeRecord),
+	)
+	msg, err := recording.Messages.New(context.Background(), params("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content[0].Text != "Recorded reply" {
+		t.Fatalf("unexpected recorded reply %q", msg.Content[0].Text)
+	}
+	if got := streamText(t, recording, "second"); got != "Recorded stream" {
+		t.Fatalf("unexpected recorded stream %q", got)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "sk-ant-secret") || strings.Contains(string(data), "aws-session-secret") {
+		t.Error("expected the credentials to be scrubbed from the cassette")
+	}
+	if !strings.Contains(string(data), "event: message_start") {
+		t.Error("expected the stream to be recorded verbatim")
+	}
+
+	// Replay with no server behind the client.
+	replaying := anthropic.NewClient(
+		option.WithBaseURL("http://127.0.0.1:1"),
+		option.WithAPIKey("another-key"),
+		option.WithMaxRetries(0),
+		option.WithRecorder(path, cassette.ModeReplay),
+	)
+	if got := streamText(t, replaying, "second"); got != "Recorded stream" {
+		t.Errorf("expected the stream to replay, got %q", got)
+	}
+	msg, err = replaying.Messages.New(context.Background(), params("first"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content[0].Text != "Recorded reply" || msg.ID == "" {
+		t.Errorf("expected the reply to replay, got %s", msg.RawJSON())
+	}
+	_, err = replaying.Messages.New(context.Background(), params("first"))
+	if !errors.Is(err, cassette.ErrNoInteraction) {
+		t.Errorf("expected a used interaction not to replay twice, got %v", err)
+	}
+	_, err = replaying.Messages.New(context.Background(), params("never recorded"))
+	if !errors.Is(err, cassette.ErrNoInteraction) {
+		t.Errorf("expected an unrecorded request to fail, got %v", err)
+	}
+}
+
+func TestMatchingNormalizesBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "cassette.json")
+	server := anthropictest.NewServer(t)
+	server.Reply(anthropictest.Text("one"))
+
+	c, err := cassette.Open(path, cassette.ModeReplayOrRecord, cassette.Options{IgnoreBodyFields: []string{"metadata.user_id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := anthropic.NewClient(append(server.Options(), option.WithCassette(c))...)
+	p := params("hello")
+	p.Metadata.UserID = anthropic.String("run-1")
+	if _, err := client.Messages.New(context.Background(), p); err != nil {
+		t.Fatal(err)
+	}
+
+	// Reopen for replay; the user ID differs and the keys are reordered.
+	c, err = cassette.Open(path, cassette.ModeReplay, cassette.Options{IgnoreBodyFields: []string{"metadata.user_id"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	recorded := c.Interactions()
+	if len(recorded) != 1 || recorded[0].Request.Header.Get("X-Api-Key") != "" {
+		t.Fatalf("unexpected recorded interactions: %+v", recorded)
+	}
+	var body map[string]any
+	if err := json.Unmarshal(recorded[0].Request.Body, &body); err != nil {
+		t.Fatal(err)
+	}
+	body["metadata"] = map[string]any{"user_id": "run-2"}
+	reordered, _ := json.Marshal(body)
+
+	replaying := anthropic.NewClient(option.WithBaseURL("http://127.0.0.1:1"), option.WithAPIKey("k"), option.WithMaxRetries(0), option.WithCassette(c))
+	msg, err := replaying.Messages.New(context.Background(), anthropic.MessageNewParams{}, option.WithRequestBody("application/json", reordered))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content[0].Text != "one" {
+		t.Errorf("unexpected replayed reply %q", msg.Content[0].Text)
+	}
+	if server.PendingReplies() != 0 || len(server.Requests()) != 1 {
+		t.Errorf("expected the server to be called once, got %d requests", len(server.Requests()))
+	}
+}
+
+func TestBinaryBodies(t *testing.T) {
+	body := cassette.Body{0xff, 0xfe, 'a'}
+	data, err := json.Marshal(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded cassette.Body
+	if err := json.Unmarshal(data, &decoded); err != nil || string(decoded) != string(body) {
+		t.Errorf("expected %v to round-trip through %s, got %v (%v)", body, data, decoded, err)
+	}
+}
+
+func TestOpenMissingCassette(t *testing.T) {
+	client := anthropic.NewClient(option.WithAPIKey("k"), option.WithRecorder(filepath.Join(t.TempDir(), "missing.json"), cassette.ModeReplay))
+	if _, err := client.Messages.New(context.Background(), params("hi")); err == nil {
+		t.Error("expected replaying a missing cassette to fail")
+	}
+}
@@ -0,0 +1,250 @@
+// Package modelregistry maps first-party Claude model IDs, such as
+// anthropic.ModelClaudeSonnet4_5, to the IDs Amazon Bedrock and Google
+// Vertex AI know the same models by. The bedrock and vertex packages apply
+// the [Default] registry to every request, so first-party model constants
+// work unchanged on either provider.
+//
+// IDs the registry does not know — provider-native IDs, ARNs, models newer
+// than this SDK — are always sent unchanged. Add or override entries with
+// [Registry.Register]:
+//
+//	modelregistry.Default().Register(modelregistry.Entry{
+//		Model:           "claude-sonnet-4-5-20250929",
+//		Aliases:         []string{"claude-sonnet-4-5"},
+//		Bedrock:         "anthropic.claude-sonnet-4-5-20250929-v1:0",
+//		BedrockProfiles: []string{modelregistry.InferenceProfileUS, modelregistry.InferenceProfileGlobal},
+//		Vertex:          "claude-sonnet-4-5@20250929",
+//	})
+package modelregistry
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Bedrock cross-region inference profile geographies. An inference profile
+// ID is the foundation-model ID prefixed with the geography and a dot, such
+// as "us.anthropic.claude-sonnet-4-5-20250929-v1:0".
+const (
+	InferenceProfileUS     = "us"
+	InferenceProfileEU     = "eu"
+	InferenceProfileAPAC   = "apac"
+	InferenceProfileGlobal = "global"
+
+	// InferenceProfileNone selects the foundation-model ID itself, for
+	// provisioned throughput or models that allow on-demand invocation in
+	// the region.
+	InferenceProfileNone = "none"
+)
+
+// Entry is one model in a [Registry].
+type Entry struct {
+	// Model is the first-party ID, dated for models with dated snapshots,
+	// such as "claude-sonnet-4-5-20250929".
+	Model string
+
+	// Aliases are other first-party IDs of the model, such as
+	// "claude-sonnet-4-5".
+	Aliases []string
+
+	// Bedrock is the Bedrock foundation-model ID, such as
+	// "anthropic.claude-sonnet-4-5-20250929-v1:0". Empty when the registry
+	// has no Bedrock ID for the model.
+	Bedrock string
+
+	// BedrockProfiles lists the geographies with a cross-region inference
+	// profile for the model, such as [InferenceProfileUS].
+	BedrockProfiles []string
+
+	// Vertex is the Vertex AI model name, such as "claude-sonnet-4-5@20250929".
+	// Empty when the registry has no Vertex name for the model.
+	Vertex string
+}
+
+// Registry maps model IDs between providers. Create one with [New], or use
+// the built-in [Default]. A Registry is safe for concurrent use.
+type Registry struct {
+	mu      sync.RWMutex
+	entries map[string]Entry
+	// ids indexes entries by first-party ID and alias.
+	ids map[string]string
+}
+
+// New returns a Registry holding entries.
+func New(entries ...Entry) *Registry {
+	r := &Registry{entries: map[string]Entry{}, ids: map[string]string{}}
+	for _, e := range entries {
+		r.Register(e)
+	}
+	return r
+}
+
+var defaultRegistry = New(
+	Entry{
+		Model:           "claude-opus-4-6",
+		Bedrock:         "anthropic.claude-opus-4-6-v1",
+		BedrockProfiles: []string{InferenceProfileUS, InferenceProfileEU, InferenceProfileGlobal},
+		Vertex:          "claude-opus-4-6",
+	},
+	Entry{
+		Model:           "claude-sonnet-4-6",
+		Bedrock:         "anthropic.claude-sonnet-4-6",
+		BedrockProfiles: []string{InferenceProfileUS, InferenceProfileEU, InferenceProfileGlobal},
+		Vertex:          "claude-sonnet-4-6",
+	},
+	Entry{
+		Model:           "claude-opus-4-5-20251101",
+		Aliases:         []string{"claude-opus-4-5"},
+		Bedrock:         "anthropic.claude-opus-4-5-20251101-v1:0",
+		BedrockProfiles: []string{InferenceProfileUS, InferenceProfileEU, InferenceProfileGlobal},
+		Vertex:          "claude-opus-4-5@20251101",
+	},
+	Entry{
+		Model:           "claude-haiku-4-5-20251001",
+		Aliases:         []string{"claude-haiku-4-5"},
+		Bedrock:         "anthropic.claude-haiku-4-5-20251001-v1:0",
+		BedrockProfiles: []string{InferenceProfileUS, InferenceProfileEU, InferenceProfileGlobal},
+		Vertex:          "claude-haiku-4-5@20251001",
+	},
+	Entry{
+		Model:           "claude-sonnet-4-5-20250929",
+		Aliases:         []string{"claude-sonnet-4-5"},
+		Bedrock:         "anthropic.claude-sonnet-4-5-20250929-v1:0",
+		BedrockProfiles: []string{InferenceProfileUS, InferenceProfileEU, InferenceProfileGlobal},
+		Vertex:          "claude-sonnet-4-5@20250929",
+	},
+	Entry{
+		Model:           "claude-opus-4-1-20250805",
+		Aliases:         []string{"claude-opus-4-1"},
+		Bedrock:         "anthropic.claude-opus-4-1-20250805-v1:0",
+		BedrockProfiles: []string{InferenceProfileUS},
+		Vertex:          "claude-opus-4-1@20250805",
+	},
+)
+
+// Default returns the registry the bedrock and vertex packages use unless a
+// request selects another. Entries registered on it apply process-wide.
+func Default() *Registry { return defaultRegistry }
+
+// Register adds e, replacing the entry for e.Model and taking over its
+// aliases from any other entry.
+func (r *Registry) Register(e Entry) {
+	if e.Model == "" {
+		panic("modelregistry: Entry.Model is required")
+	}
+	e.Aliases = slices.Clone(e.Aliases)
+	e.BedrockProfiles = slices.Clone(e.BedrockProfiles)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.entries[e.Model] = e
+	r.ids[e.Model] = e.Model
+	for _, alias := range e.Aliases {
+		r.ids[alias] = e.Model
+	}
+}
+
+// Lookup returns the entry for a first-party model ID or alias.
+func (r *Registry) Lookup(model string) (Entry, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	id, ok := r.ids[model]
+	if !ok {
+		return Entry{}, false
+	}
+	return r.entries[id], true
+}
+
+// Entries returns the registered entries, sorted by model.
+func (r *Registry) Entries() []Entry {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	entries := make([]Entry, 0, len(r.entries))
+	for _, e := range r.entries {
+		entries = append(entries, e)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Model < entries[j].Model })
+	return entries
+}
+
+// BedrockID returns the Bedrock ID for model in the inference profile
+// geography profile, or the foundation-model ID for [InferenceProfileNone].
+// A model without a Bedrock ID in the registry is returned unchanged. It
+// is an error to ask for a profile the model's entry does not list.
+func (r *Registry) BedrockID(model, profile string) (string, error) {
+	e, ok := r.Lookup(model)
+	if !ok || e.Bedrock == "" {
+		return model, nil
+	}
+	if profile == InferenceProfileNone || profile == "" {
+		return e.Bedrock, nil
+	}
+	if !slices.Contains(e.BedrockProfiles, profile) {
+		return "", fmt.Errorf("modelregistry: %s has no %q Bedrock inference profile (available: %s)", e.Model, profile, strings.Join(e.BedrockProfiles, ", "))
+	}
+	return profile + "." + e.Bedrock, nil
+}
+
+// BedrockIDForRegion returns the Bedrock ID for model when invoked in the
+// AWS region: the inference profile of the region's geography when the
+// model has one, otherwise the foundation-model ID. A model without a
+// Bedrock ID in the registry is returned unchanged.
+func (r *Registry) BedrockIDForRegion(model, region string) string {
+	e, ok := r.Lookup(model)
+	if !ok || e.Bedrock == "" {
+		return model
+	}
+	if profile := InferenceProfileForRegion(region); slices.Contains(e.BedrockProfiles, profile) {
+		return profile + "." + e.Bedrock
+	}
+	return e.Bedrock
+}
+
+// VertexID returns the Vertex AI name for model. A model without a Vertex
+// name in the registry is returned unchanged.
+func (r *Registry) VertexID(model string) string {
+	if e, ok := r.Lookup(model); ok && e.Vertex != "" {
+		return e.Vertex
+	}
+	return model
+}
+
+// AnthropicID returns the first-party ID of a Bedrock ID (with or without
+// an inference profile prefix), a Vertex name, or a first-party alias. An
+// unknown ID is returned unchanged.
+func (r *Registry) AnthropicID(id string) string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	if model, ok := r.ids[id]; ok {
+		return model
+	}
+	for _, e := range r.entries {
+		if id != "" && (id == e.Vertex || id == e.Bedrock) {
+			return e.Model
+		}
+		if prefix, ok := strings.CutSuffix(id, "."+e.Bedrock); ok && e.Bedrock != "" && slices.Contains(e.BedrockProfiles, prefix) {
+			return e.Model
+		}
+	}
+	return id
+}
+
+// InferenceProfileForRegion returns the inference profile geography of an
+// AWS region, or "" for regions outside the US, EU and Asia Pacific
+// geographies, such as the GovCloud regions.
+func InferenceProfileForRegion(region string) string {
+	switch {
+	case strings.HasPrefix(region, "us-gov-"):
+		return ""
+	case strings.HasPrefix(region, "us-"):
+		return InferenceProfileUS
+	case strings.HasPrefix(region, "eu-"):
+		return InferenceProfileEU
+	case strings.HasPrefix(region, "ap-"):
+		return InferenceProfileAPAC
+	default:
+		return ""
+	}
+}
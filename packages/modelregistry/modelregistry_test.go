@@ -0,0 +1,154 @@
+package modelregistry_test
+
+import (
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"strconv"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/packages/modelregistry"
+)
+
+func TestDefault(t *testing.T) {
+	r := modelregistry.Default()
+	if got := r.BedrockIDForRegion(anthropic.ModelClaudeOpus4_6, "us-east-1"); got != "us.anthropic.claude-opus-4-6-v1" {
+		t.Errorf("BedrockIDForRegion(%q) = %q", anthropic.ModelClaudeOpus4_6, got)
+	}
+	for _, model := range []string{anthropic.ModelClaudeSonnet4_5, anthropic.ModelClaudeSonnet4_5_20250929} {
+		if got := r.VertexID(model); got != "claude-sonnet-4-5@20250929" {
+			t.Errorf("VertexID(%q) = %q", model, got)
+		}
+		if got := r.BedrockIDForRegion(model, "eu-west-1"); got != "eu.anthropic.claude-sonnet-4-5-20250929-v1:0" {
+			t.Errorf("BedrockIDForRegion(%q) = %q", model, got)
+		}
+	}
+}
+
+func TestBedrockID(t *testing.T) {
+	r := modelregistry.New(modelregistry.Entry{
+		Model:           "claude-test-1-20250101",
+		Aliases:         []string{"claude-test-1"},
+		Bedrock:         "anthropic.claude-test-1-20250101-v1:0",
+		BedrockProfiles: []string{modelregistry.InferenceProfileUS, modelregistry.InferenceProfileGlobal},
+		Vertex:          "claude-test-1@20250101",
+	})
+
+	tests := []struct {
+		model, region, want string
+	}{
+		{"claude-test-1", "us-west-2", "us.anthropic.claude-test-1-20250101-v1:0"},
+		// No eu profile: the foundation model, not another geography.
+		{"claude-test-1", "eu-central-1", "anthropic.claude-test-1-20250101-v1:0"},
+		{"claude-test-1", "us-gov-west-1", "anthropic.claude-test-1-20250101-v1:0"},
+		// Provider IDs and unknown models pass through.
+		{"global.anthropic.claude-test-1-20250101-v1:0", "us-east-1", "global.anthropic.claude-test-1-20250101-v1:0"},
+		{"arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/abc", "us-east-1", "arn:aws:bedrock:us-east-1:123456789012:application-inference-profile/abc"},
+		{"claude-unknown", "us-east-1", "claude-unknown"},
+	}
+	for _, tt := range tests {
+		if got := r.BedrockIDForRegion(tt.model, tt.region); got != tt.want {
+			t.Errorf("BedrockIDForRegion(%q, %q) = %q, want %q", tt.model, tt.region, got, tt.want)
+		}
+	}
+
+	if got, err := r.BedrockID("claude-test-1", modelregistry.InferenceProfileGlobal); err != nil || got != "global.anthropic.claude-test-1-20250101-v1:0" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if got, err := r.BedrockID("claude-test-1", modelregistry.InferenceProfileNone); err != nil || got != "anthropic.claude-test-1-20250101-v1:0" {
+		t.Errorf("got %q, %v", got, err)
+	}
+	if _, err := r.BedrockID("claude-test-1", modelregistry.InferenceProfileEU); err == nil {
+		t.Error("expected a missing profile to fail")
+	}
+}
+
+func TestAnthropicID(t *testing.T) {
+	r := modelregistry.Default()
+	for _, id := range []string{
+		"claude-opus-4-5",
+		"claude-opus-4-5@20251101",
+		"anthropic.claude-opus-4-5-20251101-v1:0",
+		"global.anthropic.claude-opus-4-5-20251101-v1:0",
+	} {
+		if got := r.AnthropicID(id); got != "claude-opus-4-5-20251101" {
+			t.Errorf("AnthropicID(%q) = %q", id, got)
+		}
+	}
+	if got := r.AnthropicID("claude-unknown"); got != "claude-unknown" {
+		t.Errorf("expected an unknown ID to pass through, got %q", got)
+	}
+}
+
+func TestRegisterOverrides(t *testing.T) {
+	r := modelregistry.New(modelregistry.Entry{Model: "claude-test-1-20250101", Aliases: []string{"claude-test-1"}, Vertex: "claude-test-1@20250101"})
+	r.Register(modelregistry.Entry{Model: "claude-test-1-20250101", Aliases: []string{"claude-test-1"}, Vertex: "claude-test-1@20250102"})
+	r.Register(modelregistry.Entry{Model: "claude-test-2-20250301", Aliases: []string{"claude-test"}, Vertex: "claude-test-2@20250301"})
+
+	if got := r.VertexID("claude-test-1"); got != "claude-test-1@20250102" {
+		t.Errorf("expected the override, got %q", got)
+	}
+	if got := r.VertexID("claude-test"); got != "claude-test-2@20250301" {
+		t.Errorf("expected the alias on the new entry, got %q", got)
+	}
+	if n := len(r.Entries()); n != 2 {
+		t.Errorf("expected 2 entries, got %d", n)
+	}
+}
+
+// unregistered lists the anthropic.Model constants the default registry has
+// no entry for, because their Bedrock and Vertex IDs are not yet known to
+// it. Callers register them with [modelregistry.Registry.Register].
+var unregistered = map[string]string{
+	"claude-sonnet-5":       "provider IDs not yet published",
+	"claude-fable-5":        "provider IDs not yet published",
+	"claude-mythos-5":       "provider IDs not yet published",
+	"claude-opus-4-8":       "provider IDs not yet published",
+	"claude-opus-4-7":       "provider IDs not yet published",
+	"claude-mythos-preview": "provider IDs not yet published",
+}
+
+// TestDefaultCoversModels checks that every anthropic.Model constant is
+// either in the default registry or listed in unregistered, so that a new
+// model constant cannot be added without deciding how providers name it.
+func TestDefaultCoversModels(t *testing.T) {
+	file, err := parser.ParseFile(token.NewFileSet(), "../../message.go", nil, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var models []string
+	for _, decl := range file.Decls {
+		gen, ok := decl.(*ast.GenDecl)
+		if !ok || gen.Tok != token.CONST {
+			continue
+		}
+		for _, spec := range gen.Specs {
+			vs := spec.(*ast.ValueSpec)
+			if typ, ok := vs.Type.(*ast.Ident); !ok || typ.Name != "Model" {
+				continue
+			}
+			for _, v := range vs.Values {
+				if lit, ok := v.(*ast.BasicLit); ok {
+					model, _ := strconv.Unquote(lit.Value)
+					models = append(models, model)
+				}
+			}
+		}
+	}
+	if len(models) == 0 {
+		t.Fatal("found no anthropic.Model constants")
+	}
+
+	r := modelregistry.Default()
+	for _, model := range models {
+		_, registered := r.Lookup(model)
+		_, excluded := unregistered[model]
+		switch {
+		case registered && excluded:
+			t.Errorf("%s is registered, remove it from unregistered", model)
+		case !registered && !excluded:
+			t.Errorf("%s has no registry entry: register its Bedrock and Vertex IDs or list it in unregistered", model)
+		}
+	}
+}
@@ -0,0 +1,297 @@
+// Package partialjson parses JSON documents that have been cut off part-way,
+// such as the text or tool input of a message that is still streaming.
+//
+// [Complete] repairs a truncated document into the longest valid one it
+// implies: an unterminated string is closed, unclosed arrays and objects are
+// closed, and anything that cannot yet be completed (an object key without a
+// value, a half-written number or literal, a trailing comma) is dropped.
+// [Unmarshal] decodes the repaired document.
+package partialjson
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// Complete returns the valid JSON document that the possibly truncated data
+// begins. It returns nil if data does not yet contain the start of a value, and
+// an error if data is not the prefix of any valid JSON document.
+//
+//	Complete([]byte(`{"name":"Par`))           // {"name":"Par"}
+//	Complete([]byte(`{"tags":["a","b`))        // {"tags":["a","b"]}
+//	Complete([]byte(`{"city":"Paris","units`)) // {"city":"Paris"}
+func Complete(data []byte) ([]byte, error) {
+	p := parser{data: data, safeEnd: -1}
+	return p.complete()
+}
+
+// Unmarshal decodes the document that the possibly truncated data begins into
+// v, as [json.Unmarshal] does. Fields whose values have not started arriving
+// are left untouched, and strings hold the text received so far. If data does
+// not yet contain the start of a value, v is left unchanged.
+func Unmarshal(data []byte, v any) error {
+	completed, err := Complete(data)
+	if err != nil || completed == nil {
+		return err
+	}
+	return json.Unmarshal(completed, v)
+}
+
+type expect int
+
+const (
+	expectValue expect = iota
+	expectValueOrEnd
+	expectKey
+	expectKeyOrEnd
+	expectColon
+	expectCommaOrEnd
+	expectNothing
+)
+
+type parser struct {
+	data   []byte
+	pos    int
+	stack  []byte
+	expect expect
+
+	// safeEnd and safeClosers record the last point at which the document
+	// could be completed by closing the open containers: data[:safeEnd] +
+	// safeClosers is valid JSON. safeEnd is -1 until such a point is seen.
+	safeEnd     int
+	safeClosers string
+}
+
+func (p *parser) complete() ([]byte, error) {
+	for {
+		p.skipSpace()
+		if p.pos >= len(p.data) {
+			return p.fallback(), nil
+		}
+		c := p.data[p.pos]
+		switch p.expect {
+		case expectNothing:
+			return nil, p.errorf("unexpected data after top-level value")
+
+		case expectValue, expectValueOrEnd:
+			switch {
+			case c == ']' && p.expect == expectValueOrEnd:
+				p.pos++
+				p.pop()
+			case c == '{':
+				p.push('{', expectKeyOrEnd)
+			case c == '[':
+				p.push('[', expectValueOrEnd)
+			case c == '"':
+				end, ok := p.scanString()
+				if !ok {
+					// A truncated string value can be closed where it stops.
+					return p.close(string(p.data[:end]) + `"`), nil
+				}
+				p.pos = end
+				p.valueDone()
+			case c == '-' || (c >= '0' && c <= '9'):
+				end := p.scanNumber()
+				if end == len(p.data) {
+					number := strings.TrimRight(string(p.data[p.pos:end]), "+-.eE")
+					if number == "" {
+						return p.fallback(), nil
+					}
+					return p.close(string(p.data[:p.pos]) + number), nil
+				}
+				p.pos = end
+				p.valueDone()
+			case c == 't' || c == 'f' || c == 'n':
+				complete, err := p.scanLiteral()
+				if err != nil {
+					return nil, err
+				}
+				if !complete {
+					return p.fallback(), nil
+				}
+				p.valueDone()
+			default:
+				return nil, p.errorf("unexpected character %q", c)
+			}
+
+		case expectKey, expectKeyOrEnd:
+			switch {
+			case c == '}' && p.expect == expectKeyOrEnd:
+				p.pos++
+				p.pop()
+			case c == '"':
+				end, ok := p.scanString()
+				if !ok {
+					return p.fallback(), nil
+				}
+				p.pos = end
+				p.expect = expectColon
+			default:
+				return nil, p.errorf("unexpected character %q, expecting an object key", c)
+			}
+
+		case expectColon:
+			if c != ':' {
+				return nil, p.errorf("unexpected character %q, expecting ':'", c)
+			}
+			p.pos++
+			p.expect = expectValue
+
+		case expectCommaOrEnd:
+			top := p.stack[len(p.stack)-1]
+			switch {
+			case c == ',':
+				p.pos++
+				if top == '{' {
+					p.expect = expectKey
+				} else {
+					p.expect = expectValue
+				}
+			case (c == '}' && top == '{') || (c == ']' && top == '['):
+				p.pos++
+				p.pop()
+			default:
+				return nil, p.errorf("unexpected character %q", c)
+			}
+		}
+	}
+}
+
+func (p *parser) skipSpace() {
+	for p.pos < len(p.data) {
+		switch p.data[p.pos] {
+		case ' ', '\t', '\n', '\r':
+			p.pos++
+		default:
+			return
+		}
+	}
+}
+
+// push opens a container at the current position.
+func (p *parser) push(open byte, next expect) {
+	p.pos++
+	p.stack = append(p.stack, open)
+	p.expect = next
+	p.markSafe()
+}
+
+// pop closes the innermost container, which completes a value.
+func (p *parser) pop() {
+	p.stack = p.stack[:len(p.stack)-1]
+	p.valueDone()
+}
+
+func (p *parser) valueDone() {
+	if len(p.stack) == 0 {
+		p.expect = expectNothing
+	} else {
+		p.expect = expectCommaOrEnd
+	}
+	p.markSafe()
+}
+
+func (p *parser) markSafe() {
+	p.safeEnd = p.pos
+	p.safeClosers = p.closers()
+}
+
+func (p *parser) closers() string {
+	var b strings.Builder
+	for i := len(p.stack) - 1; i >= 0; i-- {
+		if p.stack[i] == '{' {
+			b.WriteByte('}')
+		} else {
+			b.WriteByte(']')
+		}
+	}
+	return b.String()
+}
+
+// close completes the document prefix by closing the open containers.
+func (p *parser) close(prefix string) []byte {
+	return []byte(prefix + p.closers())
+}
+
+// fallback completes the document at the last safe point.
+func (p *parser) fallback() []byte {
+	if p.safeEnd < 0 {
+		return nil
+	}
+	return []byte(string(p.data[:p.safeEnd]) + p.safeClosers)
+}
+
+// scanString scans the string starting at p.pos. If it is terminated, it
+// returns the position after the closing quote and true. Otherwise it returns
+// the end of the last complete character, so that a closing quote can be
+// appended there, and false.
+func (p *parser) scanString() (int, bool) {
+	i := p.pos + 1
+	for i < len(p.data) {
+		switch c := p.data[i]; {
+		case c == '"':
+			return i + 1, true
+		case c == '\\':
+			n := 2
+			if i+1 < len(p.data) && p.data[i+1] == 'u' {
+				n = 6
+			}
+			if i+n > len(p.data) {
+				return i, false
+			}
+			i += n
+		case c < utf8.RuneSelf:
+			i++
+		default:
+			if !utf8.FullRune(p.data[i:]) {
+				return i, false
+			}
+			_, size := utf8.DecodeRune(p.data[i:])
+			i += size
+		}
+	}
+	return i, false
+}
+
+func (p *parser) scanNumber() int {
+	i := p.pos
+	for i < len(p.data) {
+		switch c := p.data[i]; {
+		case c >= '0' && c <= '9', c == '-', c == '+', c == '.', c == 'e', c == 'E':
+			i++
+		default:
+			return i
+		}
+	}
+	return i
+}
+
+// scanLiteral scans true, false or null at p.pos, reporting whether the
+// literal is complete.
+func (p *parser) scanLiteral() (bool, error) {
+	var literal string
+	switch p.data[p.pos] {
+	case 't':
+		literal = "true"
+	case 'f':
+		literal = "false"
+	default:
+		literal = "null"
+	}
+	rest := p.data[p.pos:]
+	n := min(len(rest), len(literal))
+	if string(rest[:n]) != literal[:n] {
+		return false, p.errorf("invalid literal")
+	}
+	if n < len(literal) {
+		return false, nil
+	}
+	p.pos += n
+	return true, nil
+}
+
+func (p *parser) errorf(format string, args ...any) error {
+	return fmt.Errorf("partialjson: "+format+" at offset %d", append(args, p.pos)...)
+}
@@ -0,0 +1,108 @@
+package partialjson_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/anthropics/anthropic-sdk-go/packages/partialjson"
+)
+
+func TestComplete(t *testing.T) {
+	cases := map[string]string{
+		``:                              ``,
+		`  `:                            ``,
+		`{`:                             `{}`,
+		`{"na`:                          `{}`,
+		`{"name"`:                       `{}`,
+		`{"name":`:                      `{}`,
+		`{"name": "Par`:                 `{"name": "Par"}`,
+		`{"name":"Paris"`:               `{"name":"Paris"}`,
+		`{"name":"Paris",`:              `{"name":"Paris"}`,
+		`{"name":"Paris","units`:        `{"name":"Paris"}`,
+		`{"tags":["a","b`:               `{"tags":["a","b"]}`,
+		`{"tags":["a",`:                 `{"tags":["a"]}`,
+		`{"n":12`:                       `{"n":12}`,
+		`{"n":1.`:                       `{"n":1}`,
+		`{"n":1e+`:                      `{"n":1}`,
+		`{"n":-`:                        `{}`,
+		`{"ok":tr`:                      `{}`,
+		`{"ok":true`:                    `{"ok":true}`,
+		`{"a":null,"b":fa`:              `{"a":null}`,
+		`{"s":"line\`:                   `{"s":"line"}`,
+		`{"s":"snow \u26`:               `{"s":"snow "}`,
+		`{"s":"caf` + "\xc3":            `{"s":"caf"}`,
+		`{"a":[{"b":1},{"c":[2,{"d":"x`: `{"a":[{"b":1},{"c":[2,{"d":"x"}]}]}`,
+		`{"a":[{"b":1},{"c":[2,{"d`:     `{"a":[{"b":1},{"c":[2,{}]}]}`,
+		`[1,2`:                          `[1,2]`,
+		`"hel`:                          `"hel"`,
+		`{"done":{}}`:                   `{"done":{}}`,
+		"{\"a\":\"b\"}\n":               `{"a":"b"}`,
+	}
+	for input, want := range cases {
+		got, err := partialjson.Complete([]byte(input))
+		if err != nil {
+			t.Errorf("Complete(%q): unexpected error: %v", input, err)
+			continue
+		}
+		if string(got) != want {
+			t.Errorf("Complete(%q) = %q, want %q", input, got, want)
+		}
+		if got != nil && !json.Valid(got) {
+			t.Errorf("Complete(%q) = %q is not valid JSON", input, got)
+		}
+	}
+}
+
+func TestCompleteInvalid(t *testing.T) {
+	for _, input := range []string{`{1`, `{"a" 1`, `[1}`, `{"a":tx`, `{"a":1}}`, `]`} {
+		if got, err := partialjson.Complete([]byte(input)); err == nil {
+			t.Errorf("Complete(%q) = %q, expected an error", input, got)
+		}
+	}
+}
+
+func TestCompleteEveryPrefix(t *testing.T) {
+	doc := `{"city": "São Paulo", "days": [1, 2.5e1, -3], "wind": {"speed": 12, "gusts": null, "ok": false}, "note": "a \"quoted\" é"}`
+	for i := range len(doc) + 1 {
+		got, err := partialjson.Complete([]byte(doc[:i]))
+		if err != nil {
+			t.Fatalf("Complete(%q): unexpected error: %v", doc[:i], err)
+		}
+		if got != nil && !json.Valid(got) {
+			t.Fatalf("Complete(%q) = %q is not valid JSON", doc[:i], got)
+		}
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	type weather struct {
+		City string   `json:"city"`
+		Days int      `json:"days"`
+		Tags []string `json:"tags"`
+	}
+
+	var w weather
+	if err := partialjson.Unmarshal([]byte(`{"city":"San Fran`), &w); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.City != "San Fran" || w.Days != 0 {
+		t.Errorf("unexpected partial value: %+v", w)
+	}
+	if err := partialjson.Unmarshal([]byte(`{"city":"San Francisco","days":3,"tags":["wi`), &w); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if w.City != "San Francisco" || w.Days != 3 || len(w.Tags) != 1 || w.Tags[0] != "wi" {
+		t.Errorf("unexpected partial value: %+v", w)
+	}
+
+	var m map[string]any
+	if err := partialjson.Unmarshal(nil, &m); err != nil || m != nil {
+		t.Errorf("expected empty input to leave the map unchanged, got %v, %v", m, err)
+	}
+	if err := partialjson.Unmarshal([]byte(`{"a":{"b":[true`), &m); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if b := m["a"].(map[string]any)["b"].([]any); len(b) != 1 || b[0] != true {
+		t.Errorf("unexpected partial map: %v", m)
+	}
+}
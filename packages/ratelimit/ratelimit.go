@@ -0,0 +1,221 @@
+// Package ratelimit parses the API's rate-limit response headers and provides
+// a client-side limiter that paces requests by them.
+//
+// Every API response reports the organization's remaining request and token
+// budget in anthropic-ratelimit-* headers. [FromHeader] turns those headers
+// into a [Snapshot]; a [Limiter], installed with option.WithRateLimiter, uses
+// them to space out requests so that clients sharing an API key stop running
+// into 429 responses.
+package ratelimit
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Bucket is the state of one rate limit.
+type Bucket struct {
+	// Limit is the maximum allowed within the rate-limit period.
+	Limit int64
+	// Remaining is the amount left before the limit is reached.
+	Remaining int64
+	// Reset is when the limit will be fully replenished.
+	Reset time.Time
+	// Valid reports whether the response carried this limit's headers.
+	Valid bool
+}
+
+// exhausted reports whether the limit is used up as of now.
+func (b Bucket) exhausted(now time.Time) bool {
+	return b.Valid && b.Remaining <= 0 && b.Reset.After(now)
+}
+
+// Snapshot is the rate-limit state reported by a single response.
+type Snapshot struct {
+	// Requests is the requests-per-minute limit.
+	Requests Bucket
+	// Tokens is the most restrictive of the token limits currently in effect.
+	Tokens Bucket
+	// InputTokens is the input-tokens-per-minute limit.
+	InputTokens Bucket
+	// OutputTokens is the output-tokens-per-minute limit.
+	OutputTokens Bucket
+	// RetryAfter is the wait the server asked for, if any. It is usually only
+	// sent with 429 responses.
+	RetryAfter time.Duration
+}
+
+// ExhaustedUntil returns the time at which every limit the snapshot reports
+// as used up has reset. It reports false if no limit is used up.
+func (s Snapshot) ExhaustedUntil() (time.Time, bool) {
+	var until time.Time
+	now := time.Now()
+	for _, b := range s.buckets() {
+		if b.exhausted(now) && b.Reset.After(until) {
+			until = b.Reset
+		}
+	}
+	return until, !until.IsZero()
+}
+
+func (s Snapshot) buckets() []Bucket {
+	return []Bucket{s.Requests, s.Tokens, s.InputTokens, s.OutputTokens}
+}
+
+// FromHeader parses the anthropic-ratelimit-* and retry-after headers of a
+// response. It reports false if none of them are present.
+func FromHeader(header http.Header) (Snapshot, bool) {
+	s := Snapshot{
+		Requests:     parseBucket(header, "requests"),
+		Tokens:       parseBucket(header, "tokens"),
+		InputTokens:  parseBucket(header, "input-tokens"),
+		OutputTokens: parseBucket(header, "output-tokens"),
+	}
+	ok := false
+	for _, b := range s.buckets() {
+		ok = ok || b.Valid
+	}
+	if v := header.Get("retry-after"); v != "" {
+		if seconds, err := strconv.ParseFloat(v, 64); err == nil && seconds >= 0 {
+			s.RetryAfter = time.Duration(seconds * float64(time.Second))
+			ok = true
+		}
+	}
+	return s, ok
+}
+
+func parseBucket(header http.Header, name string) Bucket {
+	prefix := "anthropic-ratelimit-" + name + "-"
+	var b Bucket
+	if v, err := strconv.ParseInt(header.Get(prefix+"limit"), 10, 64); err == nil {
+		b.Limit = v
+		b.Valid = true
+	}
+	if v, err := strconv.ParseInt(header.Get(prefix+"remaining"), 10, 64); err == nil {
+		b.Remaining = v
+		b.Valid = true
+	} else {
+		// Without a remaining count the limit cannot be acted on.
+		return Bucket{}
+	}
+	if v, err := time.Parse(time.RFC3339, header.Get(prefix+"reset")); err == nil {
+		b.Reset = v
+	}
+	return b
+}
+
+// Limiter paces requests by the rate-limit headers of earlier responses. It
+// spreads the remaining request budget evenly over the time until it resets,
+// holds every request while a limit is used up, and after a 429 holds every
+// request for the Retry-After period, so concurrent requests back off
+// together instead of each being rejected in turn.
+//
+// A Limiter is safe for concurrent use and should be shared by every client
+// that uses the same API key.
+type Limiter struct {
+	mu       sync.Mutex
+	snapshot Snapshot
+	observed bool
+	// reserved counts the requests started since the last observed
+	// response, which its request budget does not reflect yet.
+	reserved int64
+	// next is the earliest time the next request may start.
+	next time.Time
+	// blockedUntil holds all requests after a 429.
+	blockedUntil time.Time
+}
+
+// NewLimiter returns a Limiter with no rate-limit information, which lets
+// requests through until a response reports the limits.
+func NewLimiter() *Limiter {
+	return &Limiter{}
+}
+
+// Snapshot returns the most recent rate-limit state the Limiter has observed.
+func (l *Limiter) Snapshot() (Snapshot, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.snapshot, l.observed
+}
+
+// Wait blocks until a request may be sent, or until ctx is done.
+func (l *Limiter) Wait(ctx context.Context) error {
+	delay := time.Until(l.reserve(time.Now()))
+	if delay <= 0 {
+		return ctx.Err()
+	}
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// reserve claims the next request slot and returns when it starts.
+func (l *Limiter) reserve(now time.Time) time.Time {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	start := now
+	if l.next.After(start) {
+		start = l.next
+	}
+	if l.blockedUntil.After(start) {
+		start = l.blockedUntil
+	}
+	// Count reserved requests against the budget until a response reports
+	// the server's view, so concurrent callers are spaced out as well.
+	snapshot := l.snapshot
+	snapshot.Requests.Remaining -= l.reserved
+	for _, b := range snapshot.buckets() {
+		if b.exhausted(start) {
+			start = b.Reset
+		}
+	}
+
+	var interval time.Duration
+	requests := snapshot.Requests
+	if requests.Valid && requests.Reset.After(start) && requests.Remaining > 0 {
+		interval = requests.Reset.Sub(start) / time.Duration(requests.Remaining)
+		l.reserved++
+	}
+	l.next = start.Add(interval)
+	return start
+}
+
+// Observe updates the Limiter from a response.
+func (l *Limiter) Observe(res *http.Response) {
+	snapshot, ok := FromHeader(res.Header)
+	if !ok {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.snapshot = snapshot
+	l.observed = true
+	l.reserved = 0
+	if res.StatusCode == http.StatusTooManyRequests && snapshot.RetryAfter > 0 {
+		if until := time.Now().Add(snapshot.RetryAfter); until.After(l.blockedUntil) {
+			l.blockedUntil = until
+		}
+	}
+}
+
+// Do waits for a request slot, sends req with next, and observes the
+// response. It has the signature of an option.Middleware.
+func (l *Limiter) Do(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	if err := l.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	res, err := next(req)
+	if res != nil {
+		l.Observe(res)
+	}
+	return res, err
+}
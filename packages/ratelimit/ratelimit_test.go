@@ -0,0 +1,192 @@
+package ratelimit_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/ratelimit"
+)
+
+const okBody = `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"end_turn","content":[{"type":"text","text":"Hi"}],"usage":{"input_tokens":1,"output_tokens":1}}`
+
+func setBucket(h http.Header, name string, limit, remaining int64, reset time.Time) {
+	h.Set("anthropic-ratelimit-"+name+"-limit", fmt.Sprint(limit))
+	h.Set("anthropic-ratelimit-"+name+"-remaining", fmt.Sprint(remaining))
+	h.Set("anthropic-ratelimit-"+name+"-reset", reset.UTC().Format(time.RFC3339))
+}
+
+func newMessage(client anthropic.Client) error {
+	_, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
+		Model:     anthropic.ModelClaudeSonnet4_5,
+		MaxTokens: 16,
+		Messages:  []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("Hello"))},
+	})
+	return err
+}
+
+func TestFromHeader(t *testing.T) {
+	reset := time.Now().Add(30 * time.Second).Truncate(time.Second)
+	h := http.Header{}
+	setBucket(h, "requests", 50, 49, reset)
+	setBucket(h, "input-tokens", 30000, 0, reset)
+	h.Set("retry-after", "7")
+
+	s, ok := ratelimit.FromHeader(h)
+	if !ok {
+		t.Fatalf("expected rate-limit headers to be found")
+	}
+	if !s.Requests.Valid || s.Requests.Limit != 50 || s.Requests.Remaining != 49 || !s.Requests.Reset.Equal(reset) {
+		t.Errorf("unexpected requests bucket %+v", s.Requests)
+	}
+	if s.Tokens.Valid || s.OutputTokens.Valid {
+		t.Errorf("expected missing buckets to be invalid")
+	}
+	if s.RetryAfter != 7*time.Second {
+		t.Errorf("expected retry-after of 7s, got %s", s.RetryAfter)
+	}
+	if until, ok := s.ExhaustedUntil(); !ok || !until.Equal(reset) {
+		t.Errorf("expected input tokens to be exhausted until %s, got %s (%v)", reset, until, ok)
+	}
+
+	if _, ok := ratelimit.FromHeader(http.Header{}); ok {
+		t.Errorf("expected no snapshot without headers")
+	}
+}
+
+func TestLimiter_WaitsForExhaustedLimit(t *testing.T) {
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		starts = append(starts, time.Now())
+		setBucket(w.Header(), "requests", 50, 0, time.Now().Add(1500*time.Millisecond))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, okBody)
+	}))
+	defer server.Close()
+
+	limiter := ratelimit.NewLimiter()
+	client := anthropic.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test-key"), option.WithRateLimiter(limiter))
+	for range 2 {
+		if err := newMessage(client); err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+	}
+	// Reset headers have second precision.
+	if gap := starts[1].Sub(starts[0]); gap < 400*time.Millisecond {
+		t.Errorf("expected the second request to wait for the reset, it came after %s", gap)
+	}
+	if s, ok := limiter.Snapshot(); !ok || s.Requests.Limit != 50 {
+		t.Errorf("expected limiter to record the snapshot, got %+v", s)
+	}
+}
+
+func TestLimiter_SpacesConcurrentRequests(t *testing.T) {
+	var mu sync.Mutex
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		starts = append(starts, time.Now())
+		mu.Unlock()
+		setBucket(w.Header(), "requests", 4, 4, time.Now().Add(2*time.Second))
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, okBody)
+	}))
+	defer server.Close()
+
+	client := anthropic.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test-key"), option.WithRateLimiter(ratelimit.NewLimiter()))
+	if err := newMessage(client); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+
+	var wg sync.WaitGroup
+	for range 3 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := newMessage(client); err != nil {
+				t.Errorf("request failed: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(starts) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(starts))
+	}
+	// The remaining budget is spread over at least a second, so the three
+	// concurrent requests cannot all go out at once.
+	if spread := starts[3].Sub(starts[1]); spread < 300*time.Millisecond {
+		t.Errorf("expected concurrent requests to be spaced out, they spanned %s", spread)
+	}
+}
+
+func TestLimiter_SnapshotIsServerReported(t *testing.T) {
+	limiter := ratelimit.NewLimiter()
+	res := &http.Response{StatusCode: http.StatusOK, Header: http.Header{}}
+	setBucket(res.Header, "requests", 50, 4, time.Now().Add(time.Minute))
+	limiter.Observe(res)
+
+	// Reservations count against the budget locally, but are not part of
+	// the server-reported snapshot.
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for range 3 {
+		_ = limiter.Wait(ctx)
+	}
+	if s, ok := limiter.Snapshot(); !ok || s.Requests.Remaining != 4 {
+		t.Errorf("expected the snapshot to keep the server's remaining count, got %+v", s.Requests)
+	}
+}
+
+func TestRateLimitedError(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		setBucket(w.Header(), "tokens", 1000, 0, time.Now().Add(time.Minute))
+		w.Header().Set("retry-after", "0")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusTooManyRequests)
+		fmt.Fprint(w, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+	}))
+	defer server.Close()
+
+	client := anthropic.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test-key"), option.WithMaxRetries(0))
+	err := newMessage(client)
+	var apierr *anthropic.Error
+	if !errors.As(err, &apierr) {
+		t.Fatalf("expected API error, got %v", err)
+	}
+	s, ok := apierr.RateLimit()
+	if !ok || !s.Tokens.Valid || s.Tokens.Remaining != 0 || s.Tokens.Limit != 1000 {
+		t.Errorf("unexpected rate-limit snapshot %+v", s)
+	}
+}
+
+func TestRetryWaitsForRateLimitReset(t *testing.T) {
+	var starts []time.Time
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		starts = append(starts, time.Now())
+		w.Header().Set("Content-Type", "application/json")
+		if len(starts) == 1 {
+			setBucket(w.Header(), "requests", 50, 0, time.Now().Add(1500*time.Millisecond))
+			w.WriteHeader(http.StatusTooManyRequests)
+			fmt.Fprint(w, `{"type":"error","error":{"type":"rate_limit_error","message":"slow down"}}`)
+			return
+		}
+		fmt.Fprint(w, okBody)
+	}))
+	defer server.Close()
+
+	client := anthropic.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test-key"), option.WithMaxRetries(1))
+	if err := newMessage(client); err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	if gap := starts[1].Sub(starts[0]); gap < 400*time.Millisecond {
+		t.Errorf("expected the retry to wait for the reset, it came after %s", gap)
+	}
+}
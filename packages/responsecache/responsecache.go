@@ -0,0 +1,262 @@
+// Package responsecache caches Messages responses on the client, so that
+// repeating an identical request, as evaluation and development runs often
+// do, returns the earlier response without calling the API.
+//
+// A [Cache], installed with option.WithResponseCache, keys each Messages
+// request on a hash of its canonicalized body, API version and betas. Both
+// JSON responses and streams are cached; a cached stream replays its events
+// verbatim. Only requests that ask for deterministic output, with a
+// temperature of 0, are cached unless [Options.AlwaysCache] is set, since the
+// API samples at a temperature of 1 by default.
+package responsecache
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/tidwall/gjson"
+	"github.com/tidwall/sjson"
+)
+
+// Header is set to "hit" on responses served from the cache.
+const Header = "X-Response-Cache"
+
+// Entry is a cached response.
+type Entry struct {
+	Header http.Header `json:"header"`
+	Body   []byte      `json:"body"`
+	// Expires is when the entry stops being served. The zero time means never.
+	Expires time.Time `json:"expires,omitzero"`
+}
+
+func (e *Entry) expired(now time.Time) bool {
+	return !e.Expires.IsZero() && now.After(e.Expires)
+}
+
+// Store holds cached responses. Implementations must be safe for concurrent
+// use.
+type Store interface {
+	// Get returns the entry stored under key, or nil if there is none.
+	Get(ctx context.Context, key string) (*Entry, error)
+	// Put stores entry under key, replacing any earlier entry.
+	Put(ctx context.Context, key string, entry *Entry) error
+}
+
+// Options configures a [Cache].
+type Options struct {
+	// TTL is how long a response is served from the cache. When set to 0 (the
+	// default), cached responses do not expire.
+	TTL time.Duration
+	// AlwaysCache caches requests whatever their temperature. By default,
+	// only requests with a temperature of 0 are cached, as the responses to
+	// others are meant to vary.
+	AlwaysCache bool
+	// IgnoreBodyFields are paths, in gjson/sjson syntax, of request body
+	// fields left out of the cache key. Defaults to "metadata".
+	IgnoreBodyFields []string
+	// OnError, if set, is called with errors of the Store. They do not fail
+	// the request, which is then sent to the API instead.
+	OnError func(error)
+}
+
+// Cache caches Messages responses in a [Store]. Create one with [New].
+type Cache struct {
+	store Store
+	opts  Options
+	now   func() time.Time
+}
+
+// New returns a Cache that keeps responses in store.
+func New(store Store, opts Options) *Cache {
+	if opts.IgnoreBodyFields == nil {
+		opts.IgnoreBodyFields = []string{"metadata"}
+	}
+	return &Cache{store: store, opts: opts, now: time.Now}
+}
+
+type bypassKey struct{}
+
+// Bypass returns a context whose requests neither use nor fill the cache.
+func Bypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+// Do serves req from the cache if it can, and otherwise sends it with next
+// and caches a successful response. A stream is only cached once it has
+// ended with message_stop and no error event. It has the signature of an
+// option.Middleware.
+func (c *Cache) Do(req *http.Request, next func(*http.Request) (*http.Response, error)) (*http.Response, error) {
+	body, ok := c.cacheable(req)
+	if !ok {
+		return next(req)
+	}
+	key := c.Key(req, body)
+
+	entry, err := c.store.Get(req.Context(), key)
+	if err != nil {
+		c.reportError(err)
+	}
+	if entry != nil && !entry.expired(c.now()) {
+		return hit(req, entry), nil
+	}
+
+	res, err := next(req)
+	if err != nil || res.StatusCode != http.StatusOK {
+		return res, err
+	}
+	header := res.Header.Clone()
+	header.Del("Set-Cookie")
+	res.Body = &fillingBody{rc: res.Body, done: func(data []byte) {
+		if isEventStream(header) && !completeStream(data) {
+			return
+		}
+		entry := &Entry{Header: header, Body: data}
+		if c.opts.TTL > 0 {
+			entry.Expires = c.now().Add(c.opts.TTL)
+		}
+		if err := c.store.Put(context.WithoutCancel(req.Context()), key, entry); err != nil {
+			c.reportError(err)
+		}
+	}}
+	return res, nil
+}
+
+// cacheable returns req's body if the response to req may be cached.
+func (c *Cache) cacheable(req *http.Request) ([]byte, bool) {
+	if req.Method != http.MethodPost || !strings.HasSuffix(req.URL.Path, "/v1/messages") {
+		return nil, false
+	}
+	if bypass, _ := req.Context().Value(bypassKey{}).(bool); bypass {
+		return nil, false
+	}
+	if req.GetBody == nil {
+		return nil, false
+	}
+	rc, err := req.GetBody()
+	if err != nil {
+		return nil, false
+	}
+	defer rc.Close()
+	body, err := io.ReadAll(rc)
+	if err != nil || !json.Valid(body) {
+		return nil, false
+	}
+	if !c.opts.AlwaysCache {
+		if temperature := gjson.GetBytes(body, "temperature"); !temperature.Exists() || temperature.Float() != 0 {
+			return nil, false
+		}
+	}
+	return body, true
+}
+
+// Key returns the cache key of req, a Messages request with the given body:
+// a hash of its path and query, API version, betas and canonicalized body.
+func (c *Cache) Key(req *http.Request, body []byte) string {
+	for _, path := range c.opts.IgnoreBodyFields {
+		if b, err := sjson.DeleteBytes(body, path); err == nil {
+			body = b
+		}
+	}
+	var v any
+	dec := json.NewDecoder(bytes.NewReader(body))
+	dec.UseNumber()
+	if err := dec.Decode(&v); err == nil {
+		if canonical, err := json.Marshal(v); err == nil {
+			body = canonical
+		}
+	}
+	h := sha256.New()
+	for _, part := range []string{
+		req.URL.Path,
+		req.URL.RawQuery,
+		req.Header.Get("Anthropic-Version"),
+		strings.Join(req.Header.Values("Anthropic-Beta"), ","),
+	} {
+		io.WriteString(h, part)
+		h.Write([]byte{0})
+	}
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (c *Cache) reportError(err error) {
+	if c.opts.OnError != nil {
+		c.opts.OnError(err)
+	}
+}
+
+func hit(req *http.Request, entry *Entry) *http.Response {
+	header := entry.Header.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	header.Set(Header, "hit")
+	return &http.Response{
+		Status:        "200 OK",
+		StatusCode:    http.StatusOK,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(bytes.NewReader(entry.Body)),
+		ContentLength: int64(len(entry.Body)),
+		Request:       req,
+	}
+}
+
+func isEventStream(header http.Header) bool {
+	mediaType, _, _ := mime.ParseMediaType(header.Get("Content-Type"))
+	return mediaType == "text/event-stream"
+}
+
+// completeStream reports whether data, an event stream, ends its message with
+// message_stop. A stream that failed part-way with an error event still
+// arrives with a 200 status, and must not be replayed.
+func completeStream(data []byte) bool {
+	stopped := false
+	for line := range bytes.Lines(data) {
+		event, ok := bytes.CutPrefix(line, []byte("event:"))
+		if !ok {
+			continue
+		}
+		switch string(bytes.TrimSpace(event)) {
+		case "error":
+			return false
+		case "message_stop":
+			stopped = true
+		}
+	}
+	return stopped
+}
+
+// fillingBody passes a response body through and caches it once it has been
+// read to the end. A body closed early, such as a stream the caller
+// abandoned, is not cached.
+type fillingBody struct {
+	rc   io.ReadCloser
+	buf  bytes.Buffer
+	done func([]byte)
+	once sync.Once
+}
+
+func (b *fillingBody) Read(p []byte) (int, error) {
+	n, err := b.rc.Read(p)
+	b.buf.Write(p[:n])
+	if err == io.EOF {
+		b.once.Do(func() { b.done(bytes.Clone(b.buf.Bytes())) })
+	}
+	return n, err
+}
+
+func (b *fillingBody) Close() error {
+	return b.rc.Close()
+}
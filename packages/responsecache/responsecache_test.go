@@ -0,0 +1,235 @@
+package responsecache_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/lib/anthropictest"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/param"
+	"github.com/anthropics/anthropic-sdk-go/packages/responsecache"
+)
+
+func params(text string, temperature float64) anthropic.MessageNewParams {
+	return anthropic.MessageNewParams{
+		Model:       anthropic.ModelClaudeSonnet4_5,
+		MaxTokens:   1024,
+		Temperature: anthropic.Float(temperature),
+		Messages:    []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock(text))},
+	}
+}
+
+func newClient(t *testing.T, cache *responsecache.Cache, opts ...option.RequestOption) (anthropic.Client, *anthropictest.Server) {
+	server := anthropictest.NewServer(t)
+	n := 0
+	server.RespondWith(func(anthropictest.Request) anthropictest.Reply {
+		n++
+		return anthropictest.Text("reply " + strconv.Itoa(n))
+	})
+	opts = append(append(server.Options(), opts...), option.WithResponseCache(cache))
+	return anthropic.NewClient(opts...), server
+}
+
+func TestCachesDeterministicRequests(t *testing.T) {
+	client, server := newClient(t, responsecache.New(responsecache.NewMemoryStore(10), responsecache.Options{}))
+	ctx := context.Background()
+
+	var first *http.Response
+	msg, err := client.Messages.New(ctx, params("hello", 0), option.WithResponseInto(&first))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content[0].Text != "reply 1" || first.Header.Get(responsecache.Header) != "" {
+		t.Fatalf("expected the first request to reach the API, got %q", msg.Content[0].Text)
+	}
+
+	var second *http.Response
+	p := params("hello", 0)
+	p.Metadata.UserID = anthropic.String("someone-else")
+	msg, err = client.Messages.New(ctx, p, option.WithResponseInto(&second))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if msg.Content[0].Text != "reply 1" || second.Header.Get(responsecache.Header) != "hit" {
+		t.Errorf("expected the repeated request to be served from cache, got %q", msg.Content[0].Text)
+	}
+
+	for _, req := range []struct {
+		name   string
+		ctx    context.Context
+		params anthropic.MessageNewParams
+	}{
+		{"different prompt", ctx, params("goodbye", 0)},
+		{"sampled", ctx, params("hello", 0.7)},
+		{"bypassed", responsecache.Bypass(ctx), params("hello", 0)},
+	} {
+		before := len(server.Requests())
+		if _, err := client.Messages.New(req.ctx, req.params); err != nil {
+			t.Fatal(err)
+		}
+		if len(server.Requests()) != before+1 {
+			t.Errorf("%s: expected the request to reach the API", req.name)
+		}
+	}
+
+	unset := params("hello", 0)
+	unset.Temperature = param.Opt[float64]{}
+	before := len(server.Requests())
+	client.Messages.New(ctx, unset)
+	client.Messages.New(ctx, unset)
+	if len(server.Requests()) != before+2 {
+		t.Error("expected requests at the default temperature not to be cached")
+	}
+}
+
+func TestCachesStreams(t *testing.T) {
+	store := responsecache.NewMemoryStore(10)
+	client, server := newClient(t, responsecache.New(store, responsecache.Options{}))
+
+	stream := func() string {
+		s := client.Messages.NewStreaming(context.Background(), params("stream me", 0))
+		var msg anthropic.Message
+		for s.Next() {
+			if err := msg.Accumulate(s.Current()); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err := s.Err(); err != nil {
+			t.Fatal(err)
+		}
+		return msg.Content[0].Text
+	}
+	if got := stream(); got != "reply 1" {
+		t.Fatalf("unexpected first stream %q", got)
+	}
+	if got := stream(); got != "reply 1" {
+		t.Errorf("expected the cached stream to replay, got %q", got)
+	}
+	if len(server.Requests()) != 1 {
+		t.Errorf("expected one API request, got %d", len(server.Requests()))
+	}
+
+	// A non-streaming request has its own entry.
+	msg, err := client.Messages.New(context.Background(), params("stream me", 0))
+	if err != nil || msg.Content[0].Text != "reply 2" {
+		t.Errorf("expected the non-streaming request to reach the API, got %v", err)
+	}
+	if store.Len() != 2 {
+		t.Errorf("expected 2 entries, got %d", store.Len())
+	}
+}
+
+func TestAlwaysCacheAndTTL(t *testing.T) {
+	cache := responsecache.New(responsecache.NewMemoryStore(10), responsecache.Options{AlwaysCache: true, TTL: 50 * time.Millisecond})
+	client, server := newClient(t, cache)
+
+	for range 2 {
+		if _, err := client.Messages.New(context.Background(), params("hot", 1)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if len(server.Requests()) != 1 {
+		t.Errorf("expected AlwaysCache to cache sampled requests, got %d requests", len(server.Requests()))
+	}
+	time.Sleep(60 * time.Millisecond)
+	msg, err := client.Messages.New(context.Background(), params("hot", 1))
+	if err != nil || msg.Content[0].Text != "reply 2" || len(server.Requests()) != 2 {
+		t.Errorf("expected the expired entry to be refetched, got %d requests", len(server.Requests()))
+	}
+}
+
+func TestErrorsAreNotCached(t *testing.T) {
+	client, server := newClient(t, responsecache.New(responsecache.NewMemoryStore(10), responsecache.Options{}), option.WithMaxRetries(0))
+
+	server.Reply(anthropictest.Overloaded())
+	if _, err := client.Messages.New(context.Background(), params("x", 0)); err == nil {
+		t.Fatal("expected the overloaded error")
+	}
+	msg, err := client.Messages.New(context.Background(), params("x", 0))
+	if err != nil || msg.Content[0].Text != "reply 1" {
+		t.Errorf("expected the error not to be cached, got %v", err)
+	}
+}
+
+func TestFailedStreamsAreNotCached(t *testing.T) {
+	var requests atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		w.Header().Set("Content-Type", "text/event-stream")
+		fmt.Fprint(w, "event: message_start\n"+
+			`data: {"type":"message_start","message":{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","content":[],"usage":{"input_tokens":1,"output_tokens":0}}}`+"\n\n")
+		fmt.Fprint(w, "event: error\n"+`data: {"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}`+"\n\n")
+	}))
+	t.Cleanup(server.Close)
+	client := anthropic.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test-key"),
+		option.WithResponseCache(responsecache.New(responsecache.NewMemoryStore(10), responsecache.Options{})))
+
+	for range 2 {
+		stream := client.Messages.NewStreaming(context.Background(), params("stream me", 0))
+		for stream.Next() {
+		}
+		if stream.Err() == nil {
+			t.Fatal("expected the stream's error event")
+		}
+	}
+	if requests.Load() != 2 {
+		t.Errorf("expected the failed stream not to be cached, got %d API requests", requests.Load())
+	}
+}
+
+func TestMemoryStoreEvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	store := responsecache.NewMemoryStore(2)
+	store.Put(ctx, "a", &responsecache.Entry{Body: []byte("a")})
+	store.Put(ctx, "b", &responsecache.Entry{Body: []byte("b")})
+	store.Get(ctx, "a")
+	store.Put(ctx, "c", &responsecache.Entry{Body: []byte("c")})
+
+	if e, _ := store.Get(ctx, "b"); e != nil {
+		t.Error("expected the least recently used entry to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if e, _ := store.Get(ctx, key); e == nil || string(e.Body) != key {
+			t.Errorf("expected %s to remain", key)
+		}
+	}
+}
+
+func TestFileStore(t *testing.T) {
+	dir := t.TempDir()
+	store, err := responsecache.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client, server := newClient(t, responsecache.New(store, responsecache.Options{}))
+	if _, err := client.Messages.New(context.Background(), params("persist", 0)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new store over the same directory, as in a later run, serves the entry.
+	reopened, err := responsecache.NewFileStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client = anthropic.NewClient(append(server.Options(), option.WithResponseCache(responsecache.New(reopened, responsecache.Options{})))...)
+	msg, err := client.Messages.New(context.Background(), params("persist", 0))
+	if err != nil || msg.Content[0].Text != "reply 1" {
+		t.Errorf("expected the persisted response, got %v", err)
+	}
+	if len(server.Requests()) != 1 {
+		t.Errorf("expected one API request, got %d", len(server.Requests()))
+	}
+
+	ctx := context.Background()
+	store.Put(ctx, "old", &responsecache.Entry{Body: []byte("x"), Expires: time.Now().Add(-time.Minute)})
+	if e, err := store.Get(ctx, "old"); e != nil || err != nil {
+		t.Errorf("expected the expired entry to be dropped, got %v, %v", e, err)
+	}
+}
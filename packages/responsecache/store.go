@@ -0,0 +1,142 @@
+package responsecache
+
+import (
+	"container/list"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// MemoryStore is a [Store] that keeps the most recently used entries in
+// memory.
+type MemoryStore struct {
+	maxEntries int
+
+	mu      sync.Mutex
+	order   *list.List // of *memoryItem, most recently used first
+	entries map[string]*list.Element
+}
+
+type memoryItem struct {
+	key   string
+	entry *Entry
+}
+
+// NewMemoryStore returns a [MemoryStore] that holds up to maxEntries entries,
+// evicting the least recently used beyond that. A maxEntries of 0 means no
+// limit.
+func NewMemoryStore(maxEntries int) *MemoryStore {
+	return &MemoryStore{maxEntries: maxEntries, order: list.New(), entries: map[string]*list.Element{}}
+}
+
+// Get implements [Store].
+func (s *MemoryStore) Get(ctx context.Context, key string) (*Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	el, ok := s.entries[key]
+	if !ok {
+		return nil, nil
+	}
+	item := el.Value.(*memoryItem)
+	if item.entry.expired(time.Now()) {
+		s.order.Remove(el)
+		delete(s.entries, key)
+		return nil, nil
+	}
+	s.order.MoveToFront(el)
+	return item.entry, nil
+}
+
+// Put implements [Store].
+func (s *MemoryStore) Put(ctx context.Context, key string, entry *Entry) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		el.Value.(*memoryItem).entry = entry
+		s.order.MoveToFront(el)
+		return nil
+	}
+	s.entries[key] = s.order.PushFront(&memoryItem{key: key, entry: entry})
+	for s.maxEntries > 0 && s.order.Len() > s.maxEntries {
+		oldest := s.order.Back()
+		s.order.Remove(oldest)
+		delete(s.entries, oldest.Value.(*memoryItem).key)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the store.
+func (s *MemoryStore) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.order.Len()
+}
+
+// FileStore is a [Store] that keeps one JSON file per entry in a directory, so
+// that cached responses survive between runs.
+type FileStore struct {
+	dir string
+}
+
+// NewFileStore returns a [FileStore] in dir, which is created if needed.
+func NewFileStore(dir string) (*FileStore, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("responsecache: %w", err)
+	}
+	return &FileStore{dir: dir}, nil
+}
+
+func (s *FileStore) path(key string) string {
+	return filepath.Join(s.dir, key+".json")
+}
+
+// Get implements [Store]. An expired entry's file is removed.
+func (s *FileStore) Get(ctx context.Context, key string) (*Entry, error) {
+	data, err := os.ReadFile(s.path(key))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("responsecache: %w", err)
+	}
+	var entry Entry
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return nil, fmt.Errorf("responsecache: decoding %s: %w", s.path(key), err)
+	}
+	if entry.expired(time.Now()) {
+		os.Remove(s.path(key))
+		return nil, nil
+	}
+	return &entry, nil
+}
+
+// Put implements [Store]. The file is written atomically and is readable only
+// by the current user.
+func (s *FileStore) Put(ctx context.Context, key string, entry *Entry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(s.dir, key+".*")
+	if err != nil {
+		return fmt.Errorf("responsecache: %w", err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("responsecache: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("responsecache: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path(key)); err != nil {
+		return fmt.Errorf("responsecache: %w", err)
+	}
+	return nil
+}
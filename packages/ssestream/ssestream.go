@@ -5,11 +5,14 @@ package ssestream
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go/internal/apierror"
 )
@@ -31,9 +34,10 @@ func NewDecoder(res *http.Response) Decoder {
 	if t, ok := decoderTypes[contentType]; ok {
 		decoder = t(res.Body)
 	} else {
-		scn := bufio.NewScanner(res.Body)
-		scn.Buffer(nil, bufio.MaxScanTokenSize<<9)
-		decoder = &eventStreamDecoder{rc: res.Body, scn: scn}
+		decoder = &eventStreamDecoder{rc: res.Body, scn: newScanner(res.Body)}
+		if res.Request != nil {
+			decoder.(*eventStreamDecoder).ctx = res.Request.Context()
+		}
 	}
 
 	// richDecoder needs the http request to provide helpful errors
@@ -82,6 +86,29 @@ func RegisterDecoder(contentType string, decoder func(io.ReadCloser) Decoder) {
 type Event struct {
 	Type string
 	Data []byte
+	// ID is the stream's last event ID when this event was dispatched. Per the
+	// SSE specification it carries over from earlier events until a later
+	// `id` field replaces it.
+	ID string
+	// Retry is the reconnection time requested by a `retry` field in this
+	// event, or zero if the event did not set one.
+	Retry time.Duration
+}
+
+// Reconnector is implemented by response bodies whose originating request can
+// be re-issued to resume an event stream. The request is sent again with a
+// `Last-Event-ID` header set to lastEventID, and the new response is returned.
+//
+// Streaming responses requested with the option.WithStreamReconnect request
+// option implement Reconnector.
+type Reconnector interface {
+	Reconnect(lastEventID string) (*http.Response, error)
+}
+
+func newScanner(r io.Reader) *bufio.Scanner {
+	scn := bufio.NewScanner(r)
+	scn.Buffer(nil, bufio.MaxScanTokenSize<<9)
+	return scn
 }
 
 // A base implementation of a Decoder for text/event-stream.
@@ -90,6 +117,19 @@ type eventStreamDecoder struct {
 	rc  io.ReadCloser
 	scn *bufio.Scanner
 	err error
+
+	ctx context.Context
+	// lastID is the last event ID buffer, which persists across events
+	// and reconnections.
+	lastID string
+	// retry is the most recent reconnection time requested by the server.
+	retry time.Duration
+	// resumeID is the event ID the current connection resumed from. A
+	// replay of that event is skipped so it isn't delivered twice.
+	resumeID string
+	// inMessage reports whether a message_start has been dispatched without
+	// the message_stop or error event that ends it.
+	inMessage bool
 }
 
 func (s *eventStreamDecoder) Next() bool {
@@ -97,17 +137,46 @@ func (s *eventStreamDecoder) Next() bool {
 		return false
 	}
 
+	for {
+		if s.next() {
+			return true
+		}
+		if s.err == nil || !s.reconnect() {
+			return false
+		}
+	}
+}
+
+// next reads the next event from the current connection.
+func (s *eventStreamDecoder) next() bool {
 	event := ""
 	data := bytes.NewBuffer(nil)
+	hasID := false
+	retry := time.Duration(0)
 
 	for s.scn.Scan() {
 		txt := s.scn.Bytes()
 
 		// Dispatch event on an empty line
 		if len(txt) == 0 {
+			if hasID && s.resumeID != "" && s.lastID == s.resumeID {
+				// The server replayed the event we resumed from.
+				event, hasID, retry = "", false, 0
+				data.Reset()
+				continue
+			}
+			s.resumeID = ""
+			switch event {
+			case "message_start":
+				s.inMessage = true
+			case "message_stop", "error":
+				s.inMessage = false
+			}
 			s.evt = Event{
-				Type: event,
-				Data: data.Bytes(),
+				Type:  event,
+				Data:  data.Bytes(),
+				ID:    s.lastID,
+				Retry: retry,
 			}
 			return true
 		}
@@ -135,16 +204,77 @@ func (s *eventStreamDecoder) Next() bool {
 			if s.err != nil {
 				break
 			}
+		case "id":
+			// IDs containing NULL are ignored, as required by the SSE specification.
+			if !bytes.ContainsRune(value, 0) {
+				s.lastID = string(value)
+				hasID = true
+			}
+		case "retry":
+			if ms, err := strconv.ParseUint(string(value), 10, 63); err == nil {
+				retry = time.Duration(ms) * time.Millisecond
+				s.retry = retry
+			}
 		}
 	}
 
 	if s.scn.Err() != nil {
 		s.err = s.scn.Err()
+	} else if s.inMessage && s.resumable() {
+		// The connection closed cleanly mid-message: resume rather than
+		// silently truncate the message.
+		s.err = io.ErrUnexpectedEOF
 	}
 
 	return false
 }
 
+func (s *eventStreamDecoder) resumable() bool {
+	_, ok := s.rc.(Reconnector)
+	return ok && s.lastID != ""
+}
+
+// reconnect attempts to resume the stream after the connection failed with
+// s.err, or closed before the message it was streaming ended. It only does so when the response body is a [Reconnector] and an
+// event ID is known, since resuming without one would replay the stream from
+// the start. On success the decoder continues reading from the new response.
+func (s *eventStreamDecoder) reconnect() bool {
+	if !s.resumable() {
+		return false
+	}
+	rc := s.rc.(Reconnector)
+	if s.ctx != nil && s.ctx.Err() != nil {
+		return false
+	}
+
+	if s.retry > 0 {
+		timer := time.NewTimer(s.retry)
+		defer timer.Stop()
+		var done <-chan struct{}
+		if s.ctx != nil {
+			done = s.ctx.Done()
+		}
+		select {
+		case <-done:
+			return false
+		case <-timer.C:
+		}
+	}
+
+	res, err := rc.Reconnect(s.lastID)
+	if err != nil {
+		s.err = fmt.Errorf("ssestream: reconnect after %w failed: %w", s.err, err)
+		return false
+	}
+
+	_ = s.rc.Close()
+	s.rc = res.Body
+	s.scn = newScanner(res.Body)
+	s.resumeID = s.lastID
+	s.err = nil
+	return true
+}
+
 func (s *eventStreamDecoder) Event() Event {
 	return s.evt
 }
@@ -161,6 +291,8 @@ type Stream[T any] struct {
 	decoder Decoder
 	cur     T
 	err     error
+	lastID  string
+	retry   time.Duration
 }
 
 func NewStream[T any](decoder Decoder, err error) *Stream[T] {
@@ -187,6 +319,12 @@ func (s *Stream[T]) Next() bool {
 	}
 
 	for s.decoder.Next() {
+		if evt := s.decoder.Event(); evt.ID != "" {
+			s.lastID = evt.ID
+		}
+		if evt := s.decoder.Event(); evt.Retry > 0 {
+			s.retry = evt.Retry
+		}
 		switch s.decoder.Event().Type {
 		case "completion":
 			var nxt T
@@ -231,6 +369,18 @@ func (s *Stream[T]) Err() error {
 	return s.err
 }
 
+// LastEventID returns the ID of the most recent event received on the stream,
+// as set by the server's `id` field, or "" if the server has not sent one.
+func (s *Stream[T]) LastEventID() string {
+	return s.lastID
+}
+
+// RetryInterval returns the most recent reconnection time requested by the
+// server's `retry` field, or zero if the server has not sent one.
+func (s *Stream[T]) RetryInterval() time.Duration {
+	return s.retry
+}
+
 func (s *Stream[T]) Close() error {
 	if s.decoder == nil {
 		// already closed
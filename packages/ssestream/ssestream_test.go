@@ -0,0 +1,208 @@
+package ssestream_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go/internal/requestconfig"
+	"github.com/anthropics/anthropic-sdk-go/packages/ssestream"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type event struct {
+	N int `json:"n"`
+}
+
+func TestStreamTracksEventIDAndRetry(t *testing.T) {
+	body := "retry: 1500\n\n" +
+		"id: evt_1\nevent: message\ndata: {\"n\":1}\n\n" +
+		"event: message\ndata: {\"n\":2}\n\n" +
+		"id: evt_3\nevent: ping\ndata: {}\n\n"
+	res := &http.Response{
+		StatusCode: 200,
+		Header:     http.Header{"Content-Type": []string{"text/event-stream"}},
+		Body:       io.NopCloser(strings.NewReader(body)),
+	}
+
+	stream := ssestream.NewStream[event](ssestream.NewDecoder(res), nil)
+	require.True(t, stream.Next())
+	assert.Equal(t, 1, stream.Current().N)
+	assert.Equal(t, "evt_1", stream.LastEventID())
+	assert.Equal(t, 1500*time.Millisecond, stream.RetryInterval())
+
+	require.True(t, stream.Next())
+	assert.Equal(t, 2, stream.Current().N)
+	assert.Equal(t, "evt_1", stream.LastEventID(), "event IDs carry over until replaced")
+
+	require.False(t, stream.Next())
+	require.NoError(t, stream.Err())
+	assert.Equal(t, "evt_3", stream.LastEventID(), "IDs on skipped events are still tracked")
+}
+
+// dropAfter writes the given events and then abruptly closes the connection,
+// leaving the chunked response unterminated.
+func dropAfter(t *testing.T, w http.ResponseWriter, events ...string) {
+	t.Helper()
+	w.Header().Set("Content-Type", "text/event-stream")
+	for _, e := range events {
+		fmt.Fprint(w, e)
+	}
+	w.(http.Flusher).Flush()
+	conn, _, err := w.(http.Hijacker).Hijack()
+	require.NoError(t, err)
+	conn.Close()
+}
+
+func sse(id string, n int) string {
+	return fmt.Sprintf("id: %s\nevent: message\ndata: {\"n\":%d}\n\n", id, n)
+}
+
+func newStream(t *testing.T, server *httptest.Server, reconnects int) *ssestream.Stream[event] {
+	t.Helper()
+	cfg, err := requestconfig.NewRequestConfig(context.Background(), http.MethodPost, "v1/stream", json.RawMessage(`{}`), nil)
+	require.NoError(t, err)
+	cfg.BaseURL, _ = url.Parse(server.URL + "/")
+	cfg.StreamReconnects = reconnects
+	var raw *http.Response
+	cfg.ResponseBodyInto = &raw
+	err = cfg.Execute()
+	return ssestream.NewStream[event](ssestream.NewDecoder(raw), err)
+}
+
+func TestStreamReconnectResumesFromLastEventID(t *testing.T) {
+	var calls atomic.Int32
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		assert.JSONEq(t, `{}`, string(body), "request body is replayed on reconnect")
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		switch calls.Add(1) {
+		case 1:
+			dropAfter(t, w, "retry: 10\n\n", sse("evt_1", 1), sse("evt_2", 2))
+		default:
+			w.Header().Set("Content-Type", "text/event-stream")
+			// Replays the resumed-from event, which must not be delivered twice.
+			fmt.Fprint(w, sse("evt_2", 2), sse("evt_3", 3), sse("evt_4", 4))
+		}
+	}))
+	defer server.Close()
+
+	stream := newStream(t, server, 1)
+	defer stream.Close()
+
+	var got []int
+	for stream.Next() {
+		got = append(got, stream.Current().N)
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, []int{1, 2, 3, 4}, got)
+	assert.Equal(t, []string{"", "evt_2"}, lastEventIDs)
+	assert.Equal(t, "evt_4", stream.LastEventID())
+}
+
+func TestStreamReconnectLimit(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		dropAfter(t, w, sse(fmt.Sprintf("evt_%d", n), n))
+	}))
+	defer server.Close()
+
+	stream := newStream(t, server, 2)
+	defer stream.Close()
+
+	var got []int
+	for stream.Next() {
+		got = append(got, stream.Current().N)
+	}
+	assert.Equal(t, []int{1, 2, 3}, got)
+	require.Error(t, stream.Err())
+	assert.Contains(t, stream.Err().Error(), "reconnect limit of 2 reached")
+}
+
+func TestStreamWithoutReconnectFails(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		dropAfter(t, w, sse("evt_1", 1))
+	}))
+	defer server.Close()
+
+	stream := newStream(t, server, 0)
+	defer stream.Close()
+
+	require.True(t, stream.Next())
+	require.False(t, stream.Next())
+	require.Error(t, stream.Err())
+}
+
+func TestStreamResumesUnderRetryPolicy(t *testing.T) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := int(calls.Add(1))
+		dropAfter(t, w, sse(fmt.Sprintf("evt_%d", n), n))
+	}))
+	defer server.Close()
+
+	var attempts []requestconfig.RetryAttempt
+	cfg, err := requestconfig.NewRequestConfig(context.Background(), http.MethodPost, "v1/stream", json.RawMessage(`{}`), nil)
+	require.NoError(t, err)
+	cfg.BaseURL, _ = url.Parse(server.URL + "/")
+	cfg.RetryPolicy = requestconfig.RetryPolicyFunc(func(a requestconfig.RetryAttempt) (time.Duration, bool) {
+		attempts = append(attempts, a)
+		return 0, a.Attempt < 1
+	})
+	var raw *http.Response
+	cfg.ResponseBodyInto = &raw
+	err = cfg.Execute()
+	stream := ssestream.NewStream[event](ssestream.NewDecoder(raw), err)
+	defer stream.Close()
+
+	var got []int
+	for stream.Next() {
+		got = append(got, stream.Current().N)
+	}
+	assert.Equal(t, []int{1, 2}, got)
+	require.Error(t, stream.Err())
+	require.Len(t, attempts, 2)
+	assert.Equal(t, "evt_1", attempts[0].LastEventID)
+	assert.Equal(t, 0, attempts[0].Attempt)
+	assert.ErrorIs(t, attempts[0].Err, io.ErrUnexpectedEOF)
+	assert.Equal(t, "evt_2", attempts[1].LastEventID)
+}
+
+func TestStreamReconnectsAfterCleanCloseMidMessage(t *testing.T) {
+	var calls atomic.Int32
+	var lastEventIDs []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		lastEventIDs = append(lastEventIDs, r.Header.Get("Last-Event-ID"))
+		w.Header().Set("Content-Type", "text/event-stream")
+		switch calls.Add(1) {
+		case 1:
+			// Ends the response normally, but before message_stop.
+			fmt.Fprint(w, "id: evt_1\nevent: message_start\ndata: {\"n\":1}\n\n", "id: evt_2\nevent: message_delta\ndata: {\"n\":2}\n\n")
+		default:
+			fmt.Fprint(w, "id: evt_3\nevent: message_stop\ndata: {\"n\":3}\n\n")
+		}
+	}))
+	defer server.Close()
+
+	stream := newStream(t, server, 1)
+	defer stream.Close()
+
+	var got []int
+	for stream.Next() {
+		got = append(got, stream.Current().N)
+	}
+	require.NoError(t, stream.Err())
+	assert.Equal(t, []int{1, 2, 3}, got)
+	assert.Equal(t, []string{"", "evt_2"}, lastEventIDs)
+}
@@ -0,0 +1,442 @@
+package anthropic
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+
+	"github.com/anthropics/anthropic-sdk-go/internal/stainlessheader"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"golang.org/x/sync/errgroup"
+)
+
+// Tool represents a tool that can be executed by the ToolRunner.
+type Tool interface {
+	// Name returns the tool's name
+	Name() string
+	// Description returns the tool's description
+	Description() string
+	// InputSchema returns the JSON schema for the tool's input
+	InputSchema() ToolInputSchemaParam
+	// Execute runs the tool with raw JSON input and returns one or more result blocks.
+	Execute(ctx context.Context, input json.RawMessage) ([]ToolResultBlockParamContentUnion, error)
+}
+
+// ToolRunnerParams contains parameters for creating a ToolRunner or ToolRunnerStreaming.
+type ToolRunnerParams struct {
+	MessageNewParams
+	// MaxIterations limits the number of API calls. When set to 0 (the default),
+	// there is no limit and the runner continues until the model stops using tools.
+	MaxIterations int
+}
+
+// toolRunnerBase holds state and logic shared by ToolRunner and ToolRunnerStreaming.
+type toolRunnerBase struct {
+	messageService *MessageService
+	// Params contains the configuration for the tool runner.
+	// This field is exported so users can modify parameters directly.
+	Params         ToolRunnerParams
+	toolMap        map[string]Tool
+	iterationCount int
+	lastMessage    *Message
+	completed      bool
+	opts           []option.RequestOption
+	err            error
+}
+
+func newToolRunnerBase(messageService *MessageService, tools []Tool, params ToolRunnerParams, opts []option.RequestOption) toolRunnerBase {
+	toolMap := make(map[string]Tool)
+	apiTools := make([]ToolUnionParam, len(tools))
+
+	for i, tool := range tools {
+		toolMap[tool.Name()] = tool
+		apiTools[i] = ToolUnionParam{
+			OfTool: &ToolParam{
+				Name:        tool.Name(),
+				Description: String(tool.Description()),
+				InputSchema: tool.InputSchema(),
+			},
+		}
+	}
+
+	// Add tools to the API params
+	params.MessageNewParams.Tools = apiTools
+	params.Messages = append([]MessageParam{}, params.Messages...)
+
+	opts = append([]option.RequestOption{stainlessheader.With(stainlessheader.ToolRunner)}, opts...)
+
+	return toolRunnerBase{
+		messageService: messageService,
+		Params:         params,
+		toolMap:        toolMap,
+		opts:           opts,
+	}
+}
+
+// LastMessage returns the most recent assistant message, or nil if no messages have been received yet.
+func (b *toolRunnerBase) LastMessage() *Message {
+	return b.lastMessage
+}
+
+// AppendMessages adds messages to the conversation history.
+// This is a convenience method equivalent to:
+//
+//	runner.Params.Messages = append(runner.Params.Messages, messages...)
+func (b *toolRunnerBase) AppendMessages(messages ...MessageParam) {
+	b.Params.Messages = append(b.Params.Messages, messages...)
+}
+
+// Messages returns a copy of the current conversation history.
+// The returned slice can be safely modified without affecting the runner's state.
+func (b *toolRunnerBase) Messages() []MessageParam {
+	result := make([]MessageParam, len(b.Params.Messages))
+	copy(result, b.Params.Messages)
+	return result
+}
+
+// IterationCount returns the number of API calls made so far.
+// This is incremented each time a turn makes an API call.
+func (b *toolRunnerBase) IterationCount() int {
+	return b.iterationCount
+}
+
+// IsCompleted returns true if the conversation has finished, either because
+// the model stopped using tools or the maximum iteration limit was reached.
+func (b *toolRunnerBase) IsCompleted() bool {
+	return b.completed
+}
+
+// Err returns the last error that occurred during iteration, if any.
+// This is useful when using All() or AllStreaming() to check for errors
+// after the iteration completes.
+func (b *toolRunnerBase) Err() error {
+	return b.err
+}
+
+// executeTools processes any tool use blocks in the given message and returns a tool result message.
+// Returns:
+//   - (result, nil) if tools executed successfully
+//   - (nil, nil) if no tools to execute or the turn ended in a refusal
+//   - (nil, ctx.Err()) if context was cancelled
+func (b *toolRunnerBase) executeTools(ctx context.Context, message *Message) (*MessageParam, error) {
+	// A refusal-terminated turn is terminal: its tool calls belong to a dead
+	// conversation — executing them fires side effects the caller never
+	// confirmed and produces tool_results that cannot be coherently replayed.
+	if message.StopReason == StopReasonRefusal {
+		return nil, nil
+	}
+
+	var toolUseBlocks []ToolUseBlock
+
+	// Find all tool use blocks in the message
+	for _, block := range message.Content {
+		if block.Type == "tool_use" {
+			toolUseBlocks = append(toolUseBlocks, block.AsToolUse())
+		}
+	}
+
+	if len(toolUseBlocks) == 0 {
+		return nil, nil
+	}
+
+	// Execute all tools in parallel using errgroup for proper cancellation handling
+	results := make([]ContentBlockParamUnion, len(toolUseBlocks))
+
+	g, gctx := errgroup.WithContext(ctx)
+	for i, toolUse := range toolUseBlocks {
+		g.Go(func() error {
+			// Check for cancellation before executing tool
+			select {
+			case <-gctx.Done():
+				return gctx.Err()
+			default:
+			}
+			result := b.executeToolUse(gctx, toolUse)
+			results[i] = ContentBlockParamUnion{OfToolResult: &result}
+			return nil // tool errors become result content, not Go errors
+		})
+	}
+
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	// Create user message with tool results
+	userMessage := NewUserMessage(results...)
+	return &userMessage, nil
+}
+
+func newToolResultErrorBlockParam(toolUseID string, errorText string) ToolResultBlockParam {
+	return ToolResultBlockParam{
+		ToolUseID: toolUseID,
+		Content: []ToolResultBlockParamContentUnion{
+			{OfText: &TextBlockParam{Text: errorText}},
+		},
+		IsError: Bool(true),
+	}
+}
+
+// executeToolUse executes a single tool use block and returns the result.
+func (b *toolRunnerBase) executeToolUse(ctx context.Context, toolUse ToolUseBlock) ToolResultBlockParam {
+	tool, exists := b.toolMap[toolUse.Name]
+	if !exists {
+		return newToolResultErrorBlockParam(
+			toolUse.ID,
+			fmt.Sprintf("Error: Tool '%s' not found", toolUse.Name),
+		)
+	}
+
+	content, err := tool.Execute(ctx, toolUse.Input)
+	if err != nil {
+		return newToolResultErrorBlockParam(
+			toolUse.ID,
+			fmt.Sprintf("Error: %v", err),
+		)
+	}
+
+	return ToolResultBlockParam{
+		ToolUseID: toolUse.ID,
+		Content:   content,
+	}
+}
+
+// ToolRunner manages the automatic conversation loop between the assistant and tools
+// using non-streaming API calls. It implements an iterator pattern for processing
+// conversation turns.
+//
+// A ToolRunner is NOT safe for concurrent use. All methods must be called
+// from a single goroutine. However, tool handlers ARE called concurrently
+// when multiple tools are invoked in a single turn - ensure your handlers
+// are thread-safe.
+type ToolRunner struct {
+	toolRunnerBase
+}
+
+// NewToolRunner creates a ToolRunner that automatically handles the loop between
+// the model generating tool calls, executing those tool calls, and sending the
+// results back to the model until a final answer is produced or the maximum
+// number of iterations is reached.
+func (r *MessageService) NewToolRunner(tools []Tool, params ToolRunnerParams, opts ...option.RequestOption) *ToolRunner {
+	return &ToolRunner{
+		toolRunnerBase: newToolRunnerBase(r, tools, params, opts),
+	}
+}
+
+// NextMessage advances the conversation by one turn. It executes any pending tool calls
+// from the previous message, then makes an API call to get the model's next response.
+//
+// Returns:
+//   - (message, nil) on success with the assistant's response
+//   - (nil, nil) when the conversation is complete (no more tool calls or max iterations reached)
+//   - (nil, error) if an error occurred during tool execution or API call
+func (r *ToolRunner) NextMessage(ctx context.Context) (*Message, error) {
+	if r.completed {
+		return nil, nil
+	}
+
+	// Check iteration limit
+	if r.Params.MaxIterations > 0 && r.iterationCount >= r.Params.MaxIterations {
+		r.completed = true
+		return r.lastMessage, nil
+	}
+
+	// Execute any pending tool calls from the last message
+	if r.lastMessage != nil {
+		toolMessage, err := r.executeTools(ctx, r.lastMessage)
+		if err != nil {
+			r.err = err
+			return nil, err
+		}
+		if toolMessage == nil {
+			// No tools to execute, conversation is complete
+			r.completed = true
+			return r.lastMessage, nil
+		}
+		r.Params.Messages = append(r.Params.Messages, *toolMessage)
+	}
+
+	// Make API call
+	r.iterationCount++
+	messageParams := r.Params.MessageNewParams
+	messageParams.Messages = r.Params.Messages
+
+	message, err := r.messageService.New(ctx, messageParams, r.opts...)
+	if err != nil {
+		r.err = err
+		return nil, fmt.Errorf("failed to get next message: %w", err)
+	}
+
+	r.lastMessage = message
+	r.Params.Messages = append(r.Params.Messages, message.ToParam())
+
+	return message, nil
+}
+
+// RunToCompletion repeatedly calls NextMessage until the conversation is complete,
+// either because the model stopped using tools or the maximum iteration limit was reached.
+//
+// Returns the final assistant message and any error that occurred.
+func (r *ToolRunner) RunToCompletion(ctx context.Context) (*Message, error) {
+	for {
+		message, err := r.NextMessage(ctx)
+		if err != nil {
+			return nil, err
+		}
+		if message == nil {
+			return r.lastMessage, nil
+		}
+	}
+}
+
+// All returns an iterator that yields all messages until the conversation completes.
+// This is a convenience method for iterating over the entire conversation.
+//
+// Example usage:
+//
+//	for message, err := range runner.All(ctx) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    // process message
+//	}
+func (r *ToolRunner) All(ctx context.Context) iter.Seq2[*Message, error] {
+	return func(yield func(*Message, error) bool) {
+		for {
+			message, err := r.NextMessage(ctx)
+			r.err = err
+			if message == nil {
+				if err != nil {
+					yield(nil, err)
+				}
+				return
+			}
+			if !yield(message, err) {
+				return
+			}
+		}
+	}
+}
+
+// ToolRunnerStreaming manages the automatic conversation loop between the assistant
+// and tools using streaming API calls. It implements an iterator pattern for processing
+// streaming events across conversation turns.
+//
+// A ToolRunnerStreaming is NOT safe for concurrent use. All methods must be called
+// from a single goroutine. However, tool handlers ARE called concurrently
+// when multiple tools are invoked in a single turn - ensure your handlers
+// are thread-safe.
+type ToolRunnerStreaming struct {
+	toolRunnerBase
+}
+
+// NewToolRunnerStreaming creates a ToolRunnerStreaming that automatically handles
+// the loop between the model generating tool calls, executing those tool calls, and
+// sending the results back to the model using streaming API calls until a final answer
+// is produced or the maximum number of iterations is reached.
+func (r *MessageService) NewToolRunnerStreaming(tools []Tool, params ToolRunnerParams, opts ...option.RequestOption) *ToolRunnerStreaming {
+	return &ToolRunnerStreaming{
+		toolRunnerBase: newToolRunnerBase(r, tools, params, opts),
+	}
+}
+
+// NextStreaming advances the conversation by one turn with streaming. It executes any
+// pending tool calls from the previous message, then makes a streaming API call.
+//
+// Returns an iterator that yields streaming events as they arrive. The iterator should
+// be fully consumed to ensure the message is properly accumulated for subsequent turns.
+//
+// If an error occurs, it will be yielded as the second value in the iterator pair.
+// Check IsCompleted() after consuming the iterator to determine if the conversation
+// has finished.
+func (r *ToolRunnerStreaming) NextStreaming(ctx context.Context) iter.Seq2[MessageStreamEventUnion, error] {
+	return func(yield func(MessageStreamEventUnion, error) bool) {
+		if r.completed {
+			return
+		}
+
+		// Check iteration limit
+		if r.Params.MaxIterations > 0 && r.iterationCount >= r.Params.MaxIterations {
+			r.completed = true
+			return
+		}
+
+		// Execute any pending tool calls from the last message
+		if r.lastMessage != nil {
+			toolMessage, err := r.executeTools(ctx, r.lastMessage)
+			if err != nil {
+				r.err = err
+				yield(MessageStreamEventUnion{}, err)
+				return
+			}
+			if toolMessage == nil {
+				// No tools to execute, conversation is complete
+				r.completed = true
+				return
+			}
+			r.Params.Messages = append(r.Params.Messages, *toolMessage)
+		}
+
+		// Make streaming API call
+		r.iterationCount++
+		streamParams := r.Params.MessageNewParams
+		streamParams.Messages = r.Params.Messages
+
+		stream := r.messageService.NewStreaming(ctx, streamParams, r.opts...)
+		defer stream.Close()
+
+		// We need to collect the final message from the stream for the next iteration
+		finalMessage := &Message{}
+		for stream.Next() {
+			event := stream.Current()
+			err := finalMessage.Accumulate(event)
+			if err != nil {
+				r.err = fmt.Errorf("failed to accumulate streaming event: %w", err)
+				yield(MessageStreamEventUnion{}, r.err)
+				return
+			}
+
+			if !yield(event, nil) {
+				return
+			}
+		}
+
+		// Check for stream errors after the loop exits
+		if stream.Err() != nil {
+			r.err = stream.Err()
+			yield(MessageStreamEventUnion{}, r.err)
+			return
+		}
+
+		r.lastMessage = finalMessage
+		r.Params.Messages = append(r.Params.Messages, finalMessage.ToParam())
+	}
+}
+
+// AllStreaming returns an iterator of iterators, where each inner iterator yields
+// streaming events for a single turn of the conversation. The outer iterator continues
+// until the conversation completes.
+//
+// Example usage:
+//
+//	for events, err := range runner.AllStreaming(ctx) {
+//	    if err != nil {
+//	        return err
+//	    }
+//	    for event, err := range events {
+//	        if err != nil {
+//	            return err
+//	        }
+//	        // process streaming event
+//	    }
+//	}
+func (r *ToolRunnerStreaming) AllStreaming(ctx context.Context) iter.Seq2[iter.Seq2[MessageStreamEventUnion, error], error] {
+	return func(yield func(iter.Seq2[MessageStreamEventUnion, error], error) bool) {
+		for !r.completed {
+			eventSeq := r.NextStreaming(ctx)
+			if !yield(eventSeq, nil) {
+				return
+			}
+		}
+	}
+}
@@ -0,0 +1,243 @@
+package toolrunner_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/toolrunner"
+	"github.com/tidwall/gjson"
+)
+
+// batchAgentServer serves the Message Batches endpoints, answering each
+// request as a weather agent would: a first turn calls get_weather, a turn that
+// carries a tool result gets a final answer quoting it. Conversations whose
+// first message is "fail" get an errored result.
+type batchAgentServer struct {
+	*httptest.Server
+	mu      sync.Mutex
+	batches map[string][]gjson.Result
+	// turns records the number of requests in each created batch.
+	turns     []int
+	failPolls int
+}
+
+func newBatchAgentServer(t *testing.T) *batchAgentServer {
+	s := &batchAgentServer{batches: map[string][]gjson.Result{}}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		path := strings.TrimPrefix(r.URL.Path, "/v1/messages/batches")
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case r.Method == http.MethodPost && path == "":
+			body, _ := io.ReadAll(r.Body)
+			id := fmt.Sprintf("msgbatch_%d", len(s.turns)+1)
+			requests := gjson.GetBytes(body, "requests").Array()
+			s.batches[id] = requests
+			s.turns = append(s.turns, len(requests))
+			fmt.Fprintf(w, `{"id":%q,"type":"message_batch","processing_status":"in_progress"}`, id)
+		case strings.HasSuffix(path, "/results"):
+			id := strings.TrimSuffix(strings.TrimPrefix(path, "/"), "/results")
+			w.Header().Set("Content-Type", "application/x-jsonl")
+			for _, req := range s.batches[id] {
+				fmt.Fprintln(w, agentResult(req))
+			}
+		default:
+			if s.failPolls > 0 {
+				s.failPolls--
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			id := strings.TrimPrefix(path, "/")
+			fmt.Fprintf(w, `{"id":%q,"type":"message_batch","processing_status":"ended"}`, id)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func agentResult(req gjson.Result) string {
+	customID := req.Get("custom_id").String()
+	messages := req.Get("params.messages").Array()
+	first := messages[0].Get("content.0.text").String()
+	if first == "fail" {
+		return fmt.Sprintf(`{"custom_id":%q,"result":{"type":"errored","error":{"type":"error","error":{"type":"overloaded_error","message":"Overloaded"}}}}`, customID)
+	}
+	last := messages[len(messages)-1]
+	var message string
+	if result := last.Get(`content.#(type=="tool_result")`); result.Exists() {
+		message = fmt.Sprintf(`{"id":"msg_%s_2","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"end_turn",
+			"content":[{"type":"text","text":%q}],"usage":{"input_tokens":20,"output_tokens":8}}`, customID, "Final: "+result.Get("content.0.text").String())
+	} else {
+		message = fmt.Sprintf(`{"id":"msg_%s_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"tool_use",
+			"content":[{"type":"tool_use","id":"toolu_%s","name":"get_weather","input":{"city":%q}}],"usage":{"input_tokens":10,"output_tokens":5}}`, customID, customID, first)
+	}
+	return fmt.Sprintf(`{"custom_id":%q,"result":{"type":"succeeded","message":%s}}`, customID, strings.Join(strings.Fields(message), " "))
+}
+
+func batchAgentParams(city string) anthropic.BetaToolRunnerParams {
+	params := betaRunnerParams()
+	params.Messages = []anthropic.BetaMessageParam{anthropic.NewBetaUserMessage(anthropic.NewBetaTextBlock(city))}
+	return params
+}
+
+var fastBatchPolling = anthropic.BetaMessageBatchJobParams{
+	MessageBatchJobParams: anthropic.MessageBatchJobParams{PollInterval: time.Millisecond, MaxPollInterval: time.Millisecond},
+}
+
+func TestBetaBatchToolRunner(t *testing.T) {
+	server := newBatchAgentServer(t)
+	client := anthropic.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test-key"), option.WithMaxRetries(0))
+
+	var calls atomic.Int32
+	var city atomic.Value
+	tool := countingWeatherTool(t, &calls, &city)
+
+	batch := client.Beta.Messages.NewBatchToolRunner(anthropic.BetaBatchToolRunnerParams{Job: fastBatchPolling})
+	for _, id := range []string{"Paris", "Tokyo", "Lima"} {
+		if _, err := batch.Add(id, []anthropic.BetaTool{tool}, batchAgentParams(id)); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	failing, err := batch.Add("fail", []anthropic.BetaTool{tool}, batchAgentParams("fail"))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+	if _, err := batch.Add("Paris", nil, batchAgentParams("Paris")); err == nil {
+		t.Error("expected a duplicate conversation ID to be rejected")
+	}
+	for _, id := range []string{"", "São Paulo", strings.Repeat("x", 65)} {
+		if _, err := batch.Add(id, nil, batchAgentParams("Paris")); err == nil {
+			t.Errorf("expected the invalid conversation ID %q to be rejected", id)
+		}
+	}
+
+	if err := batch.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+
+	if got := fmt.Sprint(server.turns); got != "[4 3]" {
+		t.Errorf("expected every active conversation's turn in one batch per turn, got batch sizes %s", got)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("expected the tool to run once per conversation, got %d calls", calls.Load())
+	}
+	for id, runner := range batch.All() {
+		if id == "fail" {
+			continue
+		}
+		if runner.Err() != nil {
+			t.Errorf("%s: unexpected error: %v", id, runner.Err())
+		}
+		if !runner.IsCompleted() || runner.IterationCount() != 2 {
+			t.Errorf("%s: expected completion after 2 iterations, got completed=%v iterations=%d", id, runner.IsCompleted(), runner.IterationCount())
+		}
+		want := fmt.Sprintf("Final: The weather in %s is 20 degrees. api_key=secret", id)
+		if got := runner.LastMessage().Content[0].Text; got != want {
+			t.Errorf("%s: expected %q, got %q", id, want, got)
+		}
+		if got := len(runner.Messages()); got != 4 {
+			t.Errorf("%s: expected 4 messages in history, got %d", id, got)
+		}
+		if usage := runner.Usage(); usage.Total.InputTokens != 30 {
+			t.Errorf("%s: expected usage to be recorded, got %+v", id, usage.Total)
+		}
+	}
+
+	var turnErr *anthropic.BetaBatchTurnError
+	if !errors.As(failing.Err(), &turnErr) || turnErr.ConversationID != "fail" || turnErr.Result.Type != "errored" {
+		t.Errorf("expected the failed conversation to report a BetaBatchTurnError, got %v", failing.Err())
+	}
+	if batch.Runner("fail") != failing {
+		t.Error("expected Runner to return the conversation's runner")
+	}
+}
+
+func TestBetaBatchToolRunnerResumesFailedWait(t *testing.T) {
+	server := newBatchAgentServer(t)
+	client := anthropic.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test-key"), option.WithMaxRetries(0))
+
+	var calls atomic.Int32
+	var city atomic.Value
+	batch := client.Beta.Messages.NewBatchToolRunner(anthropic.BetaBatchToolRunnerParams{Job: fastBatchPolling})
+	runner, err := batch.Add("Oslo", []anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, batchAgentParams("Oslo"))
+	if err != nil {
+		t.Fatalf("Add: %v", err)
+	}
+
+	server.failPolls = 1
+	if _, err := batch.NextTurn(context.Background()); err == nil {
+		t.Fatal("expected the failed poll to fail the turn")
+	}
+	if n, err := batch.NextTurn(context.Background()); err != nil || n != 1 {
+		t.Fatalf("expected the retried turn to succeed, got %d, %v", n, err)
+	}
+	if len(server.turns) != 1 {
+		t.Errorf("expected the retry to wait for the existing batch, got %d batches", len(server.turns))
+	}
+	if runner.IterationCount() != 1 || runner.LastMessage() == nil || runner.LastMessage().StopReason != anthropic.BetaStopReasonToolUse {
+		t.Errorf("expected the first turn to be recorded once, got %d iterations, last %+v", runner.IterationCount(), runner.LastMessage())
+	}
+	if err := batch.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+	if !runner.IsCompleted() || calls.Load() != 1 {
+		t.Errorf("expected the conversation to complete with one tool call, got completed=%v calls=%d", runner.IsCompleted(), calls.Load())
+	}
+}
+
+func TestBetaBatchToolRunnerCanceledPrepareIsRetried(t *testing.T) {
+	server := newBatchAgentServer(t)
+	client := anthropic.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test-key"), option.WithMaxRetries(0))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var calls atomic.Int32
+	// The first tool call cancels the turn, so the next conversation fails to
+	// prepare after this one has.
+	tool, err := toolrunner.NewBetaToolFromJSONSchema("get_weather", "Get weather",
+		func(_ context.Context, req weatherRequest) (anthropic.BetaToolResultBlockParamContentUnion, error) {
+			if calls.Add(1) == 1 {
+				cancel()
+			}
+			return anthropic.BetaToolResultBlockParamContentUnion{OfText: &anthropic.BetaTextBlockParam{Text: "sunny in " + req.City}}, nil
+		})
+	if err != nil {
+		t.Fatalf("create tool: %v", err)
+	}
+
+	batch := client.Beta.Messages.NewBatchToolRunner(anthropic.BetaBatchToolRunnerParams{Job: fastBatchPolling, MaxParallelConversations: 1})
+	for _, id := range []string{"Oslo", "Lima"} {
+		if _, err := batch.Add(id, []anthropic.BetaTool{tool}, batchAgentParams(id)); err != nil {
+			t.Fatalf("Add: %v", err)
+		}
+	}
+	if _, err := batch.NextTurn(ctx); err != nil {
+		t.Fatalf("NextTurn: %v", err)
+	}
+	if _, err := batch.NextTurn(ctx); err == nil {
+		t.Fatal("expected the canceled turn to fail")
+	}
+	if err := batch.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+	for id, runner := range batch.All() {
+		if !runner.IsCompleted() || runner.IterationCount() != 2 {
+			t.Errorf("%s: expected completion after 2 iterations, got completed=%v iterations=%d", id, runner.IsCompleted(), runner.IterationCount())
+		}
+	}
+	if calls.Load() != 2 {
+		t.Errorf("expected each conversation's tool to run once, got %d calls", calls.Load())
+	}
+}
@@ -0,0 +1,139 @@
+package toolrunner_test
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/tidwall/gjson"
+)
+
+const summaryReply = `{"id":"msg_s","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"end_turn",
+	"content":[{"type":"text","text":"The user asked for the weather in Paris; it is 20 degrees."}],
+	"usage":{"input_tokens":30,"output_tokens":12}}`
+
+func runCompactingRunner(t *testing.T, server *scriptedServer, compactor anthropic.BetaHistoryCompactor) {
+	t.Helper()
+	client := newStableClient(server)
+	var calls atomic.Int32
+	var city atomic.Value
+	params := betaRunnerParams()
+	params.HistoryCompactor = compactor
+	runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, params)
+	if _, err := runner.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+}
+
+func TestToolRunner_DropOldToolResults(t *testing.T) {
+	server := newScriptedServer(t, false, toolUseReply, toolUseReply, toolUseReply, finalReply)
+	runCompactingRunner(t, server, anthropic.BetaDropOldToolResults{KeepTurns: 2})
+
+	last := gjson.Parse(server.requests[3])
+	if got := len(last.Get("messages").Array()); got != 7 {
+		t.Fatalf("expected 7 messages, got %d", got)
+	}
+	dropped := last.Get("messages.2.content.0")
+	if dropped.Get("tool_use_id").String() != "toolu_1" || dropped.Get("content.0.text").String() != anthropic.DefaultToolResultPlaceholder {
+		t.Errorf("expected oldest tool result to be replaced, got %s", dropped.Raw)
+	}
+	for _, i := range []int{4, 6} {
+		if got := last.Get(fmt.Sprintf("messages.%d.content.0.content.0.text", i)).String(); !strings.HasPrefix(got, "The weather in Paris") {
+			t.Errorf("expected tool result %d to be kept, got %q", i, got)
+		}
+	}
+	// Earlier requests were sent before the results became old.
+	if got := gjson.Get(server.requests[2], "messages.2.content.0.content.0.text").String(); !strings.HasPrefix(got, "The weather in Paris") {
+		t.Errorf("expected tool result to be intact in request 3, got %q", got)
+	}
+}
+
+func TestToolRunner_SummarizeHistory(t *testing.T) {
+	server := newScriptedServer(t, false, toolUseReply, toolUseReply, summaryReply, finalReply)
+	runCompactingRunner(t, server, anthropic.BetaSummarizeHistory{TriggerTokens: 10, KeepTurns: 1})
+
+	if len(server.requests) != 4 {
+		t.Fatalf("expected 4 requests, got %d", len(server.requests))
+	}
+	side := gjson.Parse(server.requests[2])
+	if got := side.Get("tool_choice.type").String(); got != "none" {
+		t.Errorf("expected summary request to disable tools, got %q", got)
+	}
+	if got := len(side.Get("messages").Array()); got != 3 {
+		t.Errorf("expected 3 messages to summarize, got %d", got)
+	}
+	if got := side.Get("messages.2.content.1.text").String(); got != anthropic.DefaultSummaryPrompt {
+		t.Errorf("expected summary prompt after the tool results, got %q", got)
+	}
+
+	last := gjson.Parse(server.requests[3])
+	messages := last.Get("messages").Array()
+	if len(messages) != 3 {
+		t.Fatalf("expected summary plus the last exchange, got %d messages", len(messages))
+	}
+	if got := messages[0].Get("content.0.text").String(); !strings.Contains(got, "it is 20 degrees") {
+		t.Errorf("expected summary as first message, got %q", got)
+	}
+	if messages[1].Get("role").String() != "assistant" || messages[2].Get("content.0.tool_use_id").String() != "toolu_1" {
+		t.Errorf("expected last tool_use exchange to be kept, got %s", last.Get("messages").Raw)
+	}
+}
+
+func TestToolRunner_TrimHistoryToTokenBudget(t *testing.T) {
+	replies := []string{toolUseReply, toolUseReply, finalReply}
+	var requests []string
+	var counted atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/count_tokens") {
+			counted.Add(1)
+			// 100 tokens per message.
+			fmt.Fprintf(w, `{"input_tokens":%d}`, 100*len(gjson.GetBytes(body, "messages").Array()))
+			return
+		}
+		n := len(requests)
+		requests = append(requests, string(body))
+		fmt.Fprint(w, replies[n])
+	}))
+	defer server.Close()
+
+	client := anthropic.NewClient(option.WithBaseURL(server.URL), option.WithAPIKey("test-key"), option.WithMaxRetries(0))
+	var calls atomic.Int32
+	var city atomic.Value
+	params := betaRunnerParams()
+	params.HistoryCompactor = anthropic.BetaTrimHistoryToTokenBudget{MaxInputTokens: 350}
+	runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, params)
+	if _, err := runner.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(requests))
+	}
+	// 1 and 3 messages fit; 5 messages need one count to find they don't
+	// fit and another after dropping the oldest exchange.
+	if got := counted.Load(); got != 4 {
+		t.Errorf("expected 4 token counts, got %d", got)
+	}
+	messages := gjson.Get(requests[2], "messages").Array()
+	if len(messages) != 3 {
+		t.Fatalf("expected history trimmed to 3 messages, got %d", len(messages))
+	}
+	if got := messages[0].Get("content.0.text").String(); got != "What's the weather in Paris?" {
+		t.Errorf("expected first message to be kept, got %q", got)
+	}
+	if messages[1].Get("role").String() != "assistant" || messages[2].Get("content.0.type").String() != "tool_result" {
+		t.Errorf("expected latest exchange to be kept intact, got %s", gjson.Get(requests[2], "messages").Raw)
+	}
+	if got := len(runner.Messages()); got != 4 {
+		t.Errorf("expected trimmed history to replace Params.Messages, got %d messages", got)
+	}
+}
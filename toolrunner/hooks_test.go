@@ -0,0 +1,208 @@
+package toolrunner_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/toolrunner"
+	"github.com/tidwall/gjson"
+)
+
+// countingWeatherTool is a beta weather tool that records how often it ran
+// and the city it was asked about.
+func countingWeatherTool(t *testing.T, calls *atomic.Int32, city *atomic.Value) anthropic.BetaTool {
+	t.Helper()
+	tool, err := toolrunner.NewBetaToolFromJSONSchema("get_weather", "Get weather",
+		func(ctx context.Context, req weatherRequest) (anthropic.BetaToolResultBlockParamContentUnion, error) {
+			calls.Add(1)
+			city.Store(req.City)
+			return anthropic.BetaToolResultBlockParamContentUnion{
+				OfText: &anthropic.BetaTextBlockParam{Text: fmt.Sprintf("The weather in %s is 20 degrees. api_key=secret", req.City)},
+			}, nil
+		})
+	if err != nil {
+		t.Fatalf("create weather tool: %v", err)
+	}
+	return tool
+}
+
+func betaRunnerParams() anthropic.BetaToolRunnerParams {
+	return anthropic.BetaToolRunnerParams{
+		BetaMessageNewParams: anthropic.BetaMessageNewParams{
+			Model:     anthropic.ModelClaudeSonnet4_5,
+			MaxTokens: 512,
+			Messages: []anthropic.BetaMessageParam{
+				anthropic.NewBetaUserMessage(anthropic.NewBetaTextBlock("What's the weather in Paris?")),
+			},
+		},
+		MaxIterations: 5,
+	}
+}
+
+func TestToolRunner_BeforeToolCall(t *testing.T) {
+	tests := []struct {
+		name        string
+		before      func(context.Context, anthropic.BetaToolUseBlock) (anthropic.BetaToolCallDecision, error)
+		approve     func(context.Context, anthropic.BetaToolUseBlock) (anthropic.BetaToolCallDecision, error)
+		wantCalls   int32
+		wantCity    string
+		wantIsError bool
+		wantText    string
+	}{
+		{
+			name: "allow",
+			before: func(context.Context, anthropic.BetaToolUseBlock) (anthropic.BetaToolCallDecision, error) {
+				return anthropic.BetaToolCallDecision{Verdict: anthropic.BetaToolCallVerdictAllow}, nil
+			},
+			wantCalls: 1,
+			wantCity:  "Paris",
+			wantText:  "The weather in Paris is 20 degrees. api_key=secret",
+		},
+		{
+			name: "deny with reason",
+			before: func(context.Context, anthropic.BetaToolUseBlock) (anthropic.BetaToolCallDecision, error) {
+				return anthropic.BetaToolCallDecision{Verdict: anthropic.BetaToolCallVerdictDeny, Reason: "not allowed"}, nil
+			},
+			wantIsError: true,
+			wantText:    "not allowed",
+		},
+		{
+			name: "empty verdict fails closed",
+			before: func(context.Context, anthropic.BetaToolUseBlock) (anthropic.BetaToolCallDecision, error) {
+				return anthropic.BetaToolCallDecision{}, nil
+			},
+			wantIsError: true,
+			wantText:    "Error: Tool call 'get_weather' was denied",
+		},
+		{
+			name: "rewrite input",
+			before: func(context.Context, anthropic.BetaToolUseBlock) (anthropic.BetaToolCallDecision, error) {
+				return anthropic.BetaToolCallDecision{
+					Verdict: anthropic.BetaToolCallVerdictAllow,
+					Input:   json.RawMessage(`{"city":"Lyon"}`),
+				}, nil
+			},
+			wantCalls: 1,
+			wantCity:  "Lyon",
+			wantText:  "The weather in Lyon is 20 degrees. api_key=secret",
+		},
+		{
+			name: "ask approved",
+			before: func(context.Context, anthropic.BetaToolUseBlock) (anthropic.BetaToolCallDecision, error) {
+				return anthropic.BetaToolCallDecision{Verdict: anthropic.BetaToolCallVerdictAsk}, nil
+			},
+			approve: func(_ context.Context, toolUse anthropic.BetaToolUseBlock) (anthropic.BetaToolCallDecision, error) {
+				if toolUse.ID != "toolu_1" {
+					return anthropic.BetaToolCallDecision{}, fmt.Errorf("unexpected tool use %s", toolUse.ID)
+				}
+				return anthropic.BetaToolCallDecision{Verdict: anthropic.BetaToolCallVerdictAllow}, nil
+			},
+			wantCalls: 1,
+			wantCity:  "Paris",
+			wantText:  "The weather in Paris is 20 degrees. api_key=secret",
+		},
+		{
+			name: "ask without approver is denied",
+			before: func(context.Context, anthropic.BetaToolUseBlock) (anthropic.BetaToolCallDecision, error) {
+				return anthropic.BetaToolCallDecision{Verdict: anthropic.BetaToolCallVerdictAsk, Reason: "needs approval"}, nil
+			},
+			wantIsError: true,
+			wantText:    "needs approval",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := newScriptedServer(t, false, toolUseReply, finalReply)
+			client := newStableClient(server)
+
+			var calls atomic.Int32
+			var city atomic.Value
+			params := betaRunnerParams()
+			params.BeforeToolCall = tt.before
+			params.RequestApproval = tt.approve
+			runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, params)
+			if _, err := runner.RunToCompletion(context.Background()); err != nil {
+				t.Fatalf("RunToCompletion: %v", err)
+			}
+
+			if got := calls.Load(); got != tt.wantCalls {
+				t.Errorf("expected tool to run %d times, ran %d", tt.wantCalls, got)
+			}
+			if tt.wantCity != "" && city.Load() != tt.wantCity {
+				t.Errorf("expected tool input city %q, got %v", tt.wantCity, city.Load())
+			}
+			result := gjson.Get(server.requests[1], "messages.2.content.0")
+			if got := result.Get("is_error").Bool(); got != tt.wantIsError {
+				t.Errorf("expected is_error=%v, got %s", tt.wantIsError, result.Raw)
+			}
+			if got := result.Get("content.0.text").String(); got != tt.wantText {
+				t.Errorf("unexpected tool result text %q", got)
+			}
+			// The model's original input stays in the history.
+			if got := gjson.Get(server.requests[1], "messages.1.content.1.input.city").String(); got != "Paris" {
+				t.Errorf("expected original tool_use input in history, got %q", got)
+			}
+		})
+	}
+}
+
+func TestToolRunner_AfterToolCallRedacts(t *testing.T) {
+	server := newScriptedServer(t, false, toolUseReply, finalReply)
+	client := newStableClient(server)
+
+	var calls atomic.Int32
+	var city atomic.Value
+	params := betaRunnerParams()
+	params.AfterToolCall = func(_ context.Context, toolUse anthropic.BetaToolUseBlock, result anthropic.BetaToolResultBlockParam) (anthropic.BetaToolResultBlockParam, error) {
+		for _, block := range result.Content {
+			if block.OfText != nil {
+				block.OfText.Text = strings.ReplaceAll(block.OfText.Text, "api_key=secret", "[REDACTED]")
+			}
+		}
+		result.ToolUseID = "tampered"
+		return result, nil
+	}
+	runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, params)
+	if _, err := runner.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+
+	result := gjson.Get(server.requests[1], "messages.2.content.0")
+	if got := result.Get("content.0.text").String(); got != "The weather in Paris is 20 degrees. [REDACTED]" {
+		t.Errorf("expected redacted output, got %q", got)
+	}
+	if got := result.Get("tool_use_id").String(); got != "toolu_1" {
+		t.Errorf("expected tool_use_id to be preserved, got %q", got)
+	}
+}
+
+func TestToolRunner_HookErrorAbortsTurn(t *testing.T) {
+	server := newScriptedServer(t, false, toolUseReply)
+	client := newStableClient(server)
+
+	var calls atomic.Int32
+	var city atomic.Value
+	errPolicy := errors.New("policy backend unavailable")
+	params := betaRunnerParams()
+	params.BeforeToolCall = func(context.Context, anthropic.BetaToolUseBlock) (anthropic.BetaToolCallDecision, error) {
+		return anthropic.BetaToolCallDecision{}, errPolicy
+	}
+	runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, params)
+	_, err := runner.RunToCompletion(context.Background())
+	if !errors.Is(err, errPolicy) {
+		t.Fatalf("expected policy error, got %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Errorf("expected tool not to run")
+	}
+	if len(server.requests) != 1 {
+		t.Errorf("expected no follow-up request, got %d requests", len(server.requests))
+	}
+}
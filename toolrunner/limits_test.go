@@ -0,0 +1,177 @@
+package toolrunner_test
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/toolrunner"
+	"github.com/tidwall/gjson"
+)
+
+// parallelToolUseReply returns a tool_use turn calling each named tool once.
+func parallelToolUseReply(names ...string) string {
+	blocks := make([]string, len(names))
+	for i, name := range names {
+		blocks[i] = fmt.Sprintf(`{"type":"tool_use","id":"toolu_%d","name":%q,"input":{}}`, i, name)
+	}
+	return fmt.Sprintf(`{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"tool_use","content":[%s],"usage":{"input_tokens":10,"output_tokens":5}}`,
+		strings.Join(blocks, ","))
+}
+
+// concurrencyTool sleeps briefly and records the peak number of concurrent
+// executions across every tool sharing the counters.
+func concurrencyTool(t *testing.T, name string, active, peak *atomic.Int32) anthropic.BetaTool {
+	t.Helper()
+	tool, err := toolrunner.NewBetaToolFromJSONSchema(name, "Run a query",
+		func(ctx context.Context, _ struct{}) (anthropic.BetaToolResultBlockParamContentUnion, error) {
+			n := active.Add(1)
+			defer active.Add(-1)
+			for {
+				p := peak.Load()
+				if n <= p || peak.CompareAndSwap(p, n) {
+					break
+				}
+			}
+			time.Sleep(20 * time.Millisecond)
+			return anthropic.BetaToolResultBlockParamContentUnion{OfText: &anthropic.BetaTextBlockParam{Text: "ok"}}, nil
+		})
+	if err != nil {
+		t.Fatalf("create tool: %v", err)
+	}
+	return tool
+}
+
+func TestToolRunner_MaxParallelToolCalls(t *testing.T) {
+	names := make([]string, 8)
+	for i := range names {
+		names[i] = "query"
+	}
+	server := newScriptedServer(t, false, parallelToolUseReply(names...), finalReply)
+	client := newStableClient(server)
+
+	var active, peak atomic.Int32
+	params := betaRunnerParams()
+	params.MaxParallelToolCalls = 3
+	runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{concurrencyTool(t, "query", &active, &peak)}, params)
+	if _, err := runner.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+
+	if got := peak.Load(); got > 3 || got < 2 {
+		t.Errorf("expected up to 3 concurrent calls, peak was %d", got)
+	}
+	results := gjson.Get(server.requests[1], "messages.2.content").Array()
+	if len(results) != len(names) {
+		t.Fatalf("expected %d tool results, got %d", len(names), len(results))
+	}
+	for i, result := range results {
+		if got := result.Get("tool_use_id").String(); got != fmt.Sprintf("toolu_%d", i) {
+			t.Errorf("result %d answers %s, want results in tool_use order", i, got)
+		}
+	}
+}
+
+func TestToolRunner_ToolConcurrency(t *testing.T) {
+	server := newScriptedServer(t, false, parallelToolUseReply("db", "db", "db", "http", "http"), finalReply)
+	client := newStableClient(server)
+
+	var dbActive, dbPeak, httpActive, httpPeak atomic.Int32
+	params := betaRunnerParams()
+	params.ToolConcurrency = map[string]int{"db": 1}
+	runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{
+		concurrencyTool(t, "db", &dbActive, &dbPeak),
+		concurrencyTool(t, "http", &httpActive, &httpPeak),
+	}, params)
+	if _, err := runner.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+
+	if got := dbPeak.Load(); got != 1 {
+		t.Errorf("expected db calls to be serialized, peak was %d", got)
+	}
+	if got := httpPeak.Load(); got != 2 {
+		t.Errorf("expected unlimited http calls to run together, peak was %d", got)
+	}
+}
+
+func TestToolRunner_ToolConcurrencyDoesNotStarveOtherTools(t *testing.T) {
+	server := newScriptedServer(t, false, parallelToolUseReply("db", "db", "db", "http"), finalReply)
+	client := newStableClient(server)
+
+	// The first db call only finishes once http has run, so http must get a
+	// shared slot while the other db calls wait for theirs.
+	httpDone := make(chan struct{})
+	db, err := toolrunner.NewBetaToolFromJSONSchema("db", "Run a query",
+		func(ctx context.Context, _ struct{}) (anthropic.BetaToolResultBlockParamContentUnion, error) {
+			select {
+			case <-httpDone:
+			case <-time.After(5 * time.Second):
+				t.Error("http call starved behind queued db calls")
+			}
+			return anthropic.BetaToolResultBlockParamContentUnion{OfText: &anthropic.BetaTextBlockParam{Text: "ok"}}, nil
+		})
+	if err != nil {
+		t.Fatalf("create tool: %v", err)
+	}
+	var once atomic.Bool
+	http, err := toolrunner.NewBetaToolFromJSONSchema("http", "Fetch a URL",
+		func(ctx context.Context, _ struct{}) (anthropic.BetaToolResultBlockParamContentUnion, error) {
+			if once.CompareAndSwap(false, true) {
+				close(httpDone)
+			}
+			return anthropic.BetaToolResultBlockParamContentUnion{OfText: &anthropic.BetaTextBlockParam{Text: "ok"}}, nil
+		})
+	if err != nil {
+		t.Fatalf("create tool: %v", err)
+	}
+
+	params := betaRunnerParams()
+	params.MaxParallelToolCalls = 2
+	params.ToolConcurrency = map[string]int{"db": 1}
+	runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{db, http}, params)
+	if _, err := runner.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+}
+
+func TestToolRunner_ToolTimeout(t *testing.T) {
+	server := newScriptedServer(t, false, parallelToolUseReply("stuck"), finalReply)
+	client := newStableClient(server)
+
+	release := make(chan struct{})
+	defer close(release)
+	stuck, err := toolrunner.NewBetaToolFromJSONSchema("stuck", "Never returns on its own",
+		func(ctx context.Context, _ struct{}) (anthropic.BetaToolResultBlockParamContentUnion, error) {
+			// Ignores ctx on purpose: the runner must not wait for it.
+			<-release
+			return anthropic.BetaToolResultBlockParamContentUnion{}, nil
+		})
+	if err != nil {
+		t.Fatalf("create tool: %v", err)
+	}
+
+	params := betaRunnerParams()
+	params.ToolTimeout = 50 * time.Millisecond
+	runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{stuck}, params)
+
+	start := time.Now()
+	if _, err := runner.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("turn blocked on the stuck tool for %s", elapsed)
+	}
+
+	result := gjson.Get(server.requests[1], "messages.2.content.0")
+	if !result.Get("is_error").Bool() {
+		t.Errorf("expected error tool_result, got %s", result.Raw)
+	}
+	if got := result.Get("content.0.text").String(); got != "Error: tool 'stuck' timed out after 50ms" {
+		t.Errorf("unexpected timeout text %q", got)
+	}
+}
@@ -0,0 +1,264 @@
+package toolrunner_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/toolrunner"
+	"github.com/tidwall/gjson"
+)
+
+// scriptedServer replies to successive /v1/messages calls with the given
+// message JSON bodies, recording each request body it receives.
+type scriptedServer struct {
+	*httptest.Server
+	mu       sync.Mutex
+	requests []string
+}
+
+func newScriptedServer(t *testing.T, streaming bool, replies ...string) *scriptedServer {
+	t.Helper()
+	s := &scriptedServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		s.mu.Lock()
+		n := len(s.requests)
+		s.requests = append(s.requests, string(body))
+		s.mu.Unlock()
+		if n >= len(replies) {
+			t.Errorf("unexpected request %d", n+1)
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		if !streaming {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, replies[n])
+			return
+		}
+		w.Header().Set("Content-Type", "text/event-stream")
+		for _, event := range messageToEvents(replies[n]) {
+			fmt.Fprintf(w, "event: %s\ndata: %s\n\n", gjson.Get(event, "type").String(), event)
+		}
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+// messageToEvents renders a complete message as the SSE events that would
+// stream it.
+func messageToEvents(message string) []string {
+	msg := gjson.Parse(message)
+	start := fmt.Sprintf(`{"type":"message_start","message":{"id":%q,"type":"message","role":"assistant","model":%q,"content":[],"usage":{"input_tokens":10,"output_tokens":0}}}`,
+		msg.Get("id").String(), msg.Get("model").String())
+	events := []string{start}
+	for i, block := range msg.Get("content").Array() {
+		switch block.Get("type").String() {
+		case "text":
+			events = append(events,
+				fmt.Sprintf(`{"type":"content_block_start","index":%d,"content_block":{"type":"text","text":""}}`, i),
+				fmt.Sprintf(`{"type":"content_block_delta","index":%d,"delta":{"type":"text_delta","text":%s}}`, i, block.Get("text").Raw))
+		case "tool_use":
+			input, _ := json.Marshal(block.Get("input").Raw)
+			events = append(events,
+				fmt.Sprintf(`{"type":"content_block_start","index":%d,"content_block":{"type":"tool_use","id":%q,"name":%q,"input":{}}}`, i, block.Get("id").String(), block.Get("name").String()),
+				fmt.Sprintf(`{"type":"content_block_delta","index":%d,"delta":{"type":"input_json_delta","partial_json":%s}}`, i, input))
+		}
+		events = append(events, fmt.Sprintf(`{"type":"content_block_stop","index":%d}`, i))
+	}
+	return append(events,
+		fmt.Sprintf(`{"type":"message_delta","delta":{"stop_reason":%q,"stop_sequence":null},"usage":{"output_tokens":5}}`, msg.Get("stop_reason").String()),
+		`{"type":"message_stop"}`)
+}
+
+const toolUseReply = `{"id":"msg_1","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"tool_use",
+	"content":[{"type":"text","text":"Checking."},{"type":"tool_use","id":"toolu_1","name":"get_weather","input":{"city":"Paris","units":"celsius"}}],
+	"usage":{"input_tokens":10,"output_tokens":5}}`
+
+const finalReply = `{"id":"msg_2","type":"message","role":"assistant","model":"claude-sonnet-4-5","stop_reason":"end_turn",
+	"content":[{"type":"text","text":"It is 20 degrees in Paris."}],
+	"usage":{"input_tokens":20,"output_tokens":8}}`
+
+func stableWeatherTool(t *testing.T) anthropic.Tool {
+	t.Helper()
+	tool, err := toolrunner.NewToolFromJSONSchema("get_weather", "Get weather",
+		func(ctx context.Context, req weatherRequest) (anthropic.ToolResultBlockParamContentUnion, error) {
+			return anthropic.ToolResultBlockParamContentUnion{
+				OfText: &anthropic.TextBlockParam{Text: fmt.Sprintf("The weather in %s is 20 degrees %s.", req.City, req.Units)},
+			}, nil
+		})
+	if err != nil {
+		t.Fatalf("create weather tool: %v", err)
+	}
+	return tool
+}
+
+func stableRunnerParams() anthropic.ToolRunnerParams {
+	return anthropic.ToolRunnerParams{
+		MessageNewParams: anthropic.MessageNewParams{
+			Model:     anthropic.ModelClaudeSonnet4_5,
+			MaxTokens: 512,
+			Messages: []anthropic.MessageParam{
+				anthropic.NewUserMessage(anthropic.NewTextBlock("What's the weather in Paris?")),
+			},
+		},
+		MaxIterations: 5,
+	}
+}
+
+func newStableClient(server *scriptedServer) anthropic.Client {
+	return anthropic.NewClient(
+		option.WithBaseURL(server.URL),
+		option.WithAPIKey("test-key"),
+		option.WithMaxRetries(0),
+	)
+}
+
+func TestNewToolFromJSONSchema(t *testing.T) {
+	type input struct {
+		Query string `json:"query" jsonschema:"required,description=The search query"`
+		Limit int    `json:"limit,omitempty"`
+	}
+	tool, err := toolrunner.NewToolFromJSONSchema("search", "Search things",
+		func(ctx context.Context, in input) (anthropic.ToolResultBlockParamContentUnion, error) {
+			return anthropic.ToolResultBlockParamContentUnion{OfText: &anthropic.TextBlockParam{Text: in.Query}}, nil
+		})
+	if err != nil {
+		t.Fatalf("NewToolFromJSONSchema: %v", err)
+	}
+
+	schema := tool.InputSchema()
+	if got := schema.Required; len(got) != 1 || got[0] != "query" {
+		t.Errorf("expected required [query], got %v", got)
+	}
+	if _, ok := schema.Properties.(map[string]any)["limit"]; !ok {
+		t.Errorf("expected limit property, got %v", schema.Properties)
+	}
+
+	content, err := tool.Execute(context.Background(), json.RawMessage(`{"query":"go"}`))
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if len(content) != 1 || content[0].OfText.Text != "go" {
+		t.Errorf("unexpected result %+v", content)
+	}
+}
+
+func TestStableToolRunner_RunToCompletion(t *testing.T) {
+	server := newScriptedServer(t, false, toolUseReply, finalReply)
+	client := newStableClient(server)
+
+	runner := client.Messages.NewToolRunner([]anthropic.Tool{stableWeatherTool(t)}, stableRunnerParams())
+	last, err := runner.RunToCompletion(context.Background())
+	if err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+
+	if got := last.Content[0].Text; got != "It is 20 degrees in Paris." {
+		t.Errorf("unexpected final text %q", got)
+	}
+	if runner.IterationCount() != 2 || !runner.IsCompleted() {
+		t.Errorf("expected 2 completed iterations, got %d (completed=%v)", runner.IterationCount(), runner.IsCompleted())
+	}
+	if got := len(runner.Messages()); got != 4 {
+		t.Errorf("expected 4 messages in history, got %d", got)
+	}
+
+	if len(server.requests) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(server.requests))
+	}
+	first := gjson.Parse(server.requests[0])
+	if got := first.Get("tools.0.name").String(); got != "get_weather" {
+		t.Errorf("expected tool definition in request, got %q", got)
+	}
+	result := gjson.Get(server.requests[1], "messages.2.content.0")
+	if result.Get("type").String() != "tool_result" || result.Get("tool_use_id").String() != "toolu_1" {
+		t.Errorf("expected tool_result for toolu_1, got %s", result.Raw)
+	}
+	if got := result.Get("content.0.text").String(); got != "The weather in Paris is 20 degrees celsius." {
+		t.Errorf("unexpected tool result text %q", got)
+	}
+}
+
+func TestStableToolRunner_UnknownTool(t *testing.T) {
+	reply := strings.Replace(toolUseReply, `"get_weather"`, `"get_time"`, 1)
+	server := newScriptedServer(t, false, reply, finalReply)
+	client := newStableClient(server)
+
+	runner := client.Messages.NewToolRunner([]anthropic.Tool{stableWeatherTool(t)}, stableRunnerParams())
+	if _, err := runner.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+
+	result := gjson.Get(server.requests[1], "messages.2.content.0")
+	if !result.Get("is_error").Bool() {
+		t.Errorf("expected error tool_result, got %s", result.Raw)
+	}
+	if got := result.Get("content.0.text").String(); got != "Error: Tool 'get_time' not found" {
+		t.Errorf("unexpected error text %q", got)
+	}
+}
+
+func TestStableToolRunner_MaxIterations(t *testing.T) {
+	server := newScriptedServer(t, false, toolUseReply)
+	client := newStableClient(server)
+
+	params := stableRunnerParams()
+	params.MaxIterations = 1
+	runner := client.Messages.NewToolRunner([]anthropic.Tool{stableWeatherTool(t)}, params)
+
+	last, err := runner.RunToCompletion(context.Background())
+	if err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+	if last.StopReason != anthropic.StopReasonToolUse || runner.IterationCount() != 1 {
+		t.Errorf("expected to stop after one tool_use turn, got %d iterations", runner.IterationCount())
+	}
+	if len(server.requests) != 1 {
+		t.Errorf("expected 1 request, got %d", len(server.requests))
+	}
+}
+
+func TestStableToolRunnerStreaming_AllStreaming(t *testing.T) {
+	server := newScriptedServer(t, true, toolUseReply, finalReply)
+	client := newStableClient(server)
+
+	runner := client.Messages.NewToolRunnerStreaming([]anthropic.Tool{stableWeatherTool(t)}, stableRunnerParams())
+
+	var text strings.Builder
+	for events, err := range runner.AllStreaming(context.Background()) {
+		if err != nil {
+			t.Fatalf("runner error: %v", err)
+		}
+		for event, err := range events {
+			if err != nil {
+				t.Fatalf("stream error: %v", err)
+			}
+			if delta, ok := event.AsAny().(anthropic.ContentBlockDeltaEvent); ok {
+				text.WriteString(delta.Delta.Text)
+			}
+		}
+	}
+	if runner.Err() != nil {
+		t.Fatalf("runner error: %v", runner.Err())
+	}
+
+	if got := text.String(); got != "Checking.It is 20 degrees in Paris." {
+		t.Errorf("unexpected streamed text %q", got)
+	}
+	if runner.IterationCount() != 2 {
+		t.Errorf("expected 2 iterations, got %d", runner.IterationCount())
+	}
+	result := gjson.Get(server.requests[1], "messages.2.content.0")
+	if got := result.Get("content.0.text").String(); got != "The weather in Paris is 20 degrees celsius." {
+		t.Errorf("unexpected tool result text %q", got)
+	}
+}
@@ -0,0 +1,179 @@
+package toolrunner_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/tidwall/gjson"
+)
+
+// recordingStore wraps a ConversationStore and keeps every checkpoint saved.
+type recordingStore struct {
+	anthropic.ConversationStore
+	saved []anthropic.ConversationCheckpoint
+}
+
+func (s *recordingStore) Save(ctx context.Context, id string, checkpoint anthropic.ConversationCheckpoint) error {
+	s.saved = append(s.saved, checkpoint)
+	return s.ConversationStore.Save(ctx, id, checkpoint)
+}
+
+func TestToolRunner_ConversationCheckpoints(t *testing.T) {
+	server := newScriptedServer(t, false, toolUseReply, finalReply)
+	client := newStableClient(server)
+
+	store := &recordingStore{ConversationStore: anthropic.NewMemoryConversationStore()}
+	var calls atomic.Int32
+	var city atomic.Value
+	params := betaRunnerParams()
+	params.ConversationStore = store
+	params.ConversationID = "conv-1"
+	runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, params)
+	if _, err := runner.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+
+	// tool_use turn, tool result, tool results message, final turn, completion
+	wantMessages := []int{2, 2, 3, 4, 4}
+	if len(store.saved) != len(wantMessages) {
+		t.Fatalf("expected %d checkpoints, got %d", len(wantMessages), len(store.saved))
+	}
+	for i, want := range wantMessages {
+		if got := len(store.saved[i].Messages); got != want {
+			t.Errorf("checkpoint %d: expected %d messages, got %d", i, want, got)
+		}
+	}
+	if got := store.saved[1].ToolResults; len(got) != 1 || got[0].ToolUseID != "toolu_1" {
+		t.Errorf("expected partial tool result for toolu_1, got %+v", got)
+	}
+	if len(store.saved[2].ToolResults) != 0 {
+		t.Errorf("expected tool results to be cleared once sent")
+	}
+
+	final, err := store.Load(context.Background(), "conv-1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !final.Completed || final.IterationCount != 2 || final.StopReason != anthropic.BetaStopReasonEndTurn {
+		t.Errorf("unexpected final checkpoint %+v", final)
+	}
+}
+
+func TestToolRunner_ResumeSkipsAnsweredTools(t *testing.T) {
+	ctx := context.Background()
+	store := anthropic.NewMemoryConversationStore()
+
+	// First process: the tool runs, then the follow-up request fails.
+	var requests atomic.Int32
+	first := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if requests.Add(1) > 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, toolUseReply)
+	}))
+	defer first.Close()
+	var calls atomic.Int32
+	var city atomic.Value
+	params := betaRunnerParams()
+	params.ConversationStore = store
+	params.ConversationID = "conv-1"
+	firstClient := anthropic.NewClient(option.WithBaseURL(first.URL), option.WithAPIKey("test-key"), option.WithMaxRetries(0))
+	runner := firstClient.Beta.Messages.NewToolRunner([]anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, params)
+	if _, err := runner.RunToCompletion(ctx); err == nil {
+		t.Fatalf("expected the second request to fail")
+	}
+	if calls.Load() != 1 {
+		t.Fatalf("expected tool to run once, ran %d", calls.Load())
+	}
+
+	// Second process: resume from the checkpoint.
+	second := newScriptedServer(t, false, finalReply)
+	secondClient := newStableClient(second)
+	resumed, err := secondClient.Beta.Messages.ResumeToolRunner(ctx, []anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, params)
+	if err != nil {
+		t.Fatalf("ResumeToolRunner: %v", err)
+	}
+	if resumed.IterationCount() != 1 {
+		t.Errorf("expected resumed iteration count 1, got %d", resumed.IterationCount())
+	}
+	last, err := resumed.RunToCompletion(ctx)
+	if err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+	if got := last.Content[0].Text; got != "It is 20 degrees in Paris." {
+		t.Errorf("unexpected final text %q", got)
+	}
+	if calls.Load() != 1 {
+		t.Errorf("expected answered tool call not to run again, ran %d times", calls.Load())
+	}
+	if len(second.requests) != 1 {
+		t.Fatalf("expected 1 request after resume, got %d", len(second.requests))
+	}
+	result := gjson.Get(second.requests[0], "messages.2.content.0")
+	if result.Get("tool_use_id").String() != "toolu_1" || result.Get("content.0.text").String() != "The weather in Paris is 20 degrees. api_key=secret" {
+		t.Errorf("expected stored tool result to be sent, got %s", result.Raw)
+	}
+}
+
+func TestToolRunner_ResumeMissingConversation(t *testing.T) {
+	server := newScriptedServer(t, false)
+	params := betaRunnerParams()
+	params.ConversationStore = anthropic.NewMemoryConversationStore()
+	params.ConversationID = "missing"
+	client := newStableClient(server)
+	_, err := client.Beta.Messages.ResumeToolRunner(context.Background(), nil, params)
+	if !errors.Is(err, anthropic.ErrConversationNotFound) {
+		t.Fatalf("expected ErrConversationNotFound, got %v", err)
+	}
+}
+
+func TestFileConversationStore(t *testing.T) {
+	ctx := context.Background()
+	dir := filepath.Join(t.TempDir(), "conversations")
+	store := anthropic.NewFileConversationStore(dir)
+
+	if _, err := store.Load(ctx, "conv-1"); !errors.Is(err, anthropic.ErrConversationNotFound) {
+		t.Fatalf("expected ErrConversationNotFound, got %v", err)
+	}
+	if err := store.Save(ctx, "../escape", anthropic.ConversationCheckpoint{}); err == nil {
+		t.Fatalf("expected invalid conversation ID to be rejected")
+	}
+
+	checkpoint := anthropic.ConversationCheckpoint{
+		Messages:       betaRunnerParams().Messages,
+		IterationCount: 3,
+		StopReason:     anthropic.BetaStopReasonToolUse,
+	}
+	if err := store.Save(ctx, "conv-1", checkpoint); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	got, err := store.Load(ctx, "conv-1")
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if got.IterationCount != 3 || got.StopReason != anthropic.BetaStopReasonToolUse || len(got.Messages) != 1 {
+		t.Errorf("unexpected checkpoint %+v", got)
+	}
+	if text := got.Messages[0].Content[0].OfText.Text; text != "What's the weather in Paris?" {
+		t.Errorf("unexpected message text %q", text)
+	}
+
+	info, err := os.Stat(filepath.Join(dir, "conv-1.json"))
+	if err != nil {
+		t.Fatalf("stat checkpoint: %v", err)
+	}
+	if perm := info.Mode().Perm(); perm != 0600 {
+		t.Errorf("expected checkpoint mode 0600, got %o", perm)
+	}
+}
@@ -36,8 +36,13 @@ func (t *betaTool[T]) Execute(ctx context.Context, input json.RawMessage) ([]ant
 }
 
 // parse validates and parses the input according to the tool's schema.
-// This method handles special cases for json.RawMessage and []byte type parameters.
 func (t *betaTool[T]) parse(input json.RawMessage) (T, error) {
+	return parseInput[T](input)
+}
+
+// parseInput decodes tool input into T.
+// This function handles special cases for json.RawMessage and []byte type parameters.
+func parseInput[T any](input json.RawMessage) (T, error) {
 	var parsed T
 
 	switch any(parsed).(type) {
@@ -95,6 +100,21 @@ func NewBetaToolFromJSONSchema[T any](
 	name, description string,
 	handler func(context.Context, T) (anthropic.BetaToolResultBlockParamContentUnion, error),
 ) (anthropic.BetaTool, error) {
+	schemaMap, err := reflectSchemaMap[T]()
+	if err != nil {
+		return nil, err
+	}
+
+	inputSchema, err := parseSchemaMap(schemaMap)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBetaTool(name, description, inputSchema, handler), nil
+}
+
+// reflectSchemaMap infers the JSON schema of struct type T from its jsonschema tags.
+func reflectSchemaMap[T any]() (map[string]any, error) {
 	var zeroValue T
 	reflector := jsonschema.Reflector{
 		AllowAdditionalProperties:  false,
@@ -112,13 +132,7 @@ func NewBetaToolFromJSONSchema[T any](
 	if err := json.Unmarshal(schemaBytes, &schemaMap); err != nil {
 		return nil, err
 	}
-
-	inputSchema, err := parseSchemaMap(schemaMap)
-	if err != nil {
-		return nil, err
-	}
-
-	return NewBetaTool(name, description, inputSchema, handler), nil
+	return schemaMap, nil
 }
 
 // NewBetaTool creates a BetaTool with a BetaToolInputSchemaParam directly.
@@ -134,3 +148,73 @@ func NewBetaTool[T any](
 		handler:     handler,
 	}
 }
+
+// tool is the internal generic implementation of anthropic.Tool, the
+// counterpart of betaTool for the stable Messages API.
+type tool[T any] struct {
+	name        string
+	description string
+	schema      anthropic.ToolInputSchemaParam
+	handler     func(context.Context, T) (anthropic.ToolResultBlockParamContentUnion, error)
+}
+
+func (t *tool[T]) Name() string                                { return t.name }
+func (t *tool[T]) Description() string                         { return t.description }
+func (t *tool[T]) InputSchema() anthropic.ToolInputSchemaParam { return t.schema }
+
+func (t *tool[T]) Execute(ctx context.Context, input json.RawMessage) ([]anthropic.ToolResultBlockParamContentUnion, error) {
+	parsed, err := parseInput[T](input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse tool input: %w", err)
+	}
+	result, err := t.handler(ctx, parsed)
+	if err != nil {
+		return nil, err
+	}
+	return []anthropic.ToolResultBlockParamContentUnion{result}, nil
+}
+
+// NewToolFromBytes creates a Tool from JSON schema bytes.
+func NewToolFromBytes[T any](
+	name, description string,
+	schemaJSON []byte,
+	handler func(context.Context, T) (anthropic.ToolResultBlockParamContentUnion, error),
+) (anthropic.Tool, error) {
+	var schema anthropic.ToolInputSchemaParam
+	if err := schema.UnmarshalJSON(schemaJSON); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal schema: %w", err)
+	}
+	return NewTool(name, description, schema, handler), nil
+}
+
+// NewToolFromJSONSchema creates a Tool by inferring the schema from struct type T using reflection.
+// The struct should use jsonschema tags to define the schema (e.g., `jsonschema:"required,description=..."`).
+func NewToolFromJSONSchema[T any](
+	name, description string,
+	handler func(context.Context, T) (anthropic.ToolResultBlockParamContentUnion, error),
+) (anthropic.Tool, error) {
+	schemaMap, err := reflectSchemaMap[T]()
+	if err != nil {
+		return nil, err
+	}
+
+	schemaBytes, err := json.Marshal(schemaMap)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal schema: %w", err)
+	}
+	return NewToolFromBytes(name, description, schemaBytes, handler)
+}
+
+// NewTool creates a Tool with a ToolInputSchemaParam directly.
+func NewTool[T any](
+	name, description string,
+	schema anthropic.ToolInputSchemaParam,
+	handler func(context.Context, T) (anthropic.ToolResultBlockParamContentUnion, error),
+) anthropic.Tool {
+	return &tool[T]{
+		name:        name,
+		description: description,
+		schema:      schema,
+		handler:     handler,
+	}
+}
@@ -0,0 +1,109 @@
+package toolrunner_test
+
+import (
+	"context"
+	"math"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	anthropic "github.com/anthropics/anthropic-sdk-go"
+)
+
+func TestToolRunner_Usage(t *testing.T) {
+	reply := strings.Replace(toolUseReply, `"usage":{"input_tokens":10,"output_tokens":5}`,
+		`"usage":{"input_tokens":10,"output_tokens":5,"cache_read_input_tokens":100,"server_tool_use":{"web_search_requests":2}}`, 1)
+	// The API reports the dated snapshot that served the call.
+	reply = strings.Replace(reply, `"claude-sonnet-4-5"`, `"claude-sonnet-4-5-20250929"`, 1)
+	server := newScriptedServer(t, false, reply, strings.Replace(finalReply, `"claude-sonnet-4-5"`, `"claude-opus-4-1"`, 1))
+	client := newStableClient(server)
+
+	var calls atomic.Int32
+	var city atomic.Value
+	params := betaRunnerParams()
+	params.Pricing = map[anthropic.Model]anthropic.ModelPricing{
+		"claude-sonnet-4-5": {InputPerMTok: 3, OutputPerMTok: 15, CacheReadPerMTok: 0.3, WebSearchPerRequest: 0.01},
+	}
+	runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, params)
+	if _, err := runner.RunToCompletion(context.Background()); err != nil {
+		t.Fatalf("RunToCompletion: %v", err)
+	}
+
+	usage := runner.Usage()
+	want := anthropic.BetaUsageTotals{InputTokens: 30, OutputTokens: 13, CacheReadInputTokens: 100, WebSearchRequests: 2}
+	if usage.Total != want {
+		t.Errorf("unexpected total %+v", usage.Total)
+	}
+	if len(usage.Iterations) != 2 {
+		t.Fatalf("expected 2 iterations, got %d", len(usage.Iterations))
+	}
+	first := usage.Iterations[0]
+	if first.Iteration != 1 || first.MessageID != "msg_1" || first.Model != "claude-sonnet-4-5-20250929" || !first.Priced {
+		t.Errorf("unexpected first iteration %+v", first)
+	}
+	wantCost := (10*3+5*15+100*0.3)/1e6 + 2*0.01
+	if math.Abs(first.EstimatedCost-wantCost) > 1e-12 {
+		t.Errorf("expected cost %v, got %v", wantCost, first.EstimatedCost)
+	}
+	if usage.Iterations[1].Priced || usage.UnpricedIterations != 1 {
+		t.Errorf("expected the opus iteration to be unpriced, got %+v", usage)
+	}
+	if math.Abs(usage.EstimatedCost-wantCost) > 1e-12 {
+		t.Errorf("expected total cost %v, got %v", wantCost, usage.EstimatedCost)
+	}
+}
+
+func TestToolRunnerStreaming_Usage(t *testing.T) {
+	server := newScriptedServer(t, true, toolUseReply, finalReply)
+	client := newStableClient(server)
+
+	var calls atomic.Int32
+	var city atomic.Value
+	runner := client.Beta.Messages.NewToolRunnerStreaming([]anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, betaRunnerParams())
+	for events, err := range runner.AllStreaming(context.Background()) {
+		if err != nil {
+			t.Fatalf("runner error: %v", err)
+		}
+		for _, err := range events {
+			if err != nil {
+				t.Fatalf("stream error: %v", err)
+			}
+		}
+	}
+
+	usage := runner.Usage()
+	if len(usage.Iterations) != 2 {
+		t.Fatalf("expected 2 iterations, got %d", len(usage.Iterations))
+	}
+	if usage.Total.InputTokens != 20 || usage.Total.OutputTokens != 10 {
+		t.Errorf("unexpected streamed total %+v", usage.Total)
+	}
+	if usage.EstimatedCost != 0 || usage.UnpricedIterations != 0 {
+		t.Errorf("expected no cost estimate without pricing, got %+v", usage)
+	}
+}
+
+func TestToolRunner_HasNextTurn(t *testing.T) {
+	server := newScriptedServer(t, false, toolUseReply, finalReply)
+	client := newStableClient(server)
+
+	var calls atomic.Int32
+	var city atomic.Value
+	runner := client.Beta.Messages.NewToolRunner([]anthropic.BetaTool{countingWeatherTool(t, &calls, &city)}, betaRunnerParams())
+	var turns int
+	for runner.HasNextTurn() {
+		if _, err := runner.NextMessage(context.Background()); err != nil {
+			t.Fatalf("NextMessage: %v", err)
+		}
+		turns++
+	}
+	if turns != 2 || len(server.requests) != 2 {
+		t.Errorf("expected a turn per API call, got %d turns and %d requests", turns, len(server.requests))
+	}
+	if runner.IsCompleted() {
+		t.Error("expected the runner to complete on the next NextMessage call")
+	}
+	if _, err := runner.NextMessage(context.Background()); err != nil || !runner.IsCompleted() || len(server.requests) != 2 {
+		t.Errorf("expected the final call to complete the conversation without a request, got %v", err)
+	}
+}
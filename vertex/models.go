@@ -0,0 +1,34 @@
+package vertex
+
+import (
+	"context"
+
+	"github.com/anthropics/anthropic-sdk-go/internal/requestconfig"
+	sdkoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/modelregistry"
+)
+
+type modelRegistryKey struct{}
+
+// WithModelRegistry returns a request option that translates first-party
+// model IDs with registry instead of [modelregistry.Default]. A nil
+// registry sends model IDs unchanged. Pass it to anthropic.NewClient or to
+// a single request.
+func WithModelRegistry(registry *modelregistry.Registry) sdkoption.RequestOption {
+	return requestconfig.RequestOptionFunc(func(cfg *requestconfig.RequestConfig) error {
+		cfg.Request = cfg.Request.WithContext(context.WithValue(cfg.Request.Context(), modelRegistryKey{}, registry))
+		return nil
+	})
+}
+
+// modelID translates model with the registry of ctx.
+func modelID(ctx context.Context, model string) string {
+	registry := modelregistry.Default()
+	if r, ok := ctx.Value(modelRegistryKey{}).(*modelregistry.Registry); ok {
+		if r == nil {
+			return model
+		}
+		registry = r
+	}
+	return registry.VertexID(model)
+}
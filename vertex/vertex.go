@@ -69,6 +69,10 @@ func WithGoogleAuth(ctx context.Context, region string, projectID string, scopes
 // Ordered this way, your middleware observes Anthropic-shaped requests
 // (POST /v1/messages with the model in the body) — identical to the
 // first-party API.
+//
+// First-party model IDs, such as anthropic.ModelClaudeSonnet4_5, are
+// translated to Vertex AI names with modelregistry.Default(); see
+// [WithModelRegistry]. Other model IDs are sent unchanged.
 func WithCredentials(ctx context.Context, region string, projectID string, creds *google.Credentials) sdkoption.RequestOption {
 	client, _, err := transport.NewHTTPClient(ctx, option.WithTokenSource(creds.TokenSource))
 	if err != nil {
@@ -115,7 +119,7 @@ func vertexMiddleware(region, projectID string) sdkoption.Middleware {
 					return nil, fmt.Errorf("no projectId was given and it could not be resolved from credentials")
 				}
 
-				model := gjson.GetBytes(body, "model").String()
+				model := modelID(r.Context(), gjson.GetBytes(body, "model").String())
 				stream := gjson.GetBytes(body, "stream").Bool()
 
 				body, _ = sjson.DeleteBytes(body, "model")
@@ -133,6 +137,9 @@ func vertexMiddleware(region, projectID string) sdkoption.Middleware {
 					return nil, fmt.Errorf("no projectId was given and it could not be resolved from credentials")
 				}
 
+				if model := gjson.GetBytes(body, "model"); model.Exists() {
+					body, _ = sjson.SetBytes(body, "model", modelID(r.Context(), model.String()))
+				}
 				r.URL.Path = fmt.Sprintf("/v1/projects/%s/locations/%s/publishers/anthropic/models/count-tokens:rawPredict", projectID, region)
 			}
 
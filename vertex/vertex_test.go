@@ -10,6 +10,7 @@ import (
 	"net/http/httptest"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
 	"golang.org/x/oauth2"
@@ -18,6 +19,7 @@ import (
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/anthropics/anthropic-sdk-go/internal/requestconfig"
 	sdkoption "github.com/anthropics/anthropic-sdk-go/option"
+	"github.com/anthropics/anthropic-sdk-go/packages/modelregistry"
 )
 
 func TestBaseURLForRegion(t *testing.T) {
@@ -223,3 +225,64 @@ func TestWithGoogleAuthDefaultsCloudPlatformScope(t *testing.T) {
 		t.Fatal("Expected a token exchange to hit the local STS endpoint")
 	}
 }
+
+func TestVertexModelTranslation(t *testing.T) {
+	t.Setenv("ANTHROPIC_API_KEY", "")
+
+	var wirePath string
+	var wireBody map[string]any
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		wirePath = r.URL.Path
+		json.NewDecoder(r.Body).Decode(&wireBody)
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "count-tokens:rawPredict") {
+			json.NewEncoder(w).Encode(map[string]any{"input_tokens": 3})
+			return
+		}
+		json.NewEncoder(w).Encode(map[string]any{
+			"id": "msg_test", "type": "message", "role": "assistant",
+			"content": []map[string]any{{"type": "text", "text": "hi"}},
+			"model":   "claude-sonnet-4-5", "stop_reason": "end_turn",
+			"usage": map[string]any{"input_tokens": 1, "output_tokens": 1},
+		})
+	}))
+	t.Cleanup(server.Close)
+
+	creds := &google.Credentials{
+		TokenSource: oauth2.StaticTokenSource(&oauth2.Token{AccessToken: "fake"}),
+	}
+	client := anthropic.NewClient(
+		sdkoption.WithoutEnvironmentDefaults(),
+		WithCredentials(context.Background(), "us-east5", "test-project", creds),
+		sdkoption.WithBaseURL(server.URL),
+	)
+	messages := []anthropic.MessageParam{anthropic.NewUserMessage(anthropic.NewTextBlock("hi"))}
+
+	if _, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
+		Model: anthropic.ModelClaudeSonnet4_5, MaxTokens: 1, Messages: messages,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if want := "/v1/projects/test-project/locations/us-east5/publishers/anthropic/models/claude-sonnet-4-5@20250929:rawPredict"; wirePath != want {
+		t.Errorf("got wire path %q, want %q", wirePath, want)
+	}
+
+	if _, err := client.Messages.CountTokens(context.Background(), anthropic.MessageCountTokensParams{
+		Model: anthropic.ModelClaudeOpus4_5, Messages: messages,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if wireBody["model"] != "claude-opus-4-5@20251101" {
+		t.Errorf("expected the count_tokens model to be translated, got %v", wireBody["model"])
+	}
+
+	custom := modelregistry.New(modelregistry.Entry{Model: anthropic.ModelClaudeSonnet4_5, Vertex: "claude-sonnet-4-5@20991231"})
+	if _, err := client.Messages.New(context.Background(), anthropic.MessageNewParams{
+		Model: anthropic.ModelClaudeSonnet4_5, MaxTokens: 1, Messages: messages,
+	}, WithModelRegistry(custom)); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(wirePath, "/models/claude-sonnet-4-5@20991231:") {
+		t.Errorf("expected the custom registry's name, got %q", wirePath)
+	}
+}